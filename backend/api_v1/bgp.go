@@ -1,11 +1,13 @@
 package api_v1
 
 import (
-	"bytes"
 	"errors"
 	"github.com/gin-gonic/gin"
-	"io"
-	"net"
+	"github.com/krystal/krystal-network-tools/backend/asinfo"
+	"github.com/krystal/krystal-network-tools/backend/bird"
+	"github.com/krystal/krystal-network-tools/backend/community"
+	"github.com/krystal/krystal-network-tools/backend/rpki"
+	"net/netip"
 	"net/url"
 	"regexp"
 	"sort"
@@ -13,10 +15,17 @@ import (
 	"strings"
 )
 
-// Defines the regex for a small and large bgp community.
+// Defines the regex for a small, large and extended bgp community.
 var (
 	smallCommunityRe = regexp.MustCompile(`\d+,\d+`)
 	largeCommunityRe = regexp.MustCompile(`\d+, \d+, \d+`)
+	extCommunityRe   = regexp.MustCompile(`[\w.:]+, [\w.:]+, [\w.:]+`)
+)
+
+// Defines the regex for the header line of a route. The prefix is only on the first route for each prefix.
+var routeHeaderRe = regexp.MustCompile(
+	`^(?:(\S+)\s+)?(unicast|unreachable|blackhole|prohibited)\s+\[(\S+)\s+(.+?)(?:\s+from\s+(\S+))?\]` +
+		`(\s+\*)?(?:\s+\((\d+)(?:/\d+)?\))?`,
 )
 
 // bgpLine is a line of BGP data.
@@ -45,6 +54,13 @@ const (
 	routeBgpNextHop
 	routeBgpCommunity
 	routeBgpLargeCommunity
+	routeBgpExtCommunity
+	routeBgpOrigin
+	routeBgpMed
+	routeBgpAggregator
+	routeBgpAtomicAggr
+	routeBgpOriginatorId
+	routeBgpClusterList
 )
 
 // Type is used to define the BGP type.
@@ -56,12 +72,12 @@ func (v *bgpLine) Type() (bgpType, string) {
 		if strings.HasSuffix(v.Line, "Table ") {
 			return table, v.Line
 		}
-		if strings.Contains(v.Line, "unreachable") {
-			return routeHeader, strings.SplitN(v.Line, " ", 2)[0]
+		if routeHeaderRe.MatchString(v.Line) {
+			return routeHeader, v.Line
 		}
 	case "1008":
-		if strings.HasPrefix(v.Line, "Type:") {
-			return routeType, v.Line
+		if strings.HasPrefix(v.Line, "Type: ") {
+			return routeType, v.Line[6:]
 		}
 	case "1012":
 		if strings.HasPrefix(v.Line, "BGP.as_path: ") {
@@ -79,20 +95,92 @@ func (v *bgpLine) Type() (bgpType, string) {
 		if strings.HasPrefix(v.Line, "BGP.large_community: ") {
 			return routeBgpLargeCommunity, v.Line[21:]
 		}
+		if strings.HasPrefix(v.Line, "BGP.ext_community: ") {
+			return routeBgpExtCommunity, v.Line[19:]
+		}
+		if strings.HasPrefix(v.Line, "BGP.origin: ") {
+			return routeBgpOrigin, v.Line[12:]
+		}
+		if strings.HasPrefix(v.Line, "BGP.med: ") {
+			return routeBgpMed, v.Line[9:]
+		}
+		if strings.HasPrefix(v.Line, "BGP.aggregator: ") {
+			return routeBgpAggregator, v.Line[16:]
+		}
+		if strings.HasPrefix(v.Line, "BGP.atomic_aggr:") {
+			return routeBgpAtomicAggr, ""
+		}
+		if strings.HasPrefix(v.Line, "BGP.originator_id: ") {
+			return routeBgpOriginatorId, v.Line[19:]
+		}
+		if strings.HasPrefix(v.Line, "BGP.cluster_list: ") {
+			return routeBgpClusterList, v.Line[18:]
+		}
 	}
 
 	// Dunno
 	return 0, v.Line
 }
 
+// BGPAggregator is used to define the router which aggregated a route.
+type BGPAggregator struct {
+	// Address is used to define the address of the aggregating router.
+	Address string `json:"address"`
+
+	// ASN is used to define the AS of the aggregating router.
+	ASN int `json:"asn"`
+}
+
 // BGPRoute is used to define a route in BGP.
 type BGPRoute struct {
 	// Prefix is used to define the prefix of the BGP route.
 	Prefix *string `json:"prefix"`
 
+	// Type is used to define the type of the route, such as unicast, unreachable or blackhole.
+	Type string `json:"type"`
+
+	// Protocol is used to define the name of the BIRD protocol the route was learnt from.
+	Protocol string `json:"protocol"`
+
+	// Since is used to define when the route was last changed, in the time format BIRD is configured with.
+	Since string `json:"since"`
+
+	// From is set when the route was learnt from a different address to the next hop.
+	From *string `json:"from"`
+
+	// Primary is true if this is the best route for the prefix.
+	Primary bool `json:"primary"`
+
+	// Preference is used to define the BIRD preference of the route.
+	Preference *int `json:"preference"`
+
+	// Source is used to define the source of the route, such as BGP or static.
+	Source string `json:"source"`
+
+	// Origin is set when the route has a BGP origin, such as IGP or Incomplete.
+	Origin *string `json:"origin"`
+
+	// MED is set when the route has a multi-exit discriminator.
+	MED *int `json:"med"`
+
+	// Aggregator is set when the route has been aggregated.
+	Aggregator *BGPAggregator `json:"aggregator"`
+
+	// AtomicAggregate is true when the route has the atomic aggregate attribute.
+	AtomicAggregate bool `json:"atomic_aggregate"`
+
+	// OriginatorID is set when the route was reflected by a route reflector.
+	OriginatorID *string `json:"originator_id"`
+
+	// ClusterList is set when the route was reflected by a route reflector.
+	ClusterList []string `json:"cluster_list"`
+
 	// AsPath is set when the route has an AS path.
 	AsPath []int `json:"as_path"`
 
+	// AsPathInfo is used to define the name and country of each AS in the AS path, with prepends collapsed.
+	AsPathInfo []asinfo.Hop `json:"as_path_info"`
+
 	// LocalPref is set when the route has a local preference.
 	LocalPref *int `json:"local_pref"`
 
@@ -104,6 +192,15 @@ type BGPRoute struct {
 
 	// LargeCommunity is used to define a large BGP community.
 	LargeCommunity []string `json:"large_community"`
+
+	// ExtCommunity is used to define an extended BGP community.
+	ExtCommunity []string `json:"ext_community"`
+
+	// CommunityMeanings is used to define the meanings of the communities of the route which are known.
+	CommunityMeanings []community.Decoded `json:"community_meanings"`
+
+	// RPKI is set when RPKI validation is configured and the route has an origin AS.
+	RPKI *rpki.Result `json:"rpki,omitempty"`
 }
 
 // BGPRouteSlice is used to define a slice of BGP routes that implements sort.Interface.
@@ -129,179 +226,363 @@ func (v BGPRouteSlice) Less(i, j int) bool {
 	return len(x.AsPath) < len(y.AsPath)
 }
 
-func makeBirdSocket() (io.ReadWriteCloser, error) {
-	return net.Dial("unix", "/run/bird/bird.ctl")
+// Sets the RPKI validation state on each route from its prefix and origin AS.
+func (v BGPRouteSlice) validate(validator *rpki.Validator) {
+	for _, route := range v {
+		if route.Prefix == nil || len(route.AsPath) == 0 {
+			continue
+		}
+		prefix, err := netip.ParsePrefix(*route.Prefix)
+		if err != nil {
+			continue
+		}
+		result := validator.Validate(prefix, uint32(route.AsPath[len(route.AsPath)-1]))
+		route.RPKI = &result
+	}
 }
 
-func bgp(g group, socketBuilder func() (io.ReadWriteCloser, error)) {
-	f := func(context *gin.Context) {
-		// Get the IP address.
-		ip, _ := url.PathUnescape(context.Param("ip"))
-		rangeChunk := context.Param("range")
-		if rangeChunk != "" {
-			ip += "/" + rangeChunk
+// Sets the information about each AS in the AS path of each route.
+func (v BGPRouteSlice) resolveASNames(resolver *asinfo.Resolver) {
+	// Look up every AS in one go so they are resolved concurrently.
+	asns := []uint32{}
+	for _, route := range v {
+		for _, asn := range route.AsPath {
+			asns = append(asns, uint32(asn))
 		}
+	}
+	infos := resolver.LookupAll(asns)
 
-		// Defines if this is JSON.
-		isJson := context.ContentType() == "application/json"
+	for _, route := range v {
+		if route.AsPath == nil {
+			continue
+		}
+		route.AsPathInfo = asinfo.CollapsePath(route.AsPath)
+		for i := range route.AsPathInfo {
+			route.AsPathInfo[i].Info = infos[route.AsPathInfo[i].ASN]
+		}
+	}
+}
 
-		// Check the type of query we should make.
-		queryType := ip
-		if !ipRangeRegex.MatchString(ip) {
-			if !ipRegex.MatchString(ip) {
-				// In this situation, this isn't a valid IP address, and we should return.
-				if isJson {
-					context.JSON(400, map[string]string{
-						"message": "Invalid IP address.",
-					})
-				} else {
-					context.String(400, "Invalid IP address.")
-				}
-				return
+// Sets the meanings of the communities on each route.
+func (v BGPRouteSlice) decodeCommunities(dictionary *community.Dictionary) {
+	for _, route := range v {
+		route.CommunityMeanings = dictionary.Decode(route.Community, route.LargeCommunity, route.ExtCommunity)
+	}
+}
+
+// Returns the information about the routes that isn't in the raw BIRD output, for appending to it.
+func (v BGPRouteSlice) annotations() string {
+	pathStr := ""
+	rpkiStr := ""
+	communityStr := ""
+	for _, route := range v {
+		if len(route.AsPathInfo) != 0 {
+			hops := make([]string, len(route.AsPathInfo))
+			for i, hop := range route.AsPathInfo {
+				hops[i] = hop.String()
 			}
-			queryType = "for " + queryType
+			pathStr += *route.Prefix + " via " + route.Protocol + ": " + strings.Join(hops, " > ") + "\n"
 		}
 
-		// Make the socket.
-		conn, err := socketBuilder()
-		if err != nil {
-			context.Error(err)
-			return
+		if route.RPKI != nil {
+			rpkiStr += *route.Prefix + " AS" + strconv.Itoa(route.AsPath[len(route.AsPath)-1]) + " via " +
+				route.Protocol + ": " + string(route.RPKI.State)
+			roas := []string{}
+			for _, vrp := range route.RPKI.Covering {
+				roas = append(roas, vrp.Prefix.String()+"-"+strconv.Itoa(vrp.MaxLength)+" AS"+
+					strconv.FormatUint(uint64(vrp.ASN), 10))
+			}
+			if len(roas) != 0 {
+				rpkiStr += " (" + strings.Join(roas, ", ") + ")"
+			}
+			rpkiStr += "\n"
 		}
-		defer conn.Close()
-
-		// Allocate a 100KB page.
-		b := make([]byte, 100*1024)
 
-		// Drain the start sequence.
-		_, err = conn.Read(b)
-		if err != nil {
-			context.Error(err)
-			return
+		if len(route.CommunityMeanings) != 0 {
+			communityStr += *route.Prefix + " via " + route.Protocol + ":\n"
+			for _, c := range route.CommunityMeanings {
+				communityStr += "\t" + c.Community + " - " + c.Meaning + " (" + c.Source + ")\n"
+			}
 		}
+	}
 
-		// Perform the query.
-		query := "show route " + queryType + " all\n"
-		_, err = conn.Write([]byte(query))
-		if err != nil {
-			context.Error(err)
-			return
-		}
+	str := ""
+	if pathStr != "" {
+		str += "\nAS paths:\n" + pathStr
+	}
+	if rpkiStr != "" {
+		str += "\nRPKI:\n" + rpkiStr
+	}
+	if communityStr != "" {
+		str += "\nCommunities:\n" + communityStr
+	}
+	return str
+}
 
-		// Read the response.
-		n, err := conn.Read(b)
-		if err != nil {
-			context.Error(err)
-			return
+// Parses the routes from a BIRD reply and sorts them.
+func parseBgpRoutes(lines []bird.Line) (BGPRouteSlice, error) {
+	// Convert the lines from the reply.
+	chunks := make([]bgpLine, len(lines))
+	for i, v := range lines {
+		chunks[i] = bgpLine{
+			Code:   v.Code,
+			Line:   strings.Trim(v.Text, " \t\r"),
+			IsCont: v.IsCont,
 		}
-		b = append([]byte(nil), b[:n]...)
+	}
 
-		// Now we are done with bird, close the connection.
-		_ = conn.Close()
+	// Handle making all the routes.
+	routes := BGPRouteSlice{}
 
-		// Check if this is a bird syntax error. This would mean that the IP address/range is not valid.
-		// Note that no other errors are relevant here since we've already checked the IP address.
-		if bytes.HasPrefix(b, []byte("9001 ")) {
-			context.Error(&gin.Error{
-				Err:  errors.New("bird lookup failed: " + string(b[5:])),
-				Type: gin.ErrorTypePublic,
-			})
-			return
+	// Loop through the chunks to make each route.
+	for _, v := range chunks {
+		type_, clean := v.Type()
+		if type_ != routeHeader && len(routes) == 0 {
+			// Attributes can't be before the first route.
+			continue
 		}
+		switch type_ {
+		case routeHeader:
+			m := routeHeaderRe.FindStringSubmatch(clean)
+			prefix := m[1]
+			if prefix == "" && len(routes) != 0 {
+				// Routes after the first for a prefix do not repeat it.
+				prefix = *routes[len(routes)-1].Prefix
+			}
+			route := &BGPRoute{
+				Prefix:   &prefix,
+				Type:     m[2],
+				Protocol: m[3],
+				Since:    m[4],
+				Primary:  m[6] != "",
+			}
+			if m[5] != "" {
+				route.From = &m[5]
+			}
+			if m[7] != "" {
+				x, _ := strconv.Atoi(m[7])
+				route.Preference = &x
+			}
+			routes = append(routes, route)
+		case routeType:
+			routes[len(routes)-1].Source = strings.SplitN(clean, " ", 2)[0]
+		case routeBgpAsPath:
+			x := make([]int, 0)
+			for _, v := range strings.Split(clean, " ") {
+				i, err := strconv.Atoi(v)
+				if err == nil {
+					x = append(x, i)
+				}
+			}
+			routes[len(routes)-1].AsPath = x
+		case routeBgpLocalPref:
+			x, err := strconv.Atoi(clean)
+			if err != nil {
+				return nil, err
+			}
+			routes[len(routes)-1].LocalPref = &x
+		case routeBgpNextHop:
+			routes[len(routes)-1].NextHop = &clean
+		case routeBgpCommunity:
+			a := smallCommunityRe.FindAllString(clean, -1)
+			if a == nil {
+				return nil, errors.New("string not correct format for bgp community")
+			}
+			routes[len(routes)-1].Community = a
+		case routeBgpLargeCommunity:
+			a := largeCommunityRe.FindAllString(clean, -1)
+			if a == nil {
+				return nil, errors.New("string not correct format for bgp large community")
+			}
+			routes[len(routes)-1].LargeCommunity = a
+		case routeBgpExtCommunity:
+			a := extCommunityRe.FindAllString(clean, -1)
+			if a == nil {
+				return nil, errors.New("string not correct format for bgp extended community")
+			}
+			routes[len(routes)-1].ExtCommunity = a
+		case routeBgpOrigin:
+			routes[len(routes)-1].Origin = &clean
+		case routeBgpMed:
+			x, err := strconv.Atoi(clean)
+			if err != nil {
+				return nil, err
+			}
+			routes[len(routes)-1].MED = &x
+		case routeBgpAggregator:
+			split := strings.SplitN(clean, " ", 2)
+			if len(split) != 2 || !strings.HasPrefix(split[1], "AS") {
+				return nil, errors.New("string not correct format for bgp aggregator")
+			}
+			asn, err := strconv.Atoi(split[1][2:])
+			if err != nil {
+				return nil, err
+			}
+			routes[len(routes)-1].Aggregator = &BGPAggregator{Address: split[0], ASN: asn}
+		case routeBgpAtomicAggr:
+			routes[len(routes)-1].AtomicAggregate = true
+		case routeBgpOriginatorId:
+			routes[len(routes)-1].OriginatorID = &clean
+		case routeBgpClusterList:
+			routes[len(routes)-1].ClusterList = strings.Fields(clean)
+		}
+	}
 
-		// If the content type isn't JSON, return it here.
-		if !isJson {
-			context.String(200, string(b))
-			return
+	// Sort the slice.
+	sort.Sort(routes)
+	return routes, nil
+}
+
+// Adds the RPKI validation states, AS names and community meanings to the routes. Any of these can be nil to skip
+// them.
+func (v BGPRouteSlice) enrich(
+	validator *rpki.Validator, communities *community.Dictionary, asNames *asinfo.Resolver,
+) {
+	if validator != nil && validator.Ready() {
+		v.validate(validator)
+	}
+	if asNames != nil {
+		v.resolveASNames(asNames)
+	}
+	if communities != nil {
+		v.decodeCommunities(communities)
+	}
+}
+
+// String returns the routes in a similar format to BIRD. This is used for route sources without human readable
+// output.
+func (v BGPRouteSlice) String() string {
+	if len(v) == 0 {
+		return "Network not found\n"
+	}
+
+	str := ""
+	for _, route := range v {
+		str += *route.Prefix + " " + route.Type + " [" + route.Protocol
+		if route.Since != "" {
+			str += " " + route.Since
+		}
+		if route.From != nil {
+			str += " from " + *route.From
 		}
+		str += "]"
+		if route.Primary {
+			str += " *"
+		}
+		if route.Preference != nil {
+			str += " (" + strconv.Itoa(*route.Preference) + ")"
+		}
+		str += "\n"
 
-		// Remove any new lines from hte slice.
-		b = bytes.Trim(b, "\n\r")
-
-		// Defines the lines.
-		lines := bytes.Split(b, []byte("\n"))
-		chunks := []bgpLine{}
-		for _, v := range lines {
-			if v[0] == ' ' {
-				// Add a new line with the last code.
-				lastCode := chunks[len(chunks)-1].Code
-				chunks = append(chunks, bgpLine{
-					Code:   lastCode,
-					Line:   strings.Trim(string(v[1:]), " \t\r"),
-					IsCont: true,
-				})
-				continue
+		attr := func(name, value string) {
+			str += "\t" + name + ":"
+			if value != "" {
+				str += " " + value
+			}
+			str += "\n"
+		}
+		if route.Source != "" {
+			attr("Type", route.Source)
+		}
+		if route.Origin != nil {
+			attr("BGP.origin", *route.Origin)
+		}
+		if route.AsPath != nil {
+			asns := make([]string, len(route.AsPath))
+			for i, asn := range route.AsPath {
+				asns[i] = strconv.Itoa(asn)
+			}
+			attr("BGP.as_path", strings.Join(asns, " "))
+		}
+		if route.NextHop != nil {
+			attr("BGP.next_hop", *route.NextHop)
+		}
+		if route.MED != nil {
+			attr("BGP.med", strconv.Itoa(*route.MED))
+		}
+		if route.LocalPref != nil {
+			attr("BGP.local_pref", strconv.Itoa(*route.LocalPref))
+		}
+		if route.AtomicAggregate {
+			attr("BGP.atomic_aggr", "")
+		}
+		if route.Aggregator != nil {
+			attr("BGP.aggregator", route.Aggregator.Address+" AS"+strconv.Itoa(route.Aggregator.ASN))
+		}
+		if route.OriginatorID != nil {
+			attr("BGP.originator_id", *route.OriginatorID)
+		}
+		if route.ClusterList != nil {
+			attr("BGP.cluster_list", strings.Join(route.ClusterList, " "))
+		}
+		communities := func(name string, list []string) {
+			if list != nil {
+				attr(name, "("+strings.Join(list, ") (")+")")
 			}
+		}
+		communities("BGP.community", route.Community)
+		communities("BGP.ext_community", route.ExtCommunity)
+		communities("BGP.large_community", route.LargeCommunity)
+	}
+	return str
+}
 
-			// Get the code.
-			code := string(v[:4])
+func bgp(
+	g group, source RouteSource, validator *rpki.Validator, communities *community.Dictionary,
+	asNames *asinfo.Resolver,
+) {
+	f := func(context *gin.Context) {
+		// Get the IP address.
+		ip, _ := url.PathUnescape(context.Param("ip"))
+		rangeChunk := context.Param("range")
+		if rangeChunk != "" {
+			ip += "/" + rangeChunk
+		}
 
-			// Append to the chunk.
-			chunks = append(chunks, bgpLine{
-				Code: code,
-				Line: strings.Trim(string(v[5:]), " \t\r"),
-			})
+		// Defines if this is JSON.
+		isJson := context.ContentType() == "application/json"
 
-			// If v[4] is a space, we should break.
-			if v[4] == ' ' {
-				break
+		// Check the type of query we should make.
+		query, ok := parseRouteQuery(ip)
+		if !ok {
+			// In this situation, this isn't a valid IP address, and we should return.
+			if isJson {
+				context.JSON(400, map[string]string{
+					"message": "Invalid IP address.",
+				})
+			} else {
+				context.String(400, "Invalid IP address.")
 			}
+			return
 		}
 
-		// Handle making all the routes.
-		routes := BGPRouteSlice{}
-
-		// Loop through the chunks to make each route.
-		for _, v := range chunks {
-			switch type_, clean := v.Type(); type_ {
-			case routeHeader:
-				prefix := clean
-				if prefix != "" {
-					if len(routes) != 0 {
-						p := routes[len(routes)-1].Prefix
-						if p != nil {
-							prefix = *p
-						}
-					}
-				}
-				routes = append(routes, &BGPRoute{Prefix: &prefix})
-			case routeBgpAsPath:
-				x := make([]int, 0)
-				for _, v := range strings.Split(clean, " ") {
-					i, err := strconv.Atoi(v)
-					if err == nil {
-						x = append(x, i)
-					}
-				}
-				routes[len(routes)-1].AsPath = x
-			case routeBgpLocalPref:
-				x, err := strconv.Atoi(clean)
-				if err != nil {
-					context.Error(err)
-					return
-				}
-				routes[len(routes)-1].LocalPref = &x
-			case routeBgpNextHop:
-				routes[len(routes)-1].NextHop = &clean
-			case routeBgpCommunity:
-				a := smallCommunityRe.FindAllString(clean, -1)
-				if a == nil {
-					context.Error(errors.New("string not correct format for bgp community"))
-					return
-				}
-				routes[len(routes)-1].Community = a
-			case routeBgpLargeCommunity:
-				a := largeCommunityRe.FindAllString(clean, -1)
-				if a == nil {
-					context.Error(errors.New("string not correct format for bgp large community"))
-					return
-				}
-				routes[len(routes)-1].LargeCommunity = a
+		// Look up the routes.
+		routes, raw, err := source.Lookup(query)
+		if err != nil {
+			var sourceErr *routeSourceError
+			switch {
+			case raw != "" && !isJson:
+				// The raw output is still useful if the routes couldn't be parsed.
+				context.String(200, raw)
+			case errors.As(err, &sourceErr):
+				context.Error(&gin.Error{Err: sourceErr, Type: gin.ErrorTypePublic})
+			default:
+				context.Error(err)
 			}
+			return
 		}
 
-		// Sort the slice.
-		sort.Sort(routes)
+		// Add the information which isn't in the routing table.
+		routes.enrich(validator, communities, asNames)
+
+		// If the content type isn't JSON, return the output with the extra information.
+		if !isJson {
+			if raw == "" {
+				raw = routes.String()
+			}
+			context.String(200, raw+routes.annotations())
+			return
+		}
 
 		// Return the routes.
 		context.JSON(200, routes)
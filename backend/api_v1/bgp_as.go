@@ -0,0 +1,240 @@
+package api_v1
+
+import (
+	"errors"
+	"net/netip"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/krystal/krystal-network-tools/backend/asinfo"
+)
+
+// Defines the most routes which are returned for an AS. This stops the routes of the largest networks being loaded
+// into memory and sent in one response.
+const maxOriginRoutes = 10000
+
+// OriginSource is used to define a route source which can list the routes originated by an AS.
+type OriginSource interface {
+	// LookupOrigin returns the best route for every prefix with an AS path ending in the AS. If more routes than
+	// the limit match, a *tooManyRoutesError is returned.
+	LookupOrigin(asn uint32, limit int) (BGPRouteSlice, error)
+}
+
+// ASPrefix is used to define a prefix originated by an AS.
+type ASPrefix struct {
+	// Prefix is used to define the prefix.
+	Prefix string `json:"prefix"`
+
+	// CoveredBy is set to the most specific other prefix from the AS which covers this prefix.
+	CoveredBy *string `json:"covered_by"`
+
+	// MoreSpecifics is used to define the number of other prefixes from the AS which this prefix covers.
+	MoreSpecifics int `json:"more_specifics"`
+}
+
+// ASPrefixCounts is used to define the number of prefixes an AS originates in an address family.
+type ASPrefixCounts struct {
+	// Total is used to define the total number of prefixes.
+	Total int `json:"total"`
+
+	// Aggregates is used to define the number of prefixes which cover other prefixes and aren't covered by one.
+	Aggregates int `json:"aggregates"`
+
+	// MoreSpecifics is used to define the number of prefixes which are covered by another prefix.
+	MoreSpecifics int `json:"more_specifics"`
+
+	// Standalone is used to define the number of prefixes which don't cover or get covered by another prefix.
+	Standalone int `json:"standalone"`
+}
+
+// String returns the counts in a human readable format.
+func (c ASPrefixCounts) String() string {
+	return strconv.Itoa(c.Total) + " prefixes (" + strconv.Itoa(c.Aggregates) + " aggregates, " +
+		strconv.Itoa(c.MoreSpecifics) + " more specifics, " + strconv.Itoa(c.Standalone) + " standalone)"
+}
+
+// ASPrefixes is used to define the prefixes originated by an AS.
+type ASPrefixes struct {
+	// AS is used to define the AS and what is known about it.
+	AS asinfo.Info `json:"as"`
+
+	// IPv4 is used to define the counts of IPv4 prefixes.
+	IPv4 ASPrefixCounts `json:"ipv4"`
+
+	// IPv6 is used to define the counts of IPv6 prefixes.
+	IPv6 ASPrefixCounts `json:"ipv6"`
+
+	// Prefixes is used to define every prefix, with IPv4 first and then in address order.
+	Prefixes []ASPrefix `json:"prefixes"`
+}
+
+// String returns the prefixes in a human readable format.
+func (p *ASPrefixes) String() string {
+	str := p.AS.String() + "\nIPv4: " + p.IPv4.String() + "\nIPv6: " + p.IPv6.String() + "\n"
+	if len(p.Prefixes) != 0 {
+		str += "\n"
+	}
+	for _, v := range p.Prefixes {
+		str += v.Prefix
+		if v.MoreSpecifics != 0 {
+			str += " (aggregate of " + strconv.Itoa(v.MoreSpecifics) + ")"
+		}
+		if v.CoveredBy != nil {
+			str += " (within " + *v.CoveredBy + ")"
+		}
+		str += "\n"
+	}
+	return str
+}
+
+// Works out which of the prefixes originated by an AS cover each other and counts them.
+func summariseASPrefixes(info asinfo.Info, routes BGPRouteSlice) *ASPrefixes {
+	// Get the unique prefixes.
+	set := map[netip.Prefix]bool{}
+	for _, route := range routes {
+		if route.Prefix == nil {
+			continue
+		}
+		if prefix, err := netip.ParsePrefix(*route.Prefix); err == nil {
+			set[prefix.Masked()] = true
+		}
+	}
+	prefixes := make([]netip.Prefix, 0, len(set))
+	for prefix := range set {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Slice(prefixes, func(i, j int) bool {
+		a, b := prefixes[i], prefixes[j]
+		if a.Addr().Is4() != b.Addr().Is4() {
+			return a.Addr().Is4()
+		}
+		if c := a.Addr().Compare(b.Addr()); c != 0 {
+			return c < 0
+		}
+		return a.Bits() < b.Bits()
+	})
+
+	// Find the most specific covering prefix of each prefix.
+	result := &ASPrefixes{AS: info, Prefixes: make([]ASPrefix, len(prefixes))}
+	index := make(map[netip.Prefix]int, len(prefixes))
+	for i, prefix := range prefixes {
+		index[prefix] = i
+		result.Prefixes[i].Prefix = prefix.String()
+	}
+	for i, prefix := range prefixes {
+		for bits := prefix.Bits() - 1; bits >= 0; bits-- {
+			covering, _ := prefix.Addr().Prefix(bits)
+			if j, ok := index[covering]; ok {
+				result.Prefixes[i].CoveredBy = &result.Prefixes[j].Prefix
+				break
+			}
+		}
+	}
+
+	// Count how many prefixes each prefix covers, including ones covered through another prefix.
+	for _, prefix := range prefixes {
+		for bits := prefix.Bits() - 1; bits >= 0; bits-- {
+			covering, _ := prefix.Addr().Prefix(bits)
+			if j, ok := index[covering]; ok {
+				result.Prefixes[j].MoreSpecifics++
+			}
+		}
+	}
+
+	// Count the prefixes in each address family.
+	for i, prefix := range prefixes {
+		counts := &result.IPv4
+		if prefix.Addr().Is6() {
+			counts = &result.IPv6
+		}
+		counts.Total++
+		v := result.Prefixes[i]
+		switch {
+		case v.CoveredBy != nil:
+			counts.MoreSpecifics++
+		case v.MoreSpecifics != 0:
+			counts.Aggregates++
+		default:
+			counts.Standalone++
+		}
+	}
+	return result
+}
+
+// Parses an ASN with or without the AS prefix. False is returned if it is not valid.
+func parseASN(s string) (uint32, bool) {
+	if len(s) > 2 && strings.EqualFold(s[:2], "AS") {
+		s = s[2:]
+	}
+	asn, err := strconv.ParseUint(s, 10, 32)
+	return uint32(asn), err == nil
+}
+
+func bgpAS(g group, source RouteSource, asNames *asinfo.Resolver) {
+	g.GET("/as/:asn", func(context *gin.Context) {
+		// Defines if this is JSON.
+		isJson := context.ContentType() == "application/json"
+
+		// Get the ASN.
+		asn, ok := parseASN(context.Param("asn"))
+		if !ok {
+			if isJson {
+				context.JSON(400, map[string]string{
+					"message": "Invalid ASN.",
+				})
+			} else {
+				context.String(400, "Invalid ASN.")
+			}
+			return
+		}
+
+		// Check the route source supports this.
+		originSource, ok := source.(OriginSource)
+		if !ok {
+			context.Error(&gin.Error{
+				Err:  errors.New("looking up prefixes by origin is not supported by this region's router"),
+				Type: gin.ErrorTypePublic,
+			})
+			return
+		}
+
+		// Look up the routes.
+		routes, err := originSource.LookupOrigin(asn, maxOriginRoutes)
+		if err != nil {
+			var (
+				sourceErr  *routeSourceError
+				tooManyErr *tooManyRoutesError
+			)
+			switch {
+			case errors.As(err, &sourceErr):
+				context.Error(&gin.Error{Err: sourceErr, Type: gin.ErrorTypePublic})
+			case errors.As(err, &tooManyErr):
+				context.Error(&gin.Error{
+					Err: errors.New("AS" + strconv.FormatUint(uint64(asn), 10) + " originates " +
+						strconv.Itoa(tooManyErr.count) + " routes, which is more than the limit of " +
+						strconv.Itoa(tooManyErr.limit) + ". Search for its routes with narrower filters instead."),
+					Type: gin.ErrorTypePublic,
+				})
+			default:
+				context.Error(err)
+			}
+			return
+		}
+
+		// Work out the breakdown of the prefixes.
+		info := asinfo.Info{ASN: asn}
+		if asNames != nil {
+			info, _ = asNames.Lookup(asn)
+		}
+		result := summariseASPrefixes(info, routes)
+
+		// Return the result.
+		if isJson {
+			context.JSON(200, result)
+		} else {
+			context.String(200, result.String())
+		}
+	})
+}
@@ -0,0 +1,144 @@
+package api_v1
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jimeh/go-golden"
+	"github.com/krystal/krystal-network-tools/backend/asinfo"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_bgpAS(t *testing.T) {
+	tests := []struct {
+		name string
+
+		tapeFile string
+		writes   []string
+		source   RouteSource
+		asn      string
+		json     bool
+		code     int
+		wantErr  string
+	}{
+		{
+			name:     "successful json",
+			tapeFile: "as13335.json",
+			writes: []string{
+				"show route where net.type = NET_IP4 && bgp_path.last = 13335 primary count\n",
+				"show route table master6 where net.type = NET_IP6 && bgp_path.last = 13335 primary count\n",
+				"show route where net.type = NET_IP4 && bgp_path.last = 13335 primary\n",
+				"show route table master6 where net.type = NET_IP6 && bgp_path.last = 13335 primary\n",
+			},
+			asn:  "13335",
+			json: true,
+			code: http.StatusOK,
+		},
+		{
+			name:     "successful text",
+			tapeFile: "as13335.json",
+			writes: []string{
+				"show route where net.type = NET_IP4 && bgp_path.last = 13335 primary count\n",
+				"show route table master6 where net.type = NET_IP6 && bgp_path.last = 13335 primary count\n",
+				"show route where net.type = NET_IP4 && bgp_path.last = 13335 primary\n",
+				"show route table master6 where net.type = NET_IP6 && bgp_path.last = 13335 primary\n",
+			},
+			asn:  "AS13335",
+			code: http.StatusOK,
+		},
+		{
+			name:     "no prefixes text",
+			tapeFile: "as64500.json",
+			writes: []string{
+				"show route where net.type = NET_IP4 && bgp_path.last = 64500 primary count\n",
+				"show route table master6 where net.type = NET_IP6 && bgp_path.last = 64500 primary count\n",
+				"show route where net.type = NET_IP4 && bgp_path.last = 64500 primary\n",
+				"show route table master6 where net.type = NET_IP6 && bgp_path.last = 64500 primary\n",
+			},
+			asn:  "64500",
+			code: http.StatusOK,
+		},
+		{
+			name:     "too many routes",
+			tapeFile: "as_too_many.json",
+			writes: []string{
+				"show route where net.type = NET_IP4 && bgp_path.last = 174 primary count\n",
+				"show route table master6 where net.type = NET_IP6 && bgp_path.last = 174 primary count\n",
+			},
+			asn: "174",
+			wantErr: "AS174 originates 10500 routes, which is more than the limit of 10000. " +
+				"Search for its routes with narrower filters instead.",
+		},
+		{
+			name: "invalid asn",
+			asn:  "AS13335x",
+			json: true,
+			code: http.StatusBadRequest,
+		},
+		{
+			name:    "unsupported source",
+			source:  frrSource{},
+			asn:     "13335",
+			wantErr: "looking up prefixes by origin is not supported by this region's router",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Create the route source.
+			source := tt.source
+			if source == nil {
+				var mocker io.ReadWriteCloser
+				if tt.tapeFile != "" {
+					mocker = newBgpTape(t, tt.tapeFile, tt.writes)
+				}
+				source = birdSource{
+					socketBuilder: func() (io.ReadWriteCloser, error) { return mocker, nil },
+					tables:        bgpTables{IPv6: "master6"},
+				}
+			}
+
+			// Create the AS name resolver.
+			db := asinfo.NewDatabase()
+			db.Replace([]asinfo.Info{{ASN: 13335, Name: "CLOUDFLARENET", Country: "US"}})
+			asNames := asinfo.NewResolver(db, nil)
+
+			// Get the handler.
+			hn := mockGroupSingleHn(t, "GET", "/as/:asn", func(g group) {
+				bgpAS(g, source, asNames)
+			})
+			if hn == nil {
+				return
+			}
+
+			// Call the handler.
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Params = gin.Params{{Key: "asn", Value: tt.asn}}
+			c.Request = &http.Request{
+				URL:    &url.URL{Path: "/as/" + tt.asn},
+				Header: http.Header{},
+			}
+			if tt.json {
+				c.Request.Header.Set("Content-Type", "application/json")
+			}
+			hn(c)
+
+			// Check the result.
+			if tt.wantErr != "" {
+				if assert.Len(t, c.Errors, 1) {
+					assert.EqualError(t, c.Errors[0], tt.wantErr)
+				}
+				return
+			}
+			assert.Equal(t, tt.code, w.Code)
+			if golden.Update() {
+				golden.Set(t, attemptJsonBeautify(w.Body.Bytes()))
+			}
+			assert.Equal(t, string(golden.Get(t)), string(attemptJsonBeautify(w.Body.Bytes())))
+		})
+	}
+}
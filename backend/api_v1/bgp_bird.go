@@ -0,0 +1,351 @@
+package api_v1
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/netip"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/krystal/krystal-network-tools/backend/bird"
+)
+
+func makeBirdSocket() (io.ReadWriteCloser, error) {
+	return net.DialTimeout("unix", "/run/bird/bird.ctl", bird.DefaultTimeout)
+}
+
+// bgpTables is used to define the BIRD tables to look up each address family in.
+type bgpTables struct {
+	// IPv4 is used to define the table for IPv4 lookups. If this is blank, BIRD picks the table.
+	IPv4 string
+
+	// IPv6 is used to define the table for IPv6 lookups. If this is blank, BIRD picks the table.
+	IPv6 string
+}
+
+// Gets the BIRD tables from the environment. BIRD_TABLE_IPV4 and BIRD_TABLE_IPV6 can be set to change them.
+func getBgpTables() bgpTables {
+	tables := bgpTables{IPv4: os.Getenv("BIRD_TABLE_IPV4"), IPv6: os.Getenv("BIRD_TABLE_IPV6")}
+	if tables.IPv6 == "" {
+		tables.IPv6 = "master6"
+	}
+	return tables
+}
+
+// Returns the part of the show route command for a query, including the table for its address family.
+func bgpQuery(q RouteQuery, tables bgpTables) string {
+	query := q.String()
+	if q.Address {
+		query = "for " + query
+	}
+	if q.Prefix.Addr().Is6() {
+		return query + birdTableOption(tables.IPv6)
+	}
+	return query + birdTableOption(tables.IPv4)
+}
+
+// birdSource is used to look up routes in BIRD through its control socket.
+type birdSource struct {
+	// Defines the function used to connect to the control socket.
+	socketBuilder func() (io.ReadWriteCloser, error)
+
+	// Defines the tables to look up each address family in.
+	tables bgpTables
+}
+
+// Connects to BIRD and calls fn with the client, so it can run commands which depend on the replies to earlier
+// ones. BIRD errors are turned into errors which can be shown to the user.
+func (s birdSource) session(fn func(client *bird.Client) error) error {
+	// Make the socket.
+	conn, err := s.socketBuilder()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// Read the banner and perform the queries.
+	client, err := bird.NewClient(conn, bird.DefaultTimeout)
+	if err != nil {
+		return err
+	}
+	err = fn(client)
+
+	// Now we are done with bird, close the connection.
+	_ = client.Close()
+
+	// Check if this is a bird error. A syntax error would mean that the IP address/range is not valid.
+	if err != nil {
+		var birdErr *bird.Error
+		if errors.As(err, &birdErr) {
+			return &routeSourceError{source: "bird", message: birdErr.Message}
+		}
+		return err
+	}
+	return nil
+}
+
+// Runs commands on BIRD with one connection and returns the replies.
+func (s birdSource) query(commands ...string) ([]*bird.Reply, error) {
+	replies := make([]*bird.Reply, len(commands))
+	err := s.session(func(client *bird.Client) error {
+		for i, command := range commands {
+			var err error
+			replies[i], err = client.Query(command)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return replies, nil
+}
+
+// Lookup implements RouteSource.
+func (s birdSource) Lookup(q RouteQuery) (BGPRouteSlice, string, error) {
+	replies, err := s.query("show route " + bgpQuery(q, s.tables) + " all")
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Parse the routes. The raw output is still returned if this fails since it is still useful.
+	routes, err := parseBgpRoutes(replies[0].Lines)
+	return routes, replies[0].Raw, err
+}
+
+// Returns the table option of a show route command for a table, which is blank if BIRD should pick the table.
+func birdTableOption(table string) string {
+	if table == "" {
+		return ""
+	}
+	return " table " + table
+}
+
+// Returns the table option and where clause of a show route command which filters the routes of an address
+// family. BIRD looks in every table if no table is given, so the networks are limited to the address family as
+// well, otherwise IPv6 routes would be included in the IPv4 results.
+func birdFamilyWhere(tables bgpTables, v6 bool, condition string) string {
+	if v6 {
+		return birdTableOption(tables.IPv6) + " where net.type = NET_IP6 && " + condition
+	}
+	return birdTableOption(tables.IPv4) + " where net.type = NET_IP4 && " + condition
+}
+
+// LookupOrigin implements OriginSource.
+func (s birdSource) LookupOrigin(asn uint32, limit int) (BGPRouteSlice, error) {
+	condition := "bgp_path.last = " + strconv.FormatUint(uint64(asn), 10)
+	return s.limitedRoutes([]bool{false, true}, condition, " primary", "", limit)
+}
+
+// Parses the number of routes from the reply to a show route count command. This has a line such as
+// "0014 3 of 3 routes for 3 networks in table master4" for each table it looked in, followed by a line such as
+// "0014 Total: 5 of 5 routes for 5 networks in 2 tables" if there was more than one.
+func parseBirdRouteCount(lines []bird.Line) (int, error) {
+	count := 0
+	found := false
+	for _, line := range lines {
+		if line.Code != "0014" {
+			continue
+		}
+		text := strings.TrimSpace(line.Text)
+		total := strings.HasPrefix(text, "Total: ")
+		n, err := strconv.Atoi(strings.SplitN(strings.TrimPrefix(text, "Total: "), " ", 2)[0])
+		if err != nil {
+			return 0, err
+		}
+		if total {
+			return n, nil
+		}
+		count += n
+		found = true
+	}
+	if !found {
+		return 0, errors.New("no route count in bird reply")
+	}
+	return count, nil
+}
+
+// Parses the routes from the reply to a show route command, leaving out any which aren't in the address family.
+func parseBirdFamilyRoutes(lines []bird.Line, v6 bool) (BGPRouteSlice, error) {
+	routes, err := parseBgpRoutes(lines)
+	if err != nil {
+		return nil, err
+	}
+	familyRoutes := BGPRouteSlice{}
+	for _, route := range routes {
+		if route.Prefix == nil {
+			continue
+		}
+		prefix, err := netip.ParsePrefix(*route.Prefix)
+		if err == nil && prefix.Addr().Is6() == v6 {
+			familyRoutes = append(familyRoutes, route)
+		}
+	}
+	return familyRoutes, nil
+}
+
+// Runs show route commands for each address family on one connection. The routes are counted first so huge replies
+// are never sent, and a *tooManyRoutesError is returned if more than the limit match. The options are added to both
+// commands, and the fetch options are only added to the command which gets the routes. The routes fetched are
+// checked against the limit too since routes can be added after they are counted.
+func (s birdSource) limitedRoutes(
+	families []bool, condition, options, fetchOptions string, limit int,
+) (BGPRouteSlice, error) {
+	routes := BGPRouteSlice{}
+	err := s.session(func(client *bird.Client) error {
+		// Count the routes.
+		count := 0
+		for _, v6 := range families {
+			reply, err := client.Query("show route" + birdFamilyWhere(s.tables, v6, condition) + options + " count")
+			if err != nil {
+				return err
+			}
+			n, err := parseBirdRouteCount(reply.Lines)
+			if err != nil {
+				return err
+			}
+			count += n
+		}
+		if count > limit {
+			return &tooManyRoutesError{count: count, limit: limit}
+		}
+
+		// Get the routes.
+		for _, v6 := range families {
+			reply, err := client.Query("show route" + birdFamilyWhere(s.tables, v6, condition) + options + fetchOptions)
+			if err != nil {
+				return err
+			}
+			r, err := parseBirdFamilyRoutes(reply.Lines, v6)
+			if err != nil {
+				return err
+			}
+			routes = append(routes, r...)
+		}
+		if len(routes) > limit {
+			return &tooManyRoutesError{count: len(routes), limit: limit}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return routes, nil
+}
+
+// SearchRoutes implements SearchSource.
+func (s birdSource) SearchRoutes(filter RouteFilter, limit int) (BGPRouteSlice, error) {
+	families := []bool{}
+	if filter.IPv4 {
+		families = append(families, false)
+	}
+	if filter.IPv6 {
+		families = append(families, true)
+	}
+	return s.limitedRoutes(families, filter.birdCondition(), "", " all", limit)
+}
+
+// Returns if a field of a show protocols line is a time of day, which follows the date in some time formats.
+func isBirdClock(s string) bool {
+	return len(s) >= 8 && s[2] == ':' && s[5] == ':' && s[0] >= '0' && s[0] <= '9'
+}
+
+// Parses the counts from a line of a channel such as "1000 imported, 5 filtered, 20 exported, 900 preferred".
+func parseBirdChannelRoutes(channel *BGPPeerChannel, text string) {
+	for _, part := range strings.Split(text, ",") {
+		fields := strings.Fields(part)
+		if len(fields) != 2 {
+			continue
+		}
+		n, err := strconv.Atoi(fields[0])
+		if err != nil {
+			continue
+		}
+		switch fields[1] {
+		case "imported":
+			channel.Imported = n
+		case "filtered":
+			channel.Filtered = n
+		case "exported":
+			channel.Exported = n
+		}
+	}
+}
+
+// Parses the BGP sessions from the reply to a show protocols all command.
+func parseBirdProtocols(lines []bird.Line) ([]*BGPPeer, error) {
+	peers := []*BGPPeer{}
+	var peer *BGPPeer
+	var channel *BGPPeerChannel
+	for _, line := range lines {
+		switch line.Code {
+		case "1002":
+			// This is the summary line of a protocol, which is "name proto table state since info".
+			peer, channel = nil, nil
+			fields := strings.Fields(line.Text)
+			if len(fields) < 5 || fields[1] != "BGP" {
+				continue
+			}
+			peer = &BGPPeer{
+				Protocol: fields[0], State: fields[3], Since: fields[4], Channels: []BGPPeerChannel{},
+			}
+			info := fields[5:]
+			if len(info) != 0 && isBirdClock(info[0]) {
+				peer.Since += " " + info[0]
+				info = info[1:]
+			}
+			if len(info) != 0 {
+				peer.State = info[0]
+			}
+			peers = append(peers, peer)
+		case "1006":
+			// This is a line of the details of the protocol.
+			if peer == nil {
+				continue
+			}
+			text := strings.TrimSpace(line.Text)
+			if strings.HasPrefix(text, "Channel ") {
+				peer.Channels = append(peer.Channels, BGPPeerChannel{Name: strings.TrimPrefix(text, "Channel ")})
+				channel = &peer.Channels[len(peer.Channels)-1]
+				continue
+			}
+			key, value, ok := strings.Cut(text, ":")
+			if !ok {
+				continue
+			}
+			value = strings.TrimSpace(value)
+			switch key {
+			case "BGP state":
+				peer.State = value
+			case "Neighbor AS":
+				asn, err := strconv.ParseUint(value, 10, 32)
+				if err != nil {
+					return nil, errors.New("invalid neighbor AS for " + peer.Protocol + ": " + value)
+				}
+				peer.NeighborAS.ASN = uint32(asn)
+			case "State":
+				if channel != nil {
+					channel.State = value
+				}
+			case "Routes":
+				if channel != nil {
+					parseBirdChannelRoutes(channel, value)
+				}
+			}
+		}
+	}
+	return peers, nil
+}
+
+// Peers implements PeerSource.
+func (s birdSource) Peers() ([]*BGPPeer, error) {
+	replies, err := s.query("show protocols all")
+	if err != nil {
+		return nil, err
+	}
+	return parseBirdProtocols(replies[0].Lines)
+}
@@ -0,0 +1,170 @@
+package api_v1
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+)
+
+// Defines the well-known communities which FRR shows by name in the community list.
+var frrCommunityNames = map[string]string{
+	"gshut":       "65535:0",
+	"acceptown":   "65535:1",
+	"llgrStale":   "65535:6",
+	"noLlgr":      "65535:7",
+	"blackhole":   "65535:666",
+	"noExport":    "65535:65281",
+	"noAdvertise": "65535:65282",
+	"localAs":     "65535:65283",
+	"noPeer":      "65535:65284",
+}
+
+// Defines a path in the output of FRR's show bgp command.
+type frrPath struct {
+	AsPath struct {
+		Segments []struct {
+			Type string `json:"type"`
+			List []int  `json:"list"`
+		} `json:"segments"`
+	} `json:"aspath"`
+	AggregatorAs    *int   `json:"aggregatorAs"`
+	AggregatorId    string `json:"aggregatorId"`
+	Origin          string `json:"origin"`
+	Metric          *int   `json:"metric"`
+	LocalPref       *int   `json:"localpref"`
+	AtomicAggregate bool   `json:"atomicAggregate"`
+	Community       *struct {
+		List []string `json:"list"`
+	} `json:"community"`
+	LargeCommunity *struct {
+		List []string `json:"list"`
+	} `json:"largeCommunity"`
+	ExtendedCommunity *struct {
+		String string `json:"string"`
+	} `json:"extendedCommunity"`
+	OriginatorId string `json:"originatorId"`
+	ClusterList  *struct {
+		List []string `json:"list"`
+	} `json:"clusterList"`
+	LastUpdate struct {
+		String string `json:"string"`
+	} `json:"lastUpdate"`
+	Bestpath *struct {
+		Overall bool `json:"overall"`
+	} `json:"bestpath"`
+	Nexthops []struct {
+		IP string `json:"ip"`
+	} `json:"nexthops"`
+	Peer struct {
+		PeerId   string `json:"peerId"`
+		Hostname string `json:"hostname"`
+	} `json:"peer"`
+}
+
+// Defines the output of FRR's show bgp command for a prefix.
+type frrPrefix struct {
+	Prefix string    `json:"prefix"`
+	Paths  []frrPath `json:"paths"`
+}
+
+// Turns a path from FRR into a route.
+func (p frrPath) route(prefix string) *BGPRoute {
+	route := &BGPRoute{
+		Prefix:          &prefix,
+		Type:            "unicast",
+		Protocol:        p.Peer.PeerId,
+		Since:           strings.TrimSpace(p.LastUpdate.String),
+		Primary:         p.Bestpath != nil && p.Bestpath.Overall,
+		Source:          "BGP",
+		MED:             p.Metric,
+		LocalPref:       p.LocalPref,
+		AtomicAggregate: p.AtomicAggregate,
+		AsPath:          []int{},
+	}
+	if p.Peer.Hostname != "" {
+		route.Protocol = p.Peer.Hostname
+	}
+	if p.Origin != "" {
+		route.Origin = &p.Origin
+	}
+	for _, segment := range p.AsPath.Segments {
+		if segment.Type == "as-sequence" {
+			route.AsPath = append(route.AsPath, segment.List...)
+		}
+	}
+	if len(p.Nexthops) != 0 {
+		route.NextHop = &p.Nexthops[0].IP
+	}
+	if p.Peer.PeerId != "" && (route.NextHop == nil || *route.NextHop != p.Peer.PeerId) {
+		route.From = &p.Peer.PeerId
+	}
+	if p.AggregatorAs != nil {
+		route.Aggregator = &BGPAggregator{Address: p.AggregatorId, ASN: *p.AggregatorAs}
+	}
+	if p.OriginatorId != "" {
+		route.OriginatorID = &p.OriginatorId
+	}
+	if p.ClusterList != nil {
+		route.ClusterList = p.ClusterList.List
+	}
+	if p.Community != nil {
+		// Convert the communities, turning the named ones back into numbers.
+		communities := make([]string, len(p.Community.List))
+		for i, v := range p.Community.List {
+			if n, ok := frrCommunityNames[v]; ok {
+				v = n
+			}
+			communities[i] = v
+		}
+		route.Community = birdCommunities(communities, false)
+	}
+	if p.LargeCommunity != nil {
+		route.LargeCommunity = birdCommunities(p.LargeCommunity.List, true)
+	}
+	if p.ExtendedCommunity != nil {
+		for _, v := range strings.Fields(p.ExtendedCommunity.String) {
+			kind, value, _ := strings.Cut(v, ":")
+			route.ExtCommunity = append(route.ExtCommunity, birdExtCommunity(kind, value))
+		}
+	}
+	return route
+}
+
+// Parses the output of FRR's show bgp command for a prefix.
+func parseFrrRoutes(b []byte) (BGPRouteSlice, error) {
+	var prefix frrPrefix
+	if err := json.Unmarshal(b, &prefix); err != nil {
+		return nil, err
+	}
+	routes := BGPRouteSlice{}
+	for _, path := range prefix.Paths {
+		routes = append(routes, path.route(prefix.Prefix))
+	}
+	sort.Stable(routes)
+	return routes, nil
+}
+
+// frrSource is used to look up routes in FRR through vtysh.
+type frrSource struct {
+	// Defines the function used to run vtysh.
+	run commandRunner
+
+	// Defines the path to vtysh.
+	vtysh string
+}
+
+// Lookup implements RouteSource.
+func (s frrSource) Lookup(q RouteQuery) (BGPRouteSlice, string, error) {
+	family := "ipv4"
+	if q.Prefix.Addr().Is6() {
+		family = "ipv6"
+	}
+	out, err := s.run(s.vtysh, "-c", "show bgp "+family+" unicast "+q.String()+" json")
+	if err != nil {
+		// The output of vtysh can contain paths and configuration, so it is logged rather than shown to the user.
+		return nil, "", errors.New("vtysh failed: " + err.Error())
+	}
+	routes, err := parseFrrRoutes(out)
+	return routes, "", err
+}
@@ -0,0 +1,482 @@
+package api_v1
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Defines the method of the GoBGP gRPC API which lists the paths in a table.
+const gobgpListPathMethod = "/apipb.GobgpApi/ListPath"
+
+// Defines the prefix of the type URLs of the path attributes from GoBGP.
+const gobgpTypeURLPrefix = "type.googleapis.com/apipb."
+
+// Defines the field numbers of the messages in the GoBGP API which are used. The API is encoded by hand rather
+// than with the generated code from GoBGP, since only a few fields of one method are needed.
+const (
+	// ListPathRequest
+	gobgpRequestFamily   = 3
+	gobgpRequestPrefixes = 4
+	gobgpRequestSortType = 5
+
+	// Family
+	gobgpFamilyAfi  = 1
+	gobgpFamilySafi = 2
+
+	// TableLookupPrefix
+	gobgpLookupPrefix = 1
+
+	// ListPathResponse
+	gobgpResponseDestination = 1
+
+	// Destination
+	gobgpDestinationPrefix = 1
+	gobgpDestinationPaths  = 2
+
+	// Path
+	gobgpPathAttrs      = 2
+	gobgpPathAge        = 3
+	gobgpPathBest       = 4
+	gobgpPathNeighborIp = 15
+
+	// google.protobuf.Any
+	gobgpAnyTypeURL = 1
+	gobgpAnyValue   = 2
+)
+
+// Defines the values of the enums in the GoBGP API which are used.
+const (
+	gobgpAfiIp       = 1
+	gobgpAfiIp6      = 2
+	gobgpSafiUnicast = 1
+	gobgpSortPrefix  = 1
+)
+
+// Defines the origins in the GoBGP API.
+var gobgpOrigins = []string{"IGP", "EGP", "Incomplete"}
+
+// Defines a gRPC codec which sends and receives messages which are already encoded, since the GoBGP API is encoded
+// by hand. It is named proto so gobgpd decodes the messages as protobuf.
+type gobgpCodec struct{}
+
+// Marshal implements encoding.Codec.
+func (gobgpCodec) Marshal(v any) ([]byte, error) {
+	return *v.(*[]byte), nil
+}
+
+// Unmarshal implements encoding.Codec.
+func (gobgpCodec) Unmarshal(data []byte, v any) error {
+	*v.(*[]byte) = append([]byte(nil), data...)
+	return nil
+}
+
+// Name implements encoding.Codec.
+func (gobgpCodec) Name() string {
+	return "proto"
+}
+
+// Calls f with each field of a protobuf message. Varint fields are passed as v and length delimited fields are
+// passed as b. Other fields are skipped. If f returns an error, it stops and the error is returned.
+func gobgpFields(msg []byte, f func(num protowire.Number, typ protowire.Type, v uint64, b []byte) error) error {
+	for len(msg) > 0 {
+		num, typ, n := protowire.ConsumeTag(msg)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		msg = msg[n:]
+		var v uint64
+		var b []byte
+		switch typ {
+		case protowire.VarintType:
+			v, n = protowire.ConsumeVarint(msg)
+		case protowire.BytesType:
+			b, n = protowire.ConsumeBytes(msg)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, msg)
+		}
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		msg = msg[n:]
+		if typ == protowire.VarintType || typ == protowire.BytesType {
+			if err := f(num, typ, v, b); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Gets the values of a repeated uint32 field, which can either be packed or have one value per field.
+func gobgpUint32s(typ protowire.Type, v uint64, b []byte) ([]uint32, error) {
+	if typ == protowire.VarintType {
+		return []uint32{uint32(v)}, nil
+	}
+	var values []uint32
+	for len(b) > 0 {
+		v, n := protowire.ConsumeVarint(b)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		values = append(values, uint32(v))
+		b = b[n:]
+	}
+	return values, nil
+}
+
+// Gets the type name and value of a google.protobuf.Any from GoBGP.
+func gobgpAny(msg []byte) (string, []byte, error) {
+	var typeURL string
+	var value []byte
+	err := gobgpFields(msg, func(num protowire.Number, typ protowire.Type, v uint64, b []byte) error {
+		switch num {
+		case gobgpAnyTypeURL:
+			typeURL = string(b)
+		case gobgpAnyValue:
+			value = b
+		}
+		return nil
+	})
+	return strings.TrimPrefix(typeURL, gobgpTypeURLPrefix), value, err
+}
+
+// Formats an extended community from GoBGP in the same way as BIRD. Only route targets and route origins can be
+// shown the same way as BIRD, so false is returned for anything else.
+func gobgpExtCommunity(msg []byte) (string, bool, error) {
+	name, value, err := gobgpAny(msg)
+	if err != nil {
+		return "", false, err
+	}
+	switch name {
+	case "TwoOctetAsSpecificExtended", "FourOctetAsSpecificExtended", "IPv4AddressSpecificExtended":
+	default:
+		return "", false, nil
+	}
+
+	// These all have the sub type in field 2, the ASN or address in field 3 and the local admin in field 4.
+	var subType uint64
+	admin := "0"
+	localAdmin := "0"
+	err = gobgpFields(value, func(num protowire.Number, typ protowire.Type, v uint64, b []byte) error {
+		switch num {
+		case 2:
+			subType = v
+		case 3:
+			if typ == protowire.BytesType {
+				admin = string(b)
+			} else {
+				admin = strconv.FormatUint(v, 10)
+			}
+		case 4:
+			localAdmin = strconv.FormatUint(v, 10)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", false, err
+	}
+	switch subType {
+	case 2:
+		return "rt, " + admin + ", " + localAdmin, true, nil
+	case 3:
+		return "ro, " + admin + ", " + localAdmin, true, nil
+	default:
+		return "", false, nil
+	}
+}
+
+// Formats a large community from GoBGP in the same way as BIRD.
+func gobgpLargeCommunity(msg []byte) (string, error) {
+	var values [3]uint64
+	err := gobgpFields(msg, func(num protowire.Number, typ protowire.Type, v uint64, b []byte) error {
+		if num >= 1 && num <= 3 {
+			values[num-1] = v
+		}
+		return nil
+	})
+	return strconv.FormatUint(values[0], 10) + ", " + strconv.FormatUint(values[1], 10) + ", " +
+		strconv.FormatUint(values[2], 10), err
+}
+
+// Gets the AS numbers in a segment of an AS path from GoBGP. Only AS sequences are included, the same as BIRD.
+func gobgpAsSegment(msg []byte) ([]int, error) {
+	var segmentType uint64
+	var asns []int
+	err := gobgpFields(msg, func(num protowire.Number, typ protowire.Type, v uint64, b []byte) error {
+		switch num {
+		case 1:
+			segmentType = v
+		case 2:
+			values, err := gobgpUint32s(typ, v, b)
+			if err != nil {
+				return err
+			}
+			for _, asn := range values {
+				asns = append(asns, int(asn))
+			}
+		}
+		return nil
+	})
+	if err != nil || segmentType != 2 {
+		return nil, err
+	}
+	return asns, nil
+}
+
+// Fills in a route from a path attribute from GoBGP. Attributes which BIRD doesn't show are ignored.
+func gobgpAttr(route *BGPRoute, msg []byte) error {
+	name, value, err := gobgpAny(msg)
+	if err != nil {
+		return err
+	}
+
+	// Fields with zero values aren't encoded, so attributes with numbers start at zero.
+	switch name {
+	case "OriginAttribute":
+		route.Origin = &gobgpOrigins[0]
+	case "AsPathAttribute":
+		route.AsPath = []int{}
+	case "MultiExitDiscAttribute":
+		route.MED = new(int)
+	case "LocalPrefAttribute":
+		route.LocalPref = new(int)
+	case "AtomicAggregateAttribute":
+		route.AtomicAggregate = true
+	case "AggregatorAttribute":
+		route.Aggregator = &BGPAggregator{}
+	}
+
+	return gobgpFields(value, func(num protowire.Number, typ protowire.Type, v uint64, b []byte) error {
+		switch name + "." + strconv.Itoa(int(num)) {
+		case "OriginAttribute.1":
+			route.Origin = nil
+			if v < uint64(len(gobgpOrigins)) {
+				route.Origin = &gobgpOrigins[v]
+			}
+		case "AsPathAttribute.1":
+			asns, err := gobgpAsSegment(b)
+			if err != nil {
+				return err
+			}
+			route.AsPath = append(route.AsPath, asns...)
+		case "NextHopAttribute.1", "MpReachNLRIAttribute.2":
+			// IPv6 routes have their next hops in MP_REACH_NLRI, and the first one is the global address.
+			if route.NextHop == nil && len(b) != 0 {
+				nextHop := string(b)
+				route.NextHop = &nextHop
+			}
+		case "MultiExitDiscAttribute.1":
+			med := int(v)
+			route.MED = &med
+		case "LocalPrefAttribute.1":
+			localPref := int(v)
+			route.LocalPref = &localPref
+		case "AggregatorAttribute.2":
+			route.Aggregator.ASN = int(v)
+		case "AggregatorAttribute.3":
+			route.Aggregator.Address = string(b)
+		case "CommunitiesAttribute.1":
+			communities, err := gobgpUint32s(typ, v, b)
+			if err != nil {
+				return err
+			}
+			for _, c := range communities {
+				route.Community = append(route.Community,
+					strconv.FormatUint(uint64(c>>16), 10)+","+strconv.FormatUint(uint64(c&0xffff), 10))
+			}
+		case "OriginatorIdAttribute.1":
+			id := string(b)
+			route.OriginatorID = &id
+		case "ClusterListAttribute.1":
+			route.ClusterList = append(route.ClusterList, string(b))
+		case "ExtendedCommunitiesAttribute.1":
+			community, ok, err := gobgpExtCommunity(b)
+			if err != nil {
+				return err
+			}
+			if ok {
+				route.ExtCommunity = append(route.ExtCommunity, community)
+			}
+		case "LargeCommunitiesAttribute.1":
+			community, err := gobgpLargeCommunity(b)
+			if err != nil {
+				return err
+			}
+			route.LargeCommunity = append(route.LargeCommunity, community)
+		}
+		return nil
+	})
+}
+
+// Turns a path from GoBGP into a route.
+func gobgpRoute(prefix string, msg []byte) (*BGPRoute, error) {
+	route := &BGPRoute{
+		Prefix: &prefix,
+		Type:   "unicast",
+		Source: "BGP",
+	}
+	var age uint64
+	var neighborIp string
+	err := gobgpFields(msg, func(num protowire.Number, typ protowire.Type, v uint64, b []byte) error {
+		switch num {
+		case gobgpPathAttrs:
+			return gobgpAttr(route, b)
+		case gobgpPathAge:
+			// This is a google.protobuf.Timestamp, which has the seconds in field 1.
+			return gobgpFields(b, func(num protowire.Number, typ protowire.Type, v uint64, b []byte) error {
+				if num == 1 {
+					age = v
+				}
+				return nil
+			})
+		case gobgpPathBest:
+			route.Primary = v != 0
+		case gobgpPathNeighborIp:
+			neighborIp = string(b)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	route.Protocol = neighborIp
+	route.Since = time.Unix(int64(age), 0).UTC().Format("2006-01-02 15:04:05")
+	if neighborIp != "" && (route.NextHop == nil || *route.NextHop != neighborIp) {
+		route.From = &neighborIp
+	}
+	return route, nil
+}
+
+// Parses the responses from ListPath in GoBGP, which each have a destination with its prefix and paths.
+func parseGobgpRoutes(responses [][]byte) (BGPRouteSlice, error) {
+	routes := BGPRouteSlice{}
+	for _, response := range responses {
+		err := gobgpFields(response, func(num protowire.Number, typ protowire.Type, v uint64, b []byte) error {
+			if num != gobgpResponseDestination {
+				return nil
+			}
+			var prefix string
+			var paths [][]byte
+			err := gobgpFields(b, func(num protowire.Number, typ protowire.Type, v uint64, b []byte) error {
+				switch num {
+				case gobgpDestinationPrefix:
+					prefix = string(b)
+				case gobgpDestinationPaths:
+					paths = append(paths, b)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			for _, path := range paths {
+				route, err := gobgpRoute(prefix, path)
+				if err != nil {
+					return err
+				}
+				routes = append(routes, route)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	sort.Stable(routes)
+	return routes, nil
+}
+
+// Encodes the ListPath request for a query. This looks in the global table, and GoBGP finds the most specific
+// route for addresses and the exact route for prefixes.
+func gobgpListPathRequest(q RouteQuery) []byte {
+	afi := uint64(gobgpAfiIp)
+	if q.Prefix.Addr().Is6() {
+		afi = gobgpAfiIp6
+	}
+	var family []byte
+	family = protowire.AppendTag(family, gobgpFamilyAfi, protowire.VarintType)
+	family = protowire.AppendVarint(family, afi)
+	family = protowire.AppendTag(family, gobgpFamilySafi, protowire.VarintType)
+	family = protowire.AppendVarint(family, gobgpSafiUnicast)
+
+	var prefix []byte
+	prefix = protowire.AppendTag(prefix, gobgpLookupPrefix, protowire.BytesType)
+	prefix = protowire.AppendString(prefix, q.String())
+
+	var req []byte
+	req = protowire.AppendTag(req, gobgpRequestFamily, protowire.BytesType)
+	req = protowire.AppendBytes(req, family)
+	req = protowire.AppendTag(req, gobgpRequestPrefixes, protowire.BytesType)
+	req = protowire.AppendBytes(req, prefix)
+	req = protowire.AppendTag(req, gobgpRequestSortType, protowire.VarintType)
+	req = protowire.AppendVarint(req, gobgpSortPrefix)
+	return req
+}
+
+// gobgpSource is used to look up routes in GoBGP through the ListPath method of its gRPC API.
+type gobgpSource struct {
+	// Defines the connection to the gRPC API of gobgpd.
+	conn *grpc.ClientConn
+}
+
+// Creates a GoBGP route source for the gRPC API at the host:port given. gobgpd doesn't use TLS by default, so
+// neither does this. It connects when the first lookup is done.
+func newGobgpSource(address string) (gobgpSource, error) {
+	conn, err := grpc.NewClient(address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return gobgpSource{}, err
+	}
+	return gobgpSource{conn: conn}, nil
+}
+
+// Calls ListPath and returns the responses.
+func (s gobgpSource) listPath(req []byte) ([][]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), routeCommandTimeout)
+	defer cancel()
+
+	stream, err := s.conn.NewStream(
+		ctx, &grpc.StreamDesc{StreamName: "ListPath", ServerStreams: true}, gobgpListPathMethod,
+		grpc.ForceCodec(gobgpCodec{}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := stream.SendMsg(&req); err != nil {
+		return nil, err
+	}
+	if err := stream.CloseSend(); err != nil {
+		return nil, err
+	}
+	var responses [][]byte
+	for {
+		var response []byte
+		if err := stream.RecvMsg(&response); err != nil {
+			if errors.Is(err, io.EOF) {
+				return responses, nil
+			}
+			return nil, err
+		}
+		responses = append(responses, response)
+	}
+}
+
+// Lookup implements RouteSource.
+func (s gobgpSource) Lookup(q RouteQuery) (BGPRouteSlice, string, error) {
+	responses, err := s.listPath(gobgpListPathRequest(q))
+	if err != nil {
+		// The error can contain the address of gobgpd, so it is logged rather than shown to the user.
+		return nil, "", errors.New("gobgp ListPath failed: " + err.Error())
+	}
+	routes, err := parseGobgpRoutes(responses)
+	return routes, "", err
+}
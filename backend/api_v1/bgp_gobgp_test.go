@@ -0,0 +1,197 @@
+package api_v1
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Encodes a protobuf message for the fake GoBGP API. The fields are pairs of a field number and a value, which can
+// be an int or bool for varints, a string or []byte for length delimited fields, or a []uint32 for packed fields.
+func pb(fields ...any) []byte {
+	var b []byte
+	for i := 0; i < len(fields); i += 2 {
+		num := protowire.Number(fields[i].(int))
+		switch v := fields[i+1].(type) {
+		case int:
+			b = protowire.AppendTag(b, num, protowire.VarintType)
+			b = protowire.AppendVarint(b, uint64(v))
+		case bool:
+			b = protowire.AppendTag(b, num, protowire.VarintType)
+			b = protowire.AppendVarint(b, protowire.EncodeBool(v))
+		case string:
+			b = protowire.AppendTag(b, num, protowire.BytesType)
+			b = protowire.AppendString(b, v)
+		case []byte:
+			b = protowire.AppendTag(b, num, protowire.BytesType)
+			b = protowire.AppendBytes(b, v)
+		case []uint32:
+			var packed []byte
+			for _, x := range v {
+				packed = protowire.AppendVarint(packed, uint64(x))
+			}
+			b = protowire.AppendTag(b, num, protowire.BytesType)
+			b = protowire.AppendBytes(b, packed)
+		}
+	}
+	return b
+}
+
+// Encodes a google.protobuf.Any holding a message from the GoBGP API.
+func pbAny(name string, fields ...any) []byte {
+	return pb(1, gobgpTypeURLPrefix+name, 2, pb(fields...))
+}
+
+// Starts a fake gobgpd which checks the ListPath request and then sends the responses and returns the error given.
+func fakeGobgpd(t *testing.T, wantReq []byte, responses [][]byte, respErr error) string {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := grpc.NewServer(
+		grpc.ForceServerCodec(gobgpCodec{}),
+		grpc.UnknownServiceHandler(func(_ any, stream grpc.ServerStream) error {
+			method, _ := grpc.MethodFromServerStream(stream)
+			assert.Equal(t, gobgpListPathMethod, method)
+			var req []byte
+			if err := stream.RecvMsg(&req); err != nil {
+				return err
+			}
+			assert.Equal(t, wantReq, req)
+			for _, response := range responses {
+				if err := stream.SendMsg(&response); err != nil {
+					return err
+				}
+			}
+			return respErr
+		}),
+	)
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+	return lis.Addr().String()
+}
+
+func Test_gobgpSource(t *testing.T) {
+	tests := []struct {
+		name string
+
+		ip        string
+		wantReq   []byte
+		responses [][]byte
+		respErr   error
+		wantErr   string
+	}{
+		{
+			name:    "ipv4 prefix",
+			ip:      "8.8.8.0/24",
+			wantReq: pb(3, pb(1, 1, 2, 1), 4, pb(1, "8.8.8.0/24"), 5, 1),
+			responses: [][]byte{pb(1, pb(
+				1, "8.8.8.0/24",
+				2, pb(
+					2, pbAny("OriginAttribute"),
+					2, pbAny("AsPathAttribute", 1, pb(1, 2, 2, []uint32{15169, 15169})),
+					2, pbAny("NextHopAttribute", 1, "195.66.224.125"),
+					2, pbAny("MultiExitDiscAttribute"),
+					2, pbAny("LocalPrefAttribute", 1, 200),
+					2, pbAny("CommunitiesAttribute", 1, []uint32{571146226, 571146228}),
+					2, pbAny("ExtendedCommunitiesAttribute",
+						1, pbAny("TwoOctetAsSpecificExtended", 1, true, 2, 2, 3, 64500, 4, 100),
+						1, pbAny("FourOctetAsSpecificExtended", 1, true, 2, 5, 3, 4200000000, 4, 1),
+					),
+					2, pbAny("LargeCommunitiesAttribute", 1, pb(1, 8714, 2, 1000, 3, 1)),
+					3, pb(1, 1643364000),
+					4, true,
+					11, "195.66.224.125",
+					15, "195.66.224.125",
+				),
+				2, pb(
+					2, pbAny("OriginAttribute", 1, 2),
+					2, pbAny("AsPathAttribute", 1, pb(1, 2, 2, []uint32{3223, 15169})),
+					2, pbAny("NextHopAttribute", 1, "5.254.78.209"),
+					2, pbAny("LocalPrefAttribute", 1, 100),
+					2, pbAny("AtomicAggregateAttribute"),
+					2, pbAny("AggregatorAttribute", 2, 15169, 3, "172.253.0.1"),
+					2, pbAny("OriginatorIdAttribute", 1, "10.0.0.2"),
+					2, pbAny("ClusterListAttribute", 1, "10.0.0.1"),
+					3, pb(1, 1643277600),
+					11, "77.72.0.238",
+					15, "77.72.0.238",
+				),
+			))},
+		},
+		{
+			name:    "ipv6 address",
+			ip:      "2a03:2800::1",
+			wantReq: pb(3, pb(1, 2, 2, 1), 4, pb(1, "2a03:2800::1"), 5, 1),
+			responses: [][]byte{pb(1, pb(
+				1, "2a03:2800::/32",
+				2, pb(
+					2, pbAny("OriginAttribute", 1, 2),
+					2, pbAny("AsPathAttribute", 1, pb(1, 1, 2, []uint32{64500}), 1, pb(1, 2, 2, []uint32{32934})),
+					2, pbAny("MpReachNLRIAttribute",
+						1, pb(1, 2, 2, 1),
+						2, "2001:7f8:4::8605:1",
+						2, "fe80::8605:1",
+						3, pbAny("IPAddressPrefix", 1, 32, 2, "2a03:2800::"),
+					),
+					2, pbAny("MultiExitDiscAttribute", 1, 10),
+					2, pbAny("ExtendedCommunitiesAttribute",
+						1, pbAny("IPv4AddressSpecificExtended", 1, true, 2, 3, 3, "10.0.0.1", 4, 5),
+					),
+					3, pb(1, 1643364000),
+					4, true,
+					15, "2001:7f8:4::8605:1",
+				),
+			))},
+		},
+		{
+			name:    "no routes",
+			ip:      "192.0.2.1",
+			wantReq: pb(3, pb(1, 1, 2, 1), 4, pb(1, "192.0.2.1"), 5, 1),
+		},
+		{
+			name:    "api error",
+			ip:      "8.8.8.8",
+			wantReq: pb(3, pb(1, 1, 2, 1), 4, pb(1, "8.8.8.8"), 5, 1),
+			respErr: status.Error(codes.Internal, "failed to lookup"),
+			wantErr: "gobgp ListPath failed: rpc error: code = Internal desc = failed to lookup",
+		},
+		{
+			name:      "invalid response",
+			ip:        "8.8.8.8",
+			wantReq:   pb(3, pb(1, 1, 2, 1), 4, pb(1, "8.8.8.8"), 5, 1),
+			responses: [][]byte{pb(1, pb(2, pb(2, []byte{0x0a, 0x05})))},
+			wantErr:   "unexpected EOF",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, ok := parseRouteQuery(tt.ip)
+			if !assert.True(t, ok) {
+				return
+			}
+			source, err := newGobgpSource(fakeGobgpd(t, tt.wantReq, tt.responses, tt.respErr))
+			if !assert.NoError(t, err) {
+				return
+			}
+			defer source.conn.Close()
+			routes, raw, err := source.Lookup(q)
+			if tt.wantErr != "" {
+				// The errors can contain details of the server, so they must not be shown to the user.
+				assert.EqualError(t, err, tt.wantErr)
+				var sourceErr *routeSourceError
+				assert.False(t, errors.As(err, &sourceErr))
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, "", raw)
+			assertRoutesGolden(t, routes)
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package api_v1
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+)
+
+// Defines the well-known communities which bgpctl shows by name.
+var openbgpdCommunityNames = map[string]string{
+	"GRACEFUL_SHUTDOWN":   "65535:0",
+	"ACCEPT_OWN":          "65535:1",
+	"BLACKHOLE":           "65535:666",
+	"NO_EXPORT":           "65535:65281",
+	"NO_ADVERTISE":        "65535:65282",
+	"NO_EXPORT_SUBCONFED": "65535:65283",
+	"NO_PEER":             "65535:65284",
+}
+
+// Defines a path attribute in the output of bgpctl. Which fields are set depends on the type.
+type openbgpdAttr struct {
+	Type        string   `json:"type"`
+	AS          int      `json:"AS"`
+	RouterId    string   `json:"router_id"`
+	Originator  string   `json:"originator"`
+	ClusterList []string `json:"cluster_list"`
+}
+
+// Defines a route in the output of bgpctl's show rib command.
+type openbgpdRoute struct {
+	Prefix      string `json:"prefix"`
+	AsPath      string `json:"aspath"`
+	ExitNexthop string `json:"exit_nexthop"`
+	Neighbor    struct {
+		Description string `json:"description"`
+		RemoteAddr  string `json:"remote_addr"`
+	} `json:"neighbor"`
+	Best                bool           `json:"best"`
+	Origin              string         `json:"origin"`
+	Metric              *int           `json:"metric"`
+	LocalPref           *int           `json:"localpref"`
+	LastUpdate          string         `json:"last_update"`
+	Communities         []string       `json:"communities"`
+	LargeCommunities    []string       `json:"large_communities"`
+	ExtendedCommunities []string       `json:"extended_communities"`
+	Attributes          []openbgpdAttr `json:"attributes"`
+}
+
+// Turns a route from bgpctl into a route.
+func (r openbgpdRoute) route() *BGPRoute {
+	prefix := r.Prefix
+	route := &BGPRoute{
+		Prefix:    &prefix,
+		Type:      "unicast",
+		Protocol:  r.Neighbor.RemoteAddr,
+		Since:     r.LastUpdate,
+		Primary:   r.Best,
+		Source:    "BGP",
+		AsPath:    parseAsPathString(r.AsPath),
+		MED:       r.Metric,
+		LocalPref: r.LocalPref,
+	}
+	if r.Neighbor.Description != "" {
+		route.Protocol = r.Neighbor.Description
+	}
+	if r.Origin != "" {
+		origin := r.Origin
+		if origin == "incomplete" {
+			origin = "Incomplete"
+		}
+		route.Origin = &origin
+	}
+	if r.ExitNexthop != "" {
+		nexthop := r.ExitNexthop
+		route.NextHop = &nexthop
+	}
+	if r.Neighbor.RemoteAddr != "" && r.Neighbor.RemoteAddr != r.ExitNexthop {
+		from := r.Neighbor.RemoteAddr
+		route.From = &from
+	}
+
+	// Convert the communities, turning the named ones back into numbers.
+	communities := make([]string, len(r.Communities))
+	for i, v := range r.Communities {
+		if n, ok := openbgpdCommunityNames[v]; ok {
+			v = n
+		}
+		communities[i] = v
+	}
+	route.Community = birdCommunities(communities, false)
+	route.LargeCommunity = birdCommunities(r.LargeCommunities, true)
+	for _, v := range r.ExtendedCommunities {
+		kind, value, _ := strings.Cut(v, " ")
+		route.ExtCommunity = append(route.ExtCommunity, birdExtCommunity(kind, value))
+	}
+
+	for _, attr := range r.Attributes {
+		switch attr.Type {
+		case "AGGREGATOR", "AS4_AGGREGATOR":
+			route.Aggregator = &BGPAggregator{Address: attr.RouterId, ASN: attr.AS}
+		case "ATOMIC_AGGREGATE":
+			route.AtomicAggregate = true
+		case "ORIGINATOR_ID":
+			originator := attr.Originator
+			route.OriginatorID = &originator
+		case "CLUSTER_LIST":
+			route.ClusterList = attr.ClusterList
+		}
+	}
+	return route
+}
+
+// Parses the output of bgpctl's show rib command.
+func parseOpenbgpdRoutes(b []byte) (BGPRouteSlice, error) {
+	var out struct {
+		Rib []openbgpdRoute `json:"rib"`
+	}
+	if err := json.Unmarshal(b, &out); err != nil {
+		return nil, err
+	}
+	routes := BGPRouteSlice{}
+	for _, r := range out.Rib {
+		routes = append(routes, r.route())
+	}
+	sort.Stable(routes)
+	return routes, nil
+}
+
+// openbgpdSource is used to look up routes in OpenBGPD through bgpctl.
+type openbgpdSource struct {
+	// Defines the function used to run bgpctl.
+	run commandRunner
+
+	// Defines the path to bgpctl.
+	bgpctl string
+
+	// Defines the path to the control socket of bgpd. If this is blank, bgpctl uses its default.
+	socket string
+}
+
+// Lookup implements RouteSource.
+func (s openbgpdSource) Lookup(q RouteQuery) (BGPRouteSlice, string, error) {
+	args := []string{}
+	if s.socket != "" {
+		args = append(args, "-s", s.socket)
+	}
+	args = append(args, "-j", "show", "rib", "detail", q.String())
+	out, err := s.run(s.bgpctl, args...)
+	if err != nil {
+		// The output of bgpctl can contain paths and configuration, so it is logged rather than shown to the user.
+		return nil, "", errors.New("bgpctl failed: " + err.Error())
+	}
+	routes, err := parseOpenbgpdRoutes(out)
+	return routes, "", err
+}
@@ -0,0 +1,249 @@
+package api_v1
+
+import (
+	"errors"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hako/durafmt"
+	"github.com/krystal/krystal-network-tools/backend/asinfo"
+	"gopkg.in/yaml.v3"
+)
+
+// PeerSource is used to define a route source which can list its BGP sessions.
+type PeerSource interface {
+	// Peers returns every BGP session the routing daemon has configured.
+	Peers() ([]*BGPPeer, error)
+}
+
+// BGPPeerChannel is used to define an address family of a BGP session.
+type BGPPeerChannel struct {
+	// Name is used to define the name of the channel, such as ipv4 or ipv6.
+	Name string `json:"name"`
+
+	// State is used to define the state of the channel, such as UP.
+	State string `json:"state"`
+
+	// Imported is used to define the number of routes accepted from the peer.
+	Imported int `json:"imported"`
+
+	// Filtered is used to define the number of routes from the peer which were rejected by the import filter.
+	Filtered int `json:"filtered"`
+
+	// Exported is used to define the number of routes sent to the peer.
+	Exported int `json:"exported"`
+}
+
+// BGPPeer is used to define a BGP session. Only the fields which are safe to show to the public are in the JSON.
+type BGPPeer struct {
+	// Protocol is used to define the name of the protocol in the routing daemon.
+	Protocol string `json:"-"`
+
+	// Name is used to define the public label of the session.
+	Name string `json:"name"`
+
+	// NeighborAS is used to define the AS of the peer and what is known about it.
+	NeighborAS asinfo.Info `json:"neighbor_as"`
+
+	// State is used to define the BGP state of the session, such as Established.
+	State string `json:"state"`
+
+	// Since is used to define when the session last changed state, in the format the routing daemon uses.
+	Since string `json:"since"`
+
+	// UptimeSeconds is used to define how long the session has been established for. This is nil if the session
+	// isn't established or the time it changed state is not precise enough to tell.
+	UptimeSeconds *int64 `json:"uptime_seconds"`
+
+	// Channels is used to define the address families of the session.
+	Channels []BGPPeerChannel `json:"channels"`
+}
+
+// BGPPeerSlice is used to define a list of BGP sessions.
+type BGPPeerSlice []*BGPPeer
+
+// String returns the sessions in a human readable format.
+func (s BGPPeerSlice) String() string {
+	if len(s) == 0 {
+		return "No BGP sessions found.\n"
+	}
+	str := ""
+	for i, v := range s {
+		if i != 0 {
+			str += "\n"
+		}
+		str += v.Name + " (" + v.NeighborAS.String() + ")\n\tState: " + v.State
+		if v.Since != "" {
+			str += " since " + v.Since
+		}
+		if v.UptimeSeconds != nil {
+			uptime := time.Duration(*v.UptimeSeconds) * time.Second
+			str += " (up " + durafmt.Parse(uptime).LimitFirstN(2).String() + ")"
+		}
+		str += "\n"
+		for _, c := range v.Channels {
+			str += "\t" + c.Name + ": " + c.State + ", " + strconv.Itoa(c.Imported) + " imported, " +
+				strconv.Itoa(c.Filtered) + " filtered, " + strconv.Itoa(c.Exported) + " exported\n"
+		}
+	}
+	return str
+}
+
+// BGPPeerRule is used to define which BGP sessions are shown to the public and how they are labelled.
+type BGPPeerRule struct {
+	// Protocol is used to define the name of the protocol in the routing daemon. This can be a glob such as
+	// "linx_*".
+	Protocol string `yaml:"protocol"`
+
+	// Label is used to define the name shown to the public. If this is blank, the protocol name is shown.
+	Label string `yaml:"label"`
+}
+
+// Loads the BGP peer rules from a YAML file.
+func loadBgpPeerRules(filename string) ([]BGPPeerRule, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var rules []BGPPeerRule
+	dec := yaml.NewDecoder(f)
+	dec.KnownFields(true)
+	if err := dec.Decode(&rules); err != nil {
+		return nil, errors.New(filename + ": " + err.Error())
+	}
+	for _, rule := range rules {
+		if rule.Protocol == "" {
+			return nil, errors.New(filename + ": rule is missing a protocol")
+		}
+		if _, err := path.Match(rule.Protocol, ""); err != nil {
+			return nil, errors.New(filename + ": invalid protocol pattern " + rule.Protocol)
+		}
+	}
+	return rules, nil
+}
+
+// Gets the BGP peer rules from the file in BGP_PEERS_FILE. If it is not set, nil is returned and no sessions are
+// public.
+func getBgpPeerRules() ([]BGPPeerRule, error) {
+	filename := os.Getenv("BGP_PEERS_FILE")
+	if filename == "" {
+		return nil, nil
+	}
+	return loadBgpPeerRules(filename)
+}
+
+// Returns the sessions which match a rule, labelled by the first rule they match. They are in the order of the
+// rules, and then in the order the routing daemon returned them.
+func publicBgpPeers(peers []*BGPPeer, rules []BGPPeerRule) BGPPeerSlice {
+	result := BGPPeerSlice{}
+	ruleIndexes := map[*BGPPeer]int{}
+	for _, peer := range peers {
+		for i, rule := range rules {
+			if ok, _ := path.Match(rule.Protocol, peer.Protocol); ok {
+				peer.Name = rule.Label
+				if peer.Name == "" {
+					peer.Name = peer.Protocol
+				}
+				ruleIndexes[peer] = i
+				result = append(result, peer)
+				break
+			}
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return ruleIndexes[result[i]] < ruleIndexes[result[j]]
+	})
+	return result
+}
+
+// Works out how long a session has been up from the time BIRD says it changed state. BIRD shows the time of day
+// for changes in the last day and the date for older ones by default, so the uptime is only known for older
+// sessions if the protocol time format includes the time.
+func bgpPeerUptime(since string, now time.Time) *int64 {
+	var t time.Time
+	if strings.Contains(since, " ") {
+		var err error
+		t, err = time.ParseInLocation("2006-01-02 15:04:05", since, now.Location())
+		if err != nil {
+			return nil
+		}
+	} else {
+		clock, err := time.Parse("15:04:05", since)
+		if err != nil {
+			return nil
+		}
+		t = time.Date(
+			now.Year(), now.Month(), now.Day(), clock.Hour(), clock.Minute(), clock.Second(), clock.Nanosecond(),
+			now.Location(),
+		)
+		if t.After(now) {
+			t = t.AddDate(0, 0, -1)
+		}
+	}
+	if t.After(now) {
+		return nil
+	}
+	uptime := int64(now.Sub(t) / time.Second)
+	return &uptime
+}
+
+func bgpPeers(
+	g group, source RouteSource, rules []BGPPeerRule, asNames *asinfo.Resolver, now func() time.Time,
+) {
+	g.GET("/peers", func(context *gin.Context) {
+		// Check the route source supports this.
+		peerSource, ok := source.(PeerSource)
+		if !ok {
+			context.Error(&gin.Error{
+				Err:  errors.New("listing BGP sessions is not supported by this region's router"),
+				Type: gin.ErrorTypePublic,
+			})
+			return
+		}
+		if len(rules) == 0 {
+			context.Error(&gin.Error{
+				Err:  errors.New("no BGP sessions are public in this region"),
+				Type: gin.ErrorTypePublic,
+			})
+			return
+		}
+
+		// Get the sessions.
+		peers, err := peerSource.Peers()
+		if err != nil {
+			var sourceErr *routeSourceError
+			if errors.As(err, &sourceErr) {
+				context.Error(&gin.Error{Err: sourceErr, Type: gin.ErrorTypePublic})
+			} else {
+				context.Error(err)
+			}
+			return
+		}
+
+		// Remove the sessions which aren't public and fill in the rest.
+		result := publicBgpPeers(peers, rules)
+		t := now()
+		for _, peer := range result {
+			if peer.State == "Established" {
+				peer.UptimeSeconds = bgpPeerUptime(peer.Since, t)
+			}
+			if asNames != nil {
+				peer.NeighborAS, _ = asNames.Lookup(peer.NeighborAS.ASN)
+			}
+		}
+
+		// Return the result.
+		if context.ContentType() == "application/json" {
+			context.JSON(200, result)
+		} else {
+			context.String(200, result.String())
+		}
+	})
+}
@@ -0,0 +1,214 @@
+package api_v1
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jimeh/go-golden"
+	"github.com/krystal/krystal-network-tools/backend/asinfo"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_bgpPeers(t *testing.T) {
+	rules := []BGPPeerRule{
+		{Protocol: "transit1", Label: "Transit"},
+		{Protocol: "linx_rs1", Label: "LINX LON1 route server 1"},
+		{Protocol: "linx_cust*"},
+	}
+	tests := []struct {
+		name string
+
+		tapeFile string
+		source   RouteSource
+		rules    []BGPPeerRule
+		json     bool
+		wantErr  string
+	}{
+		{
+			name:     "successful json",
+			tapeFile: "peers.json",
+			rules:    rules,
+			json:     true,
+		},
+		{
+			name:     "successful text",
+			tapeFile: "peers.json",
+			rules:    rules,
+		},
+		{
+			name:     "no matching sessions",
+			tapeFile: "peers.json",
+			rules:    []BGPPeerRule{{Protocol: "decix_*"}},
+		},
+		{
+			name:    "no rules",
+			wantErr: "no BGP sessions are public in this region",
+		},
+		{
+			name:    "unsupported source",
+			source:  frrSource{},
+			rules:   rules,
+			wantErr: "listing BGP sessions is not supported by this region's router",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Create the route source.
+			source := tt.source
+			if source == nil {
+				var mocker io.ReadWriteCloser
+				if tt.tapeFile != "" {
+					mocker = newBgpTape(t, tt.tapeFile, []string{"show protocols all\n"})
+				}
+				source = birdSource{
+					socketBuilder: func() (io.ReadWriteCloser, error) { return mocker, nil },
+				}
+			}
+
+			// Create the AS name resolver.
+			db := asinfo.NewDatabase()
+			db.Replace([]asinfo.Info{{ASN: 8714, Name: "LINX-ROUTESRV", Country: "GB"}})
+			asNames := asinfo.NewResolver(db, nil)
+
+			// Get the handler.
+			now := func() time.Time { return time.Date(2022, 1, 28, 12, 30, 0, 0, time.UTC) }
+			hn := mockGroupSingleHn(t, "GET", "/peers", func(g group) {
+				bgpPeers(g, source, tt.rules, asNames, now)
+			})
+			if hn == nil {
+				return
+			}
+
+			// Call the handler.
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = &http.Request{
+				URL:    &url.URL{Path: "/peers"},
+				Header: http.Header{},
+			}
+			if tt.json {
+				c.Request.Header.Set("Content-Type", "application/json")
+			}
+			hn(c)
+
+			// Check the result.
+			if tt.wantErr != "" {
+				if assert.Len(t, c.Errors, 1) {
+					assert.EqualError(t, c.Errors[0], tt.wantErr)
+				}
+				return
+			}
+			assert.Equal(t, http.StatusOK, w.Code)
+			if golden.Update() {
+				golden.Set(t, attemptJsonBeautify(w.Body.Bytes()))
+			}
+			assert.Equal(t, string(golden.Get(t)), string(attemptJsonBeautify(w.Body.Bytes())))
+		})
+	}
+}
+
+func Test_bgpPeerUptime(t *testing.T) {
+	now := time.Date(2022, 1, 28, 12, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+
+		since string
+		want  *int64
+	}{
+		{
+			name:  "date and time",
+			since: "2022-01-27 10:00:00",
+			want:  int64Ptr(95400),
+		},
+		{
+			name:  "date and time with milliseconds",
+			since: "2022-01-28 12:29:59.500",
+			want:  int64Ptr(0),
+		},
+		{
+			name:  "time today",
+			since: "12:00:00.120",
+			want:  int64Ptr(1799),
+		},
+		{
+			name:  "time yesterday",
+			since: "13:30:00",
+			want:  int64Ptr(82800),
+		},
+		{
+			name:  "date only",
+			since: "2022-01-20",
+		},
+		{
+			name:  "in the future",
+			since: "2022-01-29 00:00:00",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, bgpPeerUptime(tt.since, now))
+		})
+	}
+}
+
+func int64Ptr(v int64) *int64 {
+	return &v
+}
+
+func Test_loadBgpPeerRules(t *testing.T) {
+	tests := []struct {
+		name string
+
+		file    string
+		want    []BGPPeerRule
+		wantErr string
+	}{
+		{
+			name: "valid",
+			file: "- protocol: linx_rs1\n  label: LINX LON1 route server 1\n- protocol: decix_*\n",
+			want: []BGPPeerRule{
+				{Protocol: "linx_rs1", Label: "LINX LON1 route server 1"},
+				{Protocol: "decix_*"},
+			},
+		},
+		{
+			name:    "unknown field",
+			file:    "- protocol: linx_rs1\n  name: LINX\n",
+			wantErr: "field name not found",
+		},
+		{
+			name:    "missing protocol",
+			file:    "- label: LINX\n",
+			wantErr: "rule is missing a protocol",
+		},
+		{
+			name:    "invalid pattern",
+			file:    "- protocol: linx_[\n",
+			wantErr: "invalid protocol pattern linx_[",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "peers.yml")
+			if err := os.WriteFile(path, []byte(tt.file), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			rules, err := loadBgpPeerRules(path)
+			if tt.wantErr != "" {
+				if assert.Error(t, err) {
+					assert.Contains(t, err.Error(), tt.wantErr)
+				}
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, rules)
+		})
+	}
+}
@@ -0,0 +1,329 @@
+package api_v1
+
+import (
+	"errors"
+	"net/netip"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/krystal/krystal-network-tools/backend/asinfo"
+	"github.com/krystal/krystal-network-tools/backend/community"
+	"github.com/krystal/krystal-network-tools/backend/rpki"
+)
+
+const (
+	// Defines the number of routes a search returns by default, and the most it can be set to.
+	defaultSearchLimit = 100
+	maxSearchLimit     = 1000
+
+	// Defines the maximum number of ASes and wildcards in an AS path pattern.
+	maxAsPathPatternLength = 16
+)
+
+// RouteFilter is used to define the filters for a route search. Every filter which is set must match.
+type RouteFilter struct {
+	// Community is set to a standard community the routes must have.
+	Community *[2]uint16
+
+	// LargeCommunity is set to a large community the routes must have.
+	LargeCommunity *[3]uint32
+
+	// Transit is set to an AS which must be somewhere in the AS path.
+	Transit *uint32
+
+	// Origin is set to the AS the AS path must end in.
+	Origin *uint32
+
+	// AsPath is set to a pattern the AS path must match. Each element is an ASN, "*" for any number of ASes, or
+	// "?" for any one AS.
+	AsPath []string
+
+	// MinLength and MaxLength are set to limit the prefix lengths of the routes.
+	MinLength *int
+	MaxLength *int
+
+	// IPv4 and IPv6 are used to define the address families to search.
+	IPv4 bool
+	IPv6 bool
+}
+
+// Returns the filter as the condition of a BIRD where clause. Everything in the filter has been validated, so
+// nothing from the user makes it into the condition as is.
+func (f RouteFilter) birdCondition() string {
+	conditions := []string{}
+	if f.Community != nil {
+		conditions = append(conditions, "("+strconv.Itoa(int(f.Community[0]))+","+
+			strconv.Itoa(int(f.Community[1]))+") ~ bgp_community")
+	}
+	if f.LargeCommunity != nil {
+		conditions = append(conditions, "("+strconv.FormatUint(uint64(f.LargeCommunity[0]), 10)+","+
+			strconv.FormatUint(uint64(f.LargeCommunity[1]), 10)+","+
+			strconv.FormatUint(uint64(f.LargeCommunity[2]), 10)+") ~ bgp_large_community")
+	}
+	if f.Transit != nil {
+		conditions = append(conditions, "bgp_path ~ [= * "+strconv.FormatUint(uint64(*f.Transit), 10)+" * =]")
+	}
+	if f.Origin != nil {
+		conditions = append(conditions, "bgp_path.last = "+strconv.FormatUint(uint64(*f.Origin), 10))
+	}
+	if f.AsPath != nil {
+		conditions = append(conditions, "bgp_path ~ [= "+strings.Join(f.AsPath, " ")+" =]")
+	}
+	if f.MinLength != nil {
+		conditions = append(conditions, "net.len >= "+strconv.Itoa(*f.MinLength))
+	}
+	if f.MaxLength != nil {
+		conditions = append(conditions, "net.len <= "+strconv.Itoa(*f.MaxLength))
+	}
+	return strings.Join(conditions, " && ")
+}
+
+// SearchSource is used to define a route source which can search for routes.
+type SearchSource interface {
+	// SearchRoutes returns every route matching the filter. If more routes than the limit match, a
+	// *tooManyRoutesError is returned.
+	SearchRoutes(filter RouteFilter, limit int) (BGPRouteSlice, error)
+}
+
+// Defines the error returned when a search matches too many routes.
+type tooManyRoutesError struct {
+	count int
+	limit int
+}
+
+// Error implements error.
+func (e *tooManyRoutesError) Error() string {
+	return strconv.Itoa(e.count) + " routes match the search, which is more than the limit of " +
+		strconv.Itoa(e.limit) + ". Add more filters to narrow it down."
+}
+
+type bgpSearchParams struct {
+	// Community is used to define a standard community the routes must have, such as 65000:666.
+	Community string `form:"community"`
+
+	// LargeCommunity is used to define a large community the routes must have, such as 65000:1:2.
+	LargeCommunity string `form:"large_community"`
+
+	// Transit is used to define an AS which must be somewhere in the AS path.
+	Transit string `form:"transit"`
+
+	// Origin is used to define the AS the AS path must end in.
+	Origin string `form:"origin"`
+
+	// AsPath is used to define a space separated pattern the AS path must match. Each element is an ASN, "*" for
+	// any number of ASes, or "?" for any one AS.
+	AsPath string `form:"as_path"`
+
+	// MinLength is used to define the shortest prefix length to return.
+	MinLength *int `form:"min_length"`
+
+	// MaxLength is used to define the longest prefix length to return.
+	MaxLength *int `form:"max_length"`
+
+	// Family is used to define the address family to search. This can be blank for both, ipv4 or ipv6.
+	Family string `form:"family"`
+
+	// Limit is used to define the maximum number of routes to return.
+	Limit int `form:"limit"`
+}
+
+// Parses a community with the number of fields given, each of which can be up to the maximum.
+func parseCommunityFields(s string, fields int, max uint64) ([]uint64, bool) {
+	split := strings.Split(s, ":")
+	if len(split) != fields {
+		return nil, false
+	}
+	values := make([]uint64, fields)
+	for i, v := range split {
+		n, err := strconv.ParseUint(v, 10, 64)
+		if err != nil || n > max {
+			return nil, false
+		}
+		values[i] = n
+	}
+	return values, true
+}
+
+// Validates the search params and turns them into a filter.
+func (p bgpSearchParams) filter() (RouteFilter, error) {
+	f := RouteFilter{}
+	if p.Community != "" {
+		v, ok := parseCommunityFields(p.Community, 2, 65535)
+		if !ok {
+			return f, errors.New("community must be in the format asn:value")
+		}
+		f.Community = &[2]uint16{uint16(v[0]), uint16(v[1])}
+	}
+	if p.LargeCommunity != "" {
+		v, ok := parseCommunityFields(p.LargeCommunity, 3, 4294967295)
+		if !ok {
+			return f, errors.New("large community must be in the format asn:function:parameter")
+		}
+		f.LargeCommunity = &[3]uint32{uint32(v[0]), uint32(v[1]), uint32(v[2])}
+	}
+	if p.Transit != "" {
+		asn, ok := parseASN(p.Transit)
+		if !ok {
+			return f, errors.New("transit must be an ASN")
+		}
+		f.Transit = &asn
+	}
+	if p.Origin != "" {
+		asn, ok := parseASN(p.Origin)
+		if !ok {
+			return f, errors.New("origin must be an ASN")
+		}
+		f.Origin = &asn
+	}
+	if p.AsPath != "" {
+		hasASN := false
+		for _, v := range strings.Fields(p.AsPath) {
+			if v != "*" && v != "?" {
+				asn, ok := parseASN(v)
+				if !ok {
+					return f, errors.New("as path patterns can only contain ASNs, * and ?")
+				}
+				v = strconv.FormatUint(uint64(asn), 10)
+				hasASN = true
+			}
+			f.AsPath = append(f.AsPath, v)
+		}
+		if !hasASN {
+			return f, errors.New("as path patterns must contain an ASN")
+		}
+		if len(f.AsPath) > maxAsPathPatternLength {
+			return f, errors.New("as path patterns can be at most " + strconv.Itoa(maxAsPathPatternLength) +
+				" elements long")
+		}
+	}
+	if f.Community == nil && f.LargeCommunity == nil && f.Transit == nil && f.Origin == nil && f.AsPath == nil {
+		return f, errors.New("at least one of community, large_community, transit, origin or as_path is required")
+	}
+
+	// Check the address families and prefix lengths.
+	maxBits := 128
+	switch p.Family {
+	case "":
+		f.IPv4, f.IPv6 = true, true
+	case "ipv4":
+		f.IPv4 = true
+		maxBits = 32
+	case "ipv6":
+		f.IPv6 = true
+	default:
+		return f, errors.New("family must be ipv4 or ipv6")
+	}
+	for _, v := range []*int{p.MinLength, p.MaxLength} {
+		if v != nil && (*v < 0 || *v > maxBits) {
+			return f, errors.New("prefix lengths must be between 0 and " + strconv.Itoa(maxBits))
+		}
+	}
+	if p.MinLength != nil && p.MaxLength != nil && *p.MinLength > *p.MaxLength {
+		return f, errors.New("min_length must not be more than max_length")
+	}
+	f.MinLength, f.MaxLength = p.MinLength, p.MaxLength
+	return f, nil
+}
+
+// Sorts routes by address family, then prefix, keeping the order of the routes for each prefix.
+func sortRoutesByPrefix(routes BGPRouteSlice) {
+	key := func(route *BGPRoute) netip.Prefix {
+		if route.Prefix == nil {
+			return netip.Prefix{}
+		}
+		prefix, _ := netip.ParsePrefix(*route.Prefix)
+		return prefix
+	}
+	sort.SliceStable(routes, func(i, j int) bool {
+		a, b := key(routes[i]), key(routes[j])
+		if a.Addr().Is4() != b.Addr().Is4() {
+			return a.Addr().Is4()
+		}
+		if c := a.Addr().Compare(b.Addr()); c != 0 {
+			return c < 0
+		}
+		return a.Bits() < b.Bits()
+	})
+}
+
+func bgpSearch(
+	g group, source RouteSource, validator *rpki.Validator, communities *community.Dictionary,
+	asNames *asinfo.Resolver,
+) {
+	g.GET("/search", func(context *gin.Context) {
+		// Defines if this is JSON.
+		isJson := context.ContentType() == "application/json"
+
+		// Bind the params.
+		var params bgpSearchParams
+		if err := context.BindQuery(&params); err != nil {
+			if isJson {
+				context.JSON(400, map[string]string{
+					"message": err.Error(),
+				})
+			} else {
+				context.String(400, "unable to parse query params: %s", err.Error())
+			}
+			return
+		}
+
+		// Validate the filters and limit.
+		filter, err := params.filter()
+		if err != nil {
+			context.Error(&gin.Error{Err: err, Type: gin.ErrorTypePublic})
+			return
+		}
+		limit := params.Limit
+		if limit == 0 {
+			limit = defaultSearchLimit
+		}
+		if limit < 0 || limit > maxSearchLimit {
+			context.Error(&gin.Error{
+				Err:  errors.New("limit must be between 1 and " + strconv.Itoa(maxSearchLimit)),
+				Type: gin.ErrorTypePublic,
+			})
+			return
+		}
+
+		// Check the route source supports this.
+		searchSource, ok := source.(SearchSource)
+		if !ok {
+			context.Error(&gin.Error{
+				Err:  errors.New("searching routes is not supported by this region's router"),
+				Type: gin.ErrorTypePublic,
+			})
+			return
+		}
+
+		// Do the search.
+		routes, err := searchSource.SearchRoutes(filter, limit)
+		if err != nil {
+			var (
+				sourceErr  *routeSourceError
+				tooManyErr *tooManyRoutesError
+			)
+			switch {
+			case errors.As(err, &sourceErr):
+				context.Error(&gin.Error{Err: sourceErr, Type: gin.ErrorTypePublic})
+			case errors.As(err, &tooManyErr):
+				context.Error(&gin.Error{Err: tooManyErr, Type: gin.ErrorTypePublic})
+			default:
+				context.Error(err)
+			}
+			return
+		}
+		sortRoutesByPrefix(routes)
+
+		// Add the information which isn't in the routing table.
+		routes.enrich(validator, communities, asNames)
+
+		// Return the routes.
+		if isJson {
+			context.JSON(200, routes)
+		} else {
+			context.String(200, routes.String()+routes.annotations())
+		}
+	})
+}
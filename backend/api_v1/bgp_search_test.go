@@ -0,0 +1,292 @@
+package api_v1
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jimeh/go-golden"
+	"github.com/krystal/krystal-network-tools/backend/bird"
+	"github.com/stretchr/testify/assert"
+)
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func Test_bgpSearchParams_filter(t *testing.T) {
+	tests := []struct {
+		name string
+
+		params    bgpSearchParams
+		condition string
+		ipv4      bool
+		ipv6      bool
+		wantErr   string
+	}{
+		{
+			name:      "community",
+			params:    bgpSearchParams{Community: "65000:666"},
+			condition: "(65000,666) ~ bgp_community",
+			ipv4:      true,
+			ipv6:      true,
+		},
+		{
+			name: "everything",
+			params: bgpSearchParams{
+				LargeCommunity: "4200000000:1:2",
+				Transit:        "AS174",
+				Origin:         "13335",
+				AsPath:         "* 174 ? as13335",
+				MinLength:      intPtr(8),
+				MaxLength:      intPtr(24),
+				Family:         "ipv4",
+			},
+			condition: "(4200000000,1,2) ~ bgp_large_community && bgp_path ~ [= * 174 * =] && " +
+				"bgp_path.last = 13335 && bgp_path ~ [= * 174 ? 13335 =] && net.len >= 8 && net.len <= 24",
+			ipv4: true,
+		},
+		{
+			name:    "no selective filter",
+			params:  bgpSearchParams{MinLength: intPtr(8)},
+			wantErr: "at least one of community, large_community, transit, origin or as_path is required",
+		},
+		{
+			name:    "community out of range",
+			params:  bgpSearchParams{Community: "65536:1"},
+			wantErr: "community must be in the format asn:value",
+		},
+		{
+			name:    "community injection",
+			params:  bgpSearchParams{Community: "1:1) ~ bgp_community || (1"},
+			wantErr: "community must be in the format asn:value",
+		},
+		{
+			name:    "large community wrong fields",
+			params:  bgpSearchParams{LargeCommunity: "1:2"},
+			wantErr: "large community must be in the format asn:function:parameter",
+		},
+		{
+			name:    "invalid transit",
+			params:  bgpSearchParams{Transit: "174; show protocols"},
+			wantErr: "transit must be an ASN",
+		},
+		{
+			name:    "invalid as path element",
+			params:  bgpSearchParams{AsPath: "174 =] || [= *"},
+			wantErr: "as path patterns can only contain ASNs, * and ?",
+		},
+		{
+			name:    "as path without asn",
+			params:  bgpSearchParams{AsPath: "* ?"},
+			wantErr: "as path patterns must contain an ASN",
+		},
+		{
+			name:    "as path too long",
+			params:  bgpSearchParams{AsPath: "1 2 3 4 5 6 7 8 9 10 11 12 13 14 15 16 17"},
+			wantErr: "as path patterns can be at most 16 elements long",
+		},
+		{
+			name:    "invalid family",
+			params:  bgpSearchParams{Origin: "13335", Family: "inet"},
+			wantErr: "family must be ipv4 or ipv6",
+		},
+		{
+			name:    "ipv4 length too long",
+			params:  bgpSearchParams{Origin: "13335", Family: "ipv4", MaxLength: intPtr(48)},
+			wantErr: "prefix lengths must be between 0 and 32",
+		},
+		{
+			name:    "min more than max",
+			params:  bgpSearchParams{Origin: "13335", MinLength: intPtr(24), MaxLength: intPtr(16)},
+			wantErr: "min_length must not be more than max_length",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := tt.params.filter()
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.condition, f.birdCondition())
+			assert.Equal(t, tt.ipv4, f.IPv4)
+			assert.Equal(t, tt.ipv6, f.IPv6)
+		})
+	}
+}
+
+func Test_parseBirdRouteCount(t *testing.T) {
+	tests := []struct {
+		name string
+
+		lines   []bird.Line
+		want    int
+		wantErr string
+	}{
+		{
+			name:  "one table",
+			lines: []bird.Line{{Code: "0014", Text: "3 of 3 routes for 3 networks in table master4"}},
+			want:  3,
+		},
+		{
+			name: "each table",
+			lines: []bird.Line{
+				{Code: "0014", Text: "3 of 3 routes for 3 networks in table master4"},
+				{Code: "0014", Text: "2 of 2 routes for 2 networks in table master6"},
+			},
+			want: 5,
+		},
+		{
+			name: "total",
+			lines: []bird.Line{
+				{Code: "0014", Text: "3 of 3 routes for 3 networks in table master4"},
+				{Code: "0014", Text: "2 of 2 routes for 2 networks in table master6"},
+				{Code: "0014", Text: "Total: 5 of 5 routes for 5 networks in 2 tables"},
+			},
+			want: 5,
+		},
+		{
+			name:    "no count",
+			lines:   []bird.Line{{Code: "0000"}},
+			wantErr: "no route count in bird reply",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			count, err := parseBirdRouteCount(tt.lines)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, count)
+		})
+	}
+}
+
+func Test_bgpSearch(t *testing.T) {
+	tests := []struct {
+		name string
+
+		tapeFile string
+		writes   []string
+		query    string
+		json     bool
+		wantErr  string
+	}{
+		{
+			name:     "successful json",
+			tapeFile: "search_community.json",
+			writes: []string{
+				"show route where net.type = NET_IP4 && (65000,666) ~ bgp_community count\n",
+				"show route table master6 where net.type = NET_IP6 && (65000,666) ~ bgp_community count\n",
+				"show route where net.type = NET_IP4 && (65000,666) ~ bgp_community all\n",
+				"show route table master6 where net.type = NET_IP6 && (65000,666) ~ bgp_community all\n",
+			},
+			query: "community=65000:666",
+			json:  true,
+		},
+		{
+			name:     "successful text",
+			tapeFile: "search_community.json",
+			writes: []string{
+				"show route where net.type = NET_IP4 && (65000,666) ~ bgp_community count\n",
+				"show route table master6 where net.type = NET_IP6 && (65000,666) ~ bgp_community count\n",
+				"show route where net.type = NET_IP4 && (65000,666) ~ bgp_community all\n",
+				"show route table master6 where net.type = NET_IP6 && (65000,666) ~ bgp_community all\n",
+			},
+			query: "community=65000:666",
+		},
+		{
+			name:     "too many routes",
+			tapeFile: "search_too_many.json",
+			writes: []string{
+				"show route where net.type = NET_IP4 && bgp_path ~ [= * 174 * =] count\n",
+				"show route table master6 where net.type = NET_IP6 && bgp_path ~ [= * 174 * =] count\n",
+			},
+			query:   "transit=174",
+			wantErr: "150 routes match the search, which is more than the limit of 100. Add more filters to narrow it down.",
+		},
+		{
+			name:     "ipv4 reply with ipv6 routes",
+			tapeFile: "search_family.json",
+			writes: []string{
+				"show route where net.type = NET_IP4 && (65000,666) ~ bgp_community count\n",
+				"show route where net.type = NET_IP4 && (65000,666) ~ bgp_community all\n",
+			},
+			query: "community=65000:666&family=ipv4&limit=1",
+			json:  true,
+		},
+		{
+			name:     "more routes than counted",
+			tapeFile: "search_grew.json",
+			writes: []string{
+				"show route where net.type = NET_IP4 && (65000,666) ~ bgp_community count\n",
+				"show route where net.type = NET_IP4 && (65000,666) ~ bgp_community all\n",
+			},
+			query:   "community=65000:666&family=ipv4&limit=1",
+			wantErr: "2 routes match the search, which is more than the limit of 1. Add more filters to narrow it down.",
+		},
+		{
+			name:    "invalid limit",
+			query:   "origin=13335&limit=5000",
+			wantErr: "limit must be between 1 and 1000",
+		},
+		{
+			name:    "invalid filter",
+			query:   "community=bad",
+			wantErr: "community must be in the format asn:value",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Create the route source.
+			var mocker io.ReadWriteCloser
+			if tt.tapeFile != "" {
+				mocker = newBgpTape(t, tt.tapeFile, tt.writes)
+			}
+			source := birdSource{
+				socketBuilder: func() (io.ReadWriteCloser, error) { return mocker, nil },
+				tables:        bgpTables{IPv6: "master6"},
+			}
+
+			// Get the handler.
+			hn := mockGroupSingleHn(t, "GET", "/search", func(g group) {
+				bgpSearch(g, source, nil, nil, nil)
+			})
+			if hn == nil {
+				return
+			}
+
+			// Call the handler.
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = &http.Request{
+				URL:    &url.URL{Path: "/search", RawQuery: tt.query},
+				Header: http.Header{},
+			}
+			if tt.json {
+				c.Request.Header.Set("Content-Type", "application/json")
+			}
+			hn(c)
+
+			// Check the result.
+			if tt.wantErr != "" {
+				if assert.Len(t, c.Errors, 1) {
+					assert.EqualError(t, c.Errors[0], tt.wantErr)
+				}
+				return
+			}
+			assert.Equal(t, http.StatusOK, w.Code)
+			if golden.Update() {
+				golden.Set(t, attemptJsonBeautify(w.Body.Bytes()))
+			}
+			assert.Equal(t, string(golden.Get(t)), string(attemptJsonBeautify(w.Body.Bytes())))
+		})
+	}
+}
@@ -0,0 +1,167 @@
+package api_v1
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/netip"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RouteQuery is used to define what to look up in a route source.
+type RouteQuery struct {
+	// Prefix is used to define the prefix to look up. For addresses, this is the host prefix of the address.
+	Prefix netip.Prefix
+
+	// Address is true if the most specific route covering the address should be looked up rather than the prefix.
+	Address bool
+}
+
+// String returns the address or prefix being looked up.
+func (q RouteQuery) String() string {
+	if q.Address {
+		return q.Prefix.Addr().String()
+	}
+	return q.Prefix.String()
+}
+
+// Parses an address or prefix into a query. Prefixes have their host bits cleared and IPv4 mapped IPv6 addresses
+// are turned into IPv4 addresses. False is returned if it is not a valid address or prefix.
+func parseRouteQuery(ip string) (RouteQuery, bool) {
+	if strings.Contains(ip, "/") {
+		prefix, err := netip.ParsePrefix(ip)
+		if err != nil {
+			return RouteQuery{}, false
+		}
+		return RouteQuery{Prefix: prefix.Masked()}, true
+	}
+
+	addr, err := netip.ParseAddr(ip)
+	if err != nil || addr.Zone() != "" {
+		return RouteQuery{}, false
+	}
+	addr = addr.Unmap()
+	return RouteQuery{Prefix: netip.PrefixFrom(addr, addr.BitLen()), Address: true}, true
+}
+
+// RouteSource is used to define something which routes can be looked up in, such as a routing daemon.
+type RouteSource interface {
+	// Lookup looks up the routes for a query. If the source has human readable output, it is returned along with
+	// the routes. The output can be returned along with an error if the routes could not be parsed.
+	Lookup(q RouteQuery) (BGPRouteSlice, string, error)
+}
+
+// Defines an error from a route source which is safe to show to the user.
+type routeSourceError struct {
+	source  string
+	message string
+}
+
+// Error implements error.
+func (e *routeSourceError) Error() string {
+	return e.source + " lookup failed: " + e.message
+}
+
+// Defines how long commands run for route lookups, and calls to the GoBGP API, can take.
+const routeCommandTimeout = 10 * time.Second
+
+// commandRunner is used to define a function which runs a command and returns its standard output.
+type commandRunner func(name string, args ...string) ([]byte, error)
+
+// Runs a command with a timeout. If it fails, the error contains what it wrote to standard error.
+func runCommand(name string, args ...string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), routeCommandTimeout)
+	defer cancel()
+
+	stderr := &bytes.Buffer{}
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stderr = stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, errors.New(msg)
+		}
+		return nil, err
+	}
+	return out, nil
+}
+
+// Returns the value of an environment variable, or the default if it is not set.
+func envDefault(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+// Gets the route source from the environment. BGP_ROUTE_SOURCE can be set to bird, frr, gobgp or openbgpd, and
+// defaults to bird. FRR and OpenBGPD are queried through their command line tools, and GoBGP is queried through the
+// ListPath method of its gRPC API at GOBGP_ADDRESS.
+func getRouteSource() (RouteSource, error) {
+	switch source := envDefault("BGP_ROUTE_SOURCE", "bird"); source {
+	case "bird":
+		return birdSource{socketBuilder: makeBirdSocket, tables: getBgpTables()}, nil
+	case "frr":
+		return frrSource{run: runCommand, vtysh: envDefault("VTYSH_PATH", "vtysh")}, nil
+	case "gobgp":
+		return newGobgpSource(envDefault("GOBGP_ADDRESS", "127.0.0.1:50051"))
+	case "openbgpd":
+		return openbgpdSource{
+			run:    runCommand,
+			bgpctl: envDefault("BGPCTL_PATH", "bgpctl"),
+			socket: os.Getenv("BGPCTL_SOCKET"),
+		}, nil
+	default:
+		return nil, errors.New("unknown BGP_ROUTE_SOURCE: " + source)
+	}
+}
+
+// Defines the BIRD names for the types of extended communities, keyed by the lower case name other daemons use.
+var extCommunityKinds = map[string]string{
+	"rt":  "rt",
+	"soo": "ro",
+	"ro":  "ro",
+}
+
+// Converts communities in the colon separated format other daemons use into the format BIRD uses, so BGPRoute is
+// the same whichever source it came from. Standard communities are "64500,100" and large communities are
+// "64500, 1, 2".
+func birdCommunities(communities []string, large bool) []string {
+	if len(communities) == 0 {
+		return nil
+	}
+	sep := ","
+	if large {
+		sep = ", "
+	}
+	converted := make([]string, len(communities))
+	for i, v := range communities {
+		converted[i] = strings.ReplaceAll(v, ":", sep)
+	}
+	return converted
+}
+
+// Converts an extended community with its type and value, such as "RT" and "64500:100", into the format BIRD uses,
+// which is "rt, 64500, 100".
+func birdExtCommunity(kind, value string) string {
+	kind = strings.ToLower(kind)
+	if v, ok := extCommunityKinds[kind]; ok {
+		kind = v
+	}
+	return kind + ", " + strings.ReplaceAll(value, ":", ", ")
+}
+
+// Parses the ASNs from an AS path in the format "64500 64501 {64502 64503}". AS sets are ignored.
+func parseAsPathString(path string) []int {
+	asns := []int{}
+	for _, v := range strings.Fields(path) {
+		if i, err := strconv.Atoi(v); err == nil {
+			asns = append(asns, i)
+		}
+	}
+	return asns
+}
@@ -0,0 +1,183 @@
+package api_v1
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/jimeh/go-golden"
+	"github.com/stretchr/testify/assert"
+)
+
+// Returns a command runner which checks the command and returns the output.
+func fakeCommandRunner(t *testing.T, wantArgs []string, out string, err error) commandRunner {
+	return func(name string, args ...string) ([]byte, error) {
+		assert.Equal(t, wantArgs, append([]string{name}, args...))
+		return []byte(out), err
+	}
+}
+
+// Checks the routes from a route source against the golden file. The JSON and text output are both included.
+func assertRoutesGolden(t *testing.T, routes BGPRouteSlice) {
+	t.Helper()
+	b, err := json.MarshalIndent(routes, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b = append(b, "\n\n"+routes.String()...)
+	if golden.Update() {
+		golden.Set(t, b)
+	}
+	assert.Equal(t, string(golden.Get(t)), string(b))
+}
+
+func Test_parseRouteQuery(t *testing.T) {
+	tests := []struct {
+		name string
+
+		ip  string
+		str string
+		v6  bool
+	}{
+		{
+			name: "ipv4 address",
+			ip:   "1.1.1.1",
+			str:  "1.1.1.1",
+		},
+		{
+			name: "ipv4 prefix",
+			ip:   "1.1.1.1/24",
+			str:  "1.1.1.0/24",
+		},
+		{
+			name: "ipv6 address",
+			ip:   "2a03:2800::1",
+			str:  "2a03:2800::1",
+			v6:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, ok := parseRouteQuery(tt.ip)
+			assert.True(t, ok)
+			assert.Equal(t, tt.str, q.String())
+			assert.Equal(t, tt.v6, q.Prefix.Addr().Is6())
+		})
+	}
+}
+
+func Test_routeSources(t *testing.T) {
+	tests := []struct {
+		name string
+
+		source   func(run commandRunner) RouteSource
+		ip       string
+		wantArgs []string
+		out      string
+		runErr   error
+		wantErr  string
+	}{
+		{
+			name: "frr",
+			source: func(run commandRunner) RouteSource {
+				return frrSource{run: run, vtysh: "vtysh"}
+			},
+			ip:       "1.1.1.1",
+			wantArgs: []string{"vtysh", "-c", "show bgp ipv4 unicast 1.1.1.1 json"},
+			out: `{
+  "prefix": "1.1.1.0/24",
+  "paths": [
+    {
+      "aspath": {"string": "3223 2914 13335", "segments": [{"type": "as-sequence", "list": [3223, 2914, 13335]}]},
+      "aggregatorAs": 13335,
+      "aggregatorId": "141.101.71.254",
+      "origin": "IGP",
+      "localpref": 100,
+      "valid": true,
+      "community": {"string": "0:0 3223:888 noExport", "list": ["0:0", "3223:888", "noExport"]},
+      "lastUpdate": {"epoch": 1643364000, "string": "Fri Jan 28 10:00:00 2022\n"},
+      "nexthops": [{"ip": "185.242.206.17", "afi": "ipv4", "used": true}],
+      "peer": {"peerId": "77.72.0.238", "routerId": "77.72.0.238", "hostname": "gaia-a", "type": "external"}
+    },
+    {
+      "aspath": {"string": "13335", "segments": [{"type": "as-sequence", "list": [13335]}]},
+      "origin": "IGP",
+      "metric": 0,
+      "localpref": 200,
+      "valid": true,
+      "bestpath": {"overall": true, "selectionReason": "Local Pref"},
+      "atomicAggregate": true,
+      "largeCommunity": {"string": "8714:1000:1", "list": ["8714:1000:1"]},
+      "extendedCommunity": {"string": "RT:64500:100 SoO:10.0.0.1:5"},
+      "originatorId": "10.0.0.2",
+      "clusterList": {"list": ["10.0.0.1"]},
+      "lastUpdate": {"epoch": 1643277600, "string": "Thu Jan 27 10:00:00 2022\n"},
+      "nexthops": [{"ip": "195.66.225.179", "afi": "ipv4", "used": true}],
+      "peer": {"peerId": "77.72.0.239", "routerId": "77.72.0.239", "type": "external"}
+    }
+  ]
+}`,
+		},
+		{
+			name: "frr error",
+			source: func(run commandRunner) RouteSource {
+				return frrSource{run: run, vtysh: "vtysh"}
+			},
+			ip:       "2a03:2800::/48",
+			wantArgs: []string{"vtysh", "-c", "show bgp ipv6 unicast 2a03:2800::/48 json"},
+			runErr:   errors.New("Exiting: failed to connect to any daemons."),
+			wantErr:  "vtysh failed: Exiting: failed to connect to any daemons.",
+		},
+		{
+			name: "openbgpd",
+			source: func(run commandRunner) RouteSource {
+				return openbgpdSource{run: run, bgpctl: "bgpctl", socket: "/var/run/bgpd.sock.0"}
+			},
+			ip: "2a03:2800::1",
+			wantArgs: []string{
+				"bgpctl", "-s", "/var/run/bgpd.sock.0", "-j", "show", "rib", "detail", "2a03:2800::1",
+			},
+			out: `{"rib": [
+  {
+    "prefix": "2a03:2800::/32",
+    "aspath": "34309 32934 32934 32934",
+    "exit_nexthop": "2001:7f8:4::8605:1",
+    "true_nexthop": "2001:7f8:4::8605:1",
+    "neighbor": {"description": "linx_rs1", "remote_addr": "2001:7f8:4::8605:1", "bgp_id": "80.95.152.37"},
+    "valid": true,
+    "best": true,
+    "origin": "incomplete",
+    "metric": 0,
+    "localpref": 100,
+    "last_update": "02:03:04",
+    "communities": ["34309:2100", "NO_EXPORT"],
+    "extended_communities": ["rt 64500:100"],
+    "attributes": [
+      {"type": "ATOMIC_AGGREGATE", "length": 0},
+      {"type": "AGGREGATOR", "length": 8, "AS": 32934, "router_id": "10.0.0.9"}
+    ]
+  }
+]}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, ok := parseRouteQuery(tt.ip)
+			if !assert.True(t, ok) {
+				return
+			}
+			source := tt.source(fakeCommandRunner(t, tt.wantArgs, tt.out, tt.runErr))
+			routes, raw, err := source.Lookup(q)
+			if tt.wantErr != "" {
+				// The errors can contain details of the server, so they must not be shown to the user.
+				assert.EqualError(t, err, tt.wantErr)
+				var sourceErr *routeSourceError
+				assert.False(t, errors.As(err, &sourceErr))
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, "", raw)
+			assertRoutesGolden(t, routes)
+		})
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/netip"
 	"net/url"
 	"os"
 	"path/filepath"
@@ -15,6 +16,9 @@ import (
 	"testing"
 
 	"github.com/gin-gonic/gin"
+	"github.com/krystal/krystal-network-tools/backend/asinfo"
+	"github.com/krystal/krystal-network-tools/backend/community"
+	"github.com/krystal/krystal-network-tools/backend/rpki"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -92,7 +96,8 @@ func (t *bgpTape) Write(data []byte) (n int, err error) {
 		return 0, io.EOF
 	}
 	assert.Equal(t.t, t.writes[t.writeIndex], string(data))
-	return
+	t.writeIndex++
+	return len(data), nil
 }
 
 func (t *bgpTape) Close() error {
@@ -100,6 +105,89 @@ func (t *bgpTape) Close() error {
 	return nil
 }
 
+func Test_bgpQuery(t *testing.T) {
+	tests := []struct {
+		name string
+
+		ip     string
+		tables bgpTables
+		query  string
+		ok     bool
+	}{
+		{
+			name:  "ipv4 address",
+			ip:    "1.1.1.1",
+			query: "for 1.1.1.1",
+			ok:    true,
+		},
+		{
+			name:  "ipv4 prefix",
+			ip:    "8.8.8.0/24",
+			query: "8.8.8.0/24",
+			ok:    true,
+		},
+		{
+			name:  "ipv4 prefix with host bits",
+			ip:    "8.8.8.8/24",
+			query: "8.8.8.0/24",
+			ok:    true,
+		},
+		{
+			name:   "ipv4 table",
+			ip:     "1.1.1.1",
+			tables: bgpTables{IPv4: "master4", IPv6: "master6"},
+			query:  "for 1.1.1.1 table master4",
+			ok:     true,
+		},
+		{
+			name:   "ipv6 address",
+			ip:     "2a03:2800::1",
+			tables: bgpTables{IPv6: "master6"},
+			query:  "for 2a03:2800::1 table master6",
+			ok:     true,
+		},
+		{
+			name:   "ipv6 /128",
+			ip:     "2001:db8:0:0:0:0:0:1/128",
+			tables: bgpTables{IPv6: "master6"},
+			query:  "2001:db8::1/128 table master6",
+			ok:     true,
+		},
+		{
+			name:   "ipv4 mapped ipv6 address",
+			ip:     "::ffff:1.1.1.1",
+			tables: bgpTables{IPv6: "master6"},
+			query:  "for 1.1.1.1",
+			ok:     true,
+		},
+		{
+			name: "ipv4 prefix too long",
+			ip:   "8.8.8.0/33",
+		},
+		{
+			name: "ipv6 prefix too long",
+			ip:   "2001:db8::/129",
+		},
+		{
+			name: "zone",
+			ip:   "fe80::1%eth0",
+		},
+		{
+			name: "hostname",
+			ip:   "example.com",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, ok := parseRouteQuery(tt.ip)
+			assert.Equal(t, tt.ok, ok)
+			if ok {
+				assert.Equal(t, tt.query, bgpQuery(q, tt.tables))
+			}
+		})
+	}
+}
+
 func Test_bgp(t *testing.T) {
 	tests := []struct {
 		name string
@@ -111,6 +199,9 @@ func Test_bgp(t *testing.T) {
 		addr        string
 		json        bool
 		urlEncode   bool
+		vrps        []rpki.VRP
+		communities *community.Set
+		asNames     []asinfo.Info
 	}{
 		{
 			name:        "socket error",
@@ -180,22 +271,158 @@ func Test_bgp(t *testing.T) {
 			urlEncode: true,
 			json:      true,
 		},
+		{
+			name:     "successful range with all attributes json",
+			tapeFile: "19202_24.json",
+			writes: []string{
+				"show route 192.0.2.0/24 all\n",
+			},
+			code: http.StatusOK,
+			addr: "192.0.2.0/24",
+			json: true,
+		},
+		{
+			name:     "successful ip with rpki json",
+			tapeFile: "1111.json",
+			writes: []string{
+				"show route for 1.1.1.1 all\n",
+			},
+			code: http.StatusOK,
+			addr: "1.1.1.1",
+			json: true,
+			vrps: []rpki.VRP{
+				{Prefix: netip.MustParsePrefix("1.1.1.0/24"), MaxLength: 24, ASN: 13335},
+				{Prefix: netip.MustParsePrefix("1.0.0.0/8"), MaxLength: 8, ASN: 0},
+			},
+		},
+		{
+			name:     "successful ip with rpki text",
+			tapeFile: "1111.json",
+			writes: []string{
+				"show route for 1.1.1.1 all\n",
+			},
+			code: http.StatusOK,
+			addr: "1.1.1.1",
+			vrps: []rpki.VRP{
+				{Prefix: netip.MustParsePrefix("1.1.1.0/24"), MaxLength: 24, ASN: 13335},
+				{Prefix: netip.MustParsePrefix("1.0.0.0/8"), MaxLength: 8, ASN: 0},
+			},
+		},
+		{
+			name:     "successful range with communities json",
+			tapeFile: "19202_24.json",
+			writes: []string{
+				"show route 192.0.2.0/24 all\n",
+			},
+			code: http.StatusOK,
+			addr: "192.0.2.0/24",
+			json: true,
+			communities: &community.Set{
+				Name:             "Example",
+				Communities:      map[string]string{"64500:100": "Learned from a customer"},
+				LargeCommunities: map[string]string{"64500:1:*": "Learned in region $3"},
+				ExtCommunities:   map[string]string{"rt:*:*": "Route target $2:$3", "ro:*:*": "Route origin $2:$3"},
+			},
+		},
+		{
+			name:     "successful range with communities text",
+			tapeFile: "19202_24.json",
+			writes: []string{
+				"show route 192.0.2.0/24 all\n",
+			},
+			code: http.StatusOK,
+			addr: "192.0.2.0/24",
+			communities: &community.Set{
+				Name:             "Example",
+				Communities:      map[string]string{"64500:100": "Learned from a customer"},
+				LargeCommunities: map[string]string{"64500:1:*": "Learned in region $3"},
+				ExtCommunities:   map[string]string{"rt:*:*": "Route target $2:$3", "ro:*:*": "Route origin $2:$3"},
+			},
+		},
+		{
+			name:     "successful ip with as names json",
+			tapeFile: "1111.json",
+			writes: []string{
+				"show route for 1.1.1.1 all\n",
+			},
+			code: http.StatusOK,
+			addr: "1.1.1.1",
+			json: true,
+			asNames: []asinfo.Info{
+				{ASN: 3223, Name: "VOXILITY", Country: "GB"},
+				{ASN: 2914, Name: "NTT-LTD-2914", Country: "US"},
+				{ASN: 13335, Name: "CLOUDFLARENET", Country: "US"},
+			},
+		},
+		{
+			name:     "successful ip with as names text",
+			tapeFile: "1111.json",
+			writes: []string{
+				"show route for 1.1.1.1 all\n",
+			},
+			code: http.StatusOK,
+			addr: "1.1.1.1",
+			asNames: []asinfo.Info{
+				{ASN: 3223, Name: "VOXILITY", Country: "GB"},
+				{ASN: 2914, Name: "NTT-LTD-2914", Country: "US"},
+				{ASN: 13335, Name: "CLOUDFLARENET", Country: "US"},
+			},
+		},
+		{
+			name:     "successful ipv6 range json",
+			tapeFile: "2a032800_48.json",
+			writes: []string{
+				"show route 2a03:2800::/48 table master6 all\n",
+			},
+			code:      http.StatusOK,
+			addr:      "2a03:2800::/48",
+			urlEncode: true,
+			json:      true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			// Create the mocker.
 			mocker := newBgpTape(t, tt.tapeFile, tt.writes)
 
+			// Create the RPKI validator.
+			var validator *rpki.Validator
+			if tt.vrps != nil {
+				validator = rpki.NewValidator()
+				validator.Replace(tt.vrps)
+			}
+
+			// Create the community dictionary.
+			var communities *community.Dictionary
+			if tt.communities != nil {
+				communities = community.NewDictionary()
+				if err := communities.Add(*tt.communities); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			// Create the AS name resolver.
+			var asNames *asinfo.Resolver
+			if tt.asNames != nil {
+				db := asinfo.NewDatabase()
+				db.Replace(tt.asNames)
+				asNames = asinfo.NewResolver(db, nil)
+			}
+
 			// Allow the function to insert into the group as it normally would.
 			handlers := mockGroupMultiHn(t, []string{"/:ip", "/:ip/:range"}, map[string]string{
 				"/:ip": "GET", "/:ip/:range": "GET",
 			}, func(g group) {
-				bgp(g, func() (io.ReadWriteCloser, error) {
-					if tt.socketError != "" {
-						return nil, errors.New(tt.socketError)
-					}
-					return mocker, nil
-				})
+				source := birdSource{
+					socketBuilder: func() (io.ReadWriteCloser, error) {
+						if tt.socketError != "" {
+							return nil, errors.New(tt.socketError)
+						}
+						return mocker, nil
+					},
+					tables: bgpTables{IPv6: "master6"},
+				}
+				bgp(g, source, validator, communities, asNames)
 			})
 			if handlers == nil {
 				return
@@ -13,6 +13,21 @@ import (
 type dnsParams struct {
 	// Trace is used to define if the DNS record should be traced all the way to the nameserver.
 	Trace bool `form:"trace"`
+
+	// Exhaustive is used to define if the trace should query every nameserver at every level.
+	Exhaustive bool `form:"exhaustive"`
+
+	// NoCache is used to define if the cache should be bypassed.
+	NoCache bool `form:"nocache"`
+
+	// ECS is used to define the prefix sent in the EDNS Client Subnet option.
+	ECS string `form:"ecs"`
+
+	// TTL is used to define if the cached records should be compared with the authoritative ones.
+	TTL bool `form:"ttl"`
+
+	// Format is used to define the text output format. This can be blank, zone or dig.
+	Format string `form:"format"`
 }
 
 func dns(g *gin.RouterGroup, log *zap.Logger, dnsServer string) {
@@ -44,9 +59,63 @@ func dns(g *gin.RouterGroup, log *zap.Logger, dnsServer string) {
 			return
 		}
 
+		// Check the format is valid.
+		if params.Format != "" && params.Format != "zone" && params.Format != "dig" {
+			context.Error(&gin.Error{
+				Type: gin.ErrorTypePublic,
+				Err:  errors.New("format must be zone or dig"),
+			})
+			return
+		}
+
+		// Handle TTL comparisons. These have a different response since there are two sides.
+		if params.TTL {
+			comparison, err := dnsLib.CompareTTLs(log, dnsServer, recordType, hostname, dnsLib.LookupOptions{
+				ClientSubnet: params.ECS,
+			})
+			if err != nil {
+				context.Error(&gin.Error{
+					Type: gin.ErrorTypePublic,
+					Err:  fmt.Errorf("failed to perform dns lookup: %v", err),
+				})
+				return
+			}
+			if isJson {
+				context.JSON(200, comparison)
+			} else {
+				context.String(200, comparison.String())
+			}
+			return
+		}
+
+		// Handle exhaustive traces. These have a different response since they are a tree.
+		if params.Trace && params.Exhaustive {
+			tree, err := dnsLib.TraceDelegations(log, recordType, hostname, dnsLib.LookupOptions{
+				NoCache:      params.NoCache,
+				ClientSubnet: params.ECS,
+			})
+			if err != nil {
+				context.Error(&gin.Error{
+					Type: gin.ErrorTypePublic,
+					Err:  fmt.Errorf("failed to perform dns lookup: %v", err),
+				})
+				return
+			}
+			if isJson {
+				context.JSON(200, tree)
+			} else {
+				context.String(200, tree.String())
+			}
+			return
+		}
+
 		// Do the DNS lookup.
 		results, err := dnsLib.Lookup(
-			log, dnsServer, recordType, hostname, params.Trace,
+			log, dnsServer, recordType, hostname, dnsLib.LookupOptions{
+				Trace:        params.Trace,
+				NoCache:      params.NoCache,
+				ClientSubnet: params.ECS,
+			},
 		)
 		if err != nil {
 			context.Error(&gin.Error{
@@ -56,6 +125,16 @@ func dns(g *gin.RouterGroup, log *zap.Logger, dnsServer string) {
 			return
 		}
 
+		// Handle the other output formats. These are always text since they are meant to be copied.
+		switch params.Format {
+		case "zone":
+			context.String(200, results.ZoneString())
+			return
+		case "dig":
+			context.String(200, results.DigString())
+			return
+		}
+
 		// Handle JSON responses.
 		if isJson {
 			context.JSON(200, results)
@@ -0,0 +1,67 @@
+package api_v1
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	dnsLib "github.com/krystal/krystal-network-tools/backend/dns"
+	"go.uber.org/zap"
+)
+
+type dnsCompareParams struct {
+	// NoCache is used to define if the cache should be bypassed.
+	NoCache bool `form:"nocache"`
+}
+
+func dnsCompare(g group, log *zap.Logger, resolvers []dnsLib.Resolver) {
+	g.GET("/:recordType/:hostname", func(ctx *gin.Context) {
+		// Defines if this is JSON.
+		isJson := ctx.ContentType() == "application/json"
+
+		// Bind the params.
+		var params dnsCompareParams
+		if err := ctx.BindQuery(&params); err != nil {
+			if isJson {
+				ctx.JSON(400, map[string]string{
+					"message": err.Error(),
+				})
+			} else {
+				ctx.String(400, "unable to parse query params: %s", err.Error())
+			}
+			return
+		}
+
+		// Get the type and hostname from the URL.
+		recordType := ctx.Param("recordType")
+		hostname := strings.TrimSuffix(ctx.Param("hostname"), ".")
+		if hostname == "" {
+			ctx.Error(&gin.Error{
+				Type: gin.ErrorTypePublic,
+				Err:  errors.New("invalid hostname"),
+			})
+			return
+		}
+
+		// Ask all of the resolvers.
+		comparison, err := dnsLib.CompareResolvers(
+			log, resolvers, recordType, hostname, dnsLib.LookupOptions{NoCache: params.NoCache},
+		)
+		if err != nil {
+			ctx.Error(&gin.Error{
+				Type: gin.ErrorTypePublic,
+				Err:  fmt.Errorf("failed to compare dns resolvers: %v", err),
+			})
+			return
+		}
+
+		// Handle JSON responses.
+		if isJson {
+			ctx.JSON(200, comparison)
+			return
+		}
+
+		ctx.String(200, comparison.String())
+	})
+}
@@ -0,0 +1,44 @@
+package api_v1
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/krystal/krystal-network-tools/backend/asinfo"
+	dnsLib "github.com/krystal/krystal-network-tools/backend/dns"
+	"go.uber.org/zap"
+)
+
+func dnsHealth(g group, log *zap.Logger, dnsServer string, asNames *asinfo.Resolver) {
+	g.GET("/:zone", func(ctx *gin.Context) {
+		// Get the zone from the URL.
+		zone := strings.TrimSuffix(ctx.Param("zone"), ".")
+		if zone == "" {
+			ctx.Error(&gin.Error{
+				Type: gin.ErrorTypePublic,
+				Err:  errors.New("invalid zone"),
+			})
+			return
+		}
+
+		// Run the checks against the zone.
+		report, err := dnsLib.CheckZoneHealth(log, dnsServer, zone, asNames)
+		if err != nil {
+			ctx.Error(&gin.Error{
+				Type: gin.ErrorTypePublic,
+				Err:  fmt.Errorf("failed to check zone health: %v", err),
+			})
+			return
+		}
+
+		// Handle JSON responses.
+		if ctx.ContentType() == "application/json" {
+			ctx.JSON(200, report)
+			return
+		}
+
+		ctx.String(200, report.String())
+	})
+}
@@ -1,16 +1,43 @@
 package api_v1
 
 import (
+	"os"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/krystal/krystal-network-tools/backend/asinfo"
+	"github.com/krystal/krystal-network-tools/backend/community"
+	dnsLib "github.com/krystal/krystal-network-tools/backend/dns"
 	"github.com/krystal/krystal-network-tools/backend/ratelimiter"
+	"github.com/krystal/krystal-network-tools/backend/rpki"
 	pingttl "github.com/strideynet/go-ping-ttl"
 	"go.uber.org/zap"
 )
 
 // Init initializes the API.
-func Init(g *gin.RouterGroup, log *zap.Logger, cachedDnsServer string, pinger *pingttl.Pinger) {
+func Init(
+	g *gin.RouterGroup, log *zap.Logger, cachedDnsServer string, compareResolvers []dnsLib.Resolver,
+	pinger *pingttl.Pinger, rpkiValidator *rpki.Validator, communities *community.Dictionary,
+	asNames *asinfo.Database,
+) {
+	// Create the AS name resolver. ASes which aren't in the dataset are only looked up with whois if
+	// AS_NAMES_WHOIS is set to true, since it makes responses wait on whois servers.
+	var asWhois asinfo.WhoisLookuper
+	if os.Getenv("AS_NAMES_WHOIS") == "true" {
+		asWhois = defaultWhoisLookuper{}
+	}
+	asResolver := asinfo.NewResolver(asNames, asWhois)
+
+	// Get the routing daemon to look up routes in.
+	routeSource, err := getRouteSource()
+	if err != nil {
+		log.Fatal("failed to configure bgp route source", zap.Error(err))
+	}
+	bgpPeerRules, err := getBgpPeerRules()
+	if err != nil {
+		log.Fatal("failed to load bgp peers file", zap.Error(err))
+	}
+
 	// Create the base bucket for a few types of requests related to pinging. This works out to
 	// 10 requests/second, so not awfully consequential to a server but will likely be fine for us.
 	pingingBucket := ratelimiter.NewBucket(log, 100, time.Second*10, time.Minute*10)
@@ -22,12 +49,33 @@ func Init(g *gin.RouterGroup, log *zap.Logger, cachedDnsServer string, pinger *p
 		g.Group("/dns", ratelimiter.NewBucket(log, 20, time.Hour, time.Minute*10)), log,
 		cachedDnsServer,
 	)
+	dnsHealth(
+		g.Group("/dns-health", ratelimiter.NewBucket(log, 20, time.Hour, time.Minute*10)), log,
+		cachedDnsServer, asResolver,
+	)
+	dnsCompare(
+		g.Group("/dns-compare", ratelimiter.NewBucket(log, 20, time.Hour, time.Minute*10)), log,
+		compareResolvers,
+	)
+	mailAuth(
+		g.Group("/mail-auth", ratelimiter.NewBucket(log, 20, time.Hour, time.Minute*10)), log,
+		cachedDnsServer,
+	)
 	traceroute(g.Group("/traceroute", pingingBucket), pinger)
-	bgp(g.Group("/bgp", ratelimiter.NewBucket(log, 20, time.Hour, time.Minute*10)), makeBirdSocket)
+	bgpGroup := g.Group("/bgp", ratelimiter.NewBucket(log, 20, time.Hour, time.Minute*10))
+	bgp(bgpGroup, routeSource, rpkiValidator, communities, asResolver)
+	bgpAS(bgpGroup, routeSource, asResolver)
+	bgpSearch(bgpGroup, routeSource, rpkiValidator, communities, asResolver)
+	bgpPeers(bgpGroup, routeSource, bgpPeerRules, asResolver, time.Now)
 	whois(g.Group("/whois", ratelimiter.NewBucket(log, 20, time.Hour, time.Minute*10)), defaultWhoisLookuper{})
 	rdns(
 		g.Group("/rdns", ratelimiter.NewBucket(log, 40, time.Hour, time.Minute*10)),
 		log,
 		cachedDnsServer,
 	)
+	rdnsBulk(
+		g.Group("/rdns-bulk", ratelimiter.NewBucket(log, 10, time.Hour, time.Minute*10)),
+		log,
+		cachedDnsServer,
+	)
 }
@@ -0,0 +1,80 @@
+package api_v1
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	dnsLib "github.com/krystal/krystal-network-tools/backend/dns"
+	"go.uber.org/zap"
+)
+
+// Defines the most DKIM selectors which can be checked in one request, since each one is looked up separately.
+const maxDKIMSelectors = 10
+
+type mailAuthParams struct {
+	DKIM string `form:"dkim"`
+}
+
+func mailAuth(g group, log *zap.Logger, dnsServer string) {
+	g.GET("/:domain", func(ctx *gin.Context) {
+		// Get the domain from the URL.
+		domain := strings.TrimSuffix(ctx.Param("domain"), ".")
+		if domain == "" {
+			ctx.Error(&gin.Error{
+				Type: gin.ErrorTypePublic,
+				Err:  errors.New("invalid domain"),
+			})
+			return
+		}
+
+		// Get the DKIM selectors from the query.
+		var params mailAuthParams
+		if err := ctx.BindQuery(&params); err != nil {
+			if ctx.ContentType() == "application/json" {
+				ctx.JSON(400, map[string]string{
+					"message": err.Error(),
+				})
+			} else {
+				ctx.String(400, "unable to parse query params: %s", err.Error())
+			}
+			return
+		}
+		selectors := []string{}
+		for _, v := range strings.Split(params.DKIM, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				selectors = append(selectors, v)
+			}
+		}
+		if len(selectors) > maxDKIMSelectors {
+			msg := fmt.Sprintf("at most %d DKIM selectors can be checked at once", maxDKIMSelectors)
+			if ctx.ContentType() == "application/json" {
+				ctx.JSON(400, map[string]string{
+					"message": msg,
+				})
+			} else {
+				ctx.String(400, "%s", msg)
+			}
+			return
+		}
+
+		// Run the checks against the domain.
+		report, err := dnsLib.CheckMailAuth(log, dnsServer, domain, selectors)
+		if err != nil {
+			ctx.Error(&gin.Error{
+				Type: gin.ErrorTypePublic,
+				Err:  fmt.Errorf("failed to check mail authentication: %v", err),
+			})
+			return
+		}
+
+		// Handle JSON responses.
+		if ctx.ContentType() == "application/json" {
+			ctx.JSON(200, report)
+			return
+		}
+
+		ctx.String(200, report.String())
+	})
+}
@@ -12,6 +12,12 @@ import (
 type rdnsParams struct {
 	// Trace is used to define if the DNS record should be traced all the way to the nameserver.
 	Trace bool `form:"trace"`
+
+	// NoCache is used to define if the cache should be bypassed.
+	NoCache bool `form:"nocache"`
+
+	// FCrDNS is used to define if every PTR hostname should be looked up forward to check it matches the IP.
+	FCrDNS bool `form:"fcrdns"`
 }
 
 func rdns(g group, log *zap.Logger, dnsServer string) {
@@ -42,6 +48,25 @@ func rdns(g group, log *zap.Logger, dnsServer string) {
 			return
 		}
 
+		if params.FCrDNS {
+			result, err := dnsLib.CheckFCrDNS(
+				log, ipAddr, dnsServer, dnsLib.LookupOptions{NoCache: params.NoCache},
+			)
+			if err != nil {
+				ctx.Error(&gin.Error{
+					Type: gin.ErrorTypePublic,
+					Err:  fmt.Errorf("failed to perform dns lookup: %v", err),
+				})
+				return
+			}
+			if ctx.ContentType() == "application/json" {
+				ctx.JSON(200, result)
+			} else {
+				ctx.String(200, result.String())
+			}
+			return
+		}
+
 		if !params.Trace {
 			hosts, err := net.LookupAddr(ip)
 			if err != nil || len(hosts) == 0 {
@@ -66,7 +91,7 @@ func rdns(g group, log *zap.Logger, dnsServer string) {
 		}
 
 		result, err := dnsLib.LookupRDNS(
-			log, ipAddr, dnsServer,
+			log, ipAddr, dnsServer, dnsLib.LookupOptions{NoCache: params.NoCache},
 		)
 		if err != nil {
 			ctx.Error(&gin.Error{
@@ -76,9 +101,7 @@ func rdns(g group, log *zap.Logger, dnsServer string) {
 			return
 		}
 		if ctx.ContentType() == "application/json" {
-			ctx.JSON(200, map[string]interface{}{
-				"trace": result,
-			})
+			ctx.JSON(200, result)
 		} else {
 			ctx.String(200, result.String())
 		}
@@ -0,0 +1,93 @@
+package api_v1
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	dnsLib "github.com/krystal/krystal-network-tools/backend/dns"
+	"go.uber.org/zap"
+)
+
+type rdnsBulkParams struct {
+	// IPs is used to define a comma separated list of addresses to look up when a prefix is not given.
+	IPs string `form:"ips"`
+
+	// NoCache is used to define if the cache should be bypassed.
+	NoCache bool `form:"nocache"`
+}
+
+func rdnsBulk(g group, log *zap.Logger, dnsServer string) {
+	hn := func(ctx *gin.Context) {
+		var params rdnsBulkParams
+		if err := ctx.BindQuery(&params); err != nil {
+			if ctx.ContentType() == "application/json" {
+				ctx.JSON(400, map[string]string{
+					"message": err.Error(),
+				})
+			} else {
+				ctx.String(400, "unable to parse query params: %s", err.Error())
+			}
+			return
+		}
+
+		// Get the addresses from either the prefix in the URL or the list in the query.
+		var (
+			prefix string
+			ips    []net.IP
+		)
+		if ctx.Param("range") != "" {
+			_, ipNet, err := net.ParseCIDR(ctx.Param("ip") + "/" + ctx.Param("range"))
+			if err == nil {
+				prefix = ipNet.String()
+				ips, err = dnsLib.ExpandIPv4Prefix(ipNet)
+			}
+			if err != nil {
+				ctx.Error(&gin.Error{
+					Type: gin.ErrorTypePublic,
+					Err:  fmt.Errorf("invalid prefix: %v", err),
+				})
+				return
+			}
+		} else {
+			for _, v := range strings.Split(params.IPs, ",") {
+				if v = strings.TrimSpace(v); v == "" {
+					continue
+				}
+				ip := net.ParseIP(v)
+				if ip == nil {
+					ctx.Error(&gin.Error{
+						Type: gin.ErrorTypePublic,
+						Err:  errors.New("invalid ip: " + v),
+					})
+					return
+				}
+				ips = append(ips, ip)
+			}
+		}
+
+		// Do the lookups.
+		result, err := dnsLib.LookupRDNSBulk(
+			log, dnsServer, prefix, ips, dnsLib.LookupOptions{NoCache: params.NoCache},
+		)
+		if err != nil {
+			ctx.Error(&gin.Error{
+				Type: gin.ErrorTypePublic,
+				Err:  fmt.Errorf("failed to perform dns lookups: %v", err),
+			})
+			return
+		}
+
+		// Handle JSON responses.
+		if ctx.ContentType() == "application/json" {
+			ctx.JSON(200, result)
+			return
+		}
+
+		ctx.String(200, result.String())
+	}
+	g.GET("/", hn)
+	g.GET("/:ip/:range", hn)
+}
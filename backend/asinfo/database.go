@@ -0,0 +1,121 @@
+package asinfo
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Info is used to define what is known about an AS.
+type Info struct {
+	// ASN is used to define the AS number.
+	ASN uint32 `json:"asn"`
+
+	// Name is used to define the name of the AS, such as CLOUDFLARENET. This is blank if it is not known.
+	Name string `json:"name"`
+
+	// Organisation is used to define the organisation which holds the AS, if it is known.
+	Organisation string `json:"organisation"`
+
+	// Country is used to define the ISO 3166 country code the AS is registered in, if it is known.
+	Country string `json:"country"`
+}
+
+// String returns the AS number followed by the name and country, if they are known.
+func (i Info) String() string {
+	s := "AS" + strconv.FormatUint(uint64(i.ASN), 10)
+	if i.Name != "" {
+		s += " " + i.Name
+	}
+	if i.Country != "" {
+		s += " (" + i.Country + ")"
+	}
+	return s
+}
+
+// Database is used to hold a dataset of AS names. The dataset can be replaced at any time.
+type Database struct {
+	lock    sync.RWMutex
+	infos   map[uint32]Info
+	updated time.Time
+}
+
+// NewDatabase creates an empty database.
+func NewDatabase() *Database {
+	return &Database{infos: map[uint32]Info{}}
+}
+
+// Replace replaces everything in the database.
+func (d *Database) Replace(infos []Info) {
+	m := make(map[uint32]Info, len(infos))
+	for _, v := range infos {
+		m[v.ASN] = v
+	}
+
+	d.lock.Lock()
+	d.infos = m
+	d.updated = time.Now()
+	d.lock.Unlock()
+}
+
+// Get gets the information for an AS. False is returned if it is not in the database.
+func (d *Database) Get(asn uint32) (Info, bool) {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+	v, ok := d.infos[asn]
+	return v, ok
+}
+
+// Stats returns the number of ASNs in the database and when it was last replaced.
+func (d *Database) Stats() (int, time.Time) {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+	return len(d.infos), d.updated
+}
+
+// Parses the part of a line after the ASN. This is the name, optionally followed by the organisation, and then a
+// comma and the country code, such as "TWELVE99 Arelion, fka Telia Carrier, SE".
+func parseDescription(asn uint32, s string) Info {
+	info := Info{ASN: asn}
+	if i := strings.LastIndex(s, ","); i != -1 {
+		country := strings.TrimSpace(s[i+1:])
+		if len(country) == 2 {
+			info.Country = strings.ToUpper(country)
+			s = s[:i]
+		}
+	}
+	s = strings.TrimSpace(s)
+	name, org, _ := strings.Cut(s, " ")
+	info.Name = name
+	info.Organisation = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(org), "- "))
+	return info
+}
+
+// ParseASNames parses a dataset in the format of the RIPE NCC asn.txt file, which has a line for each AS in the
+// format "13335 CLOUDFLARENET, US".
+func ParseASNames(r io.Reader) ([]Info, error) {
+	infos := []Info{}
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		asnStr, rest, _ := strings.Cut(text, " ")
+		asn, err := strconv.ParseUint(strings.TrimPrefix(strings.ToUpper(asnStr), "AS"), 10, 32)
+		if err != nil {
+			return nil, errors.New("line " + strconv.Itoa(line) + ": invalid asn: " + strconv.Quote(asnStr))
+		}
+		infos = append(infos, parseDescription(uint32(asn), rest))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return infos, nil
+}
@@ -0,0 +1,49 @@
+package asinfo
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseASNames(t *testing.T) {
+	tests := []struct {
+		name string
+
+		text    string
+		infos   []Info
+		wantErr string
+	}{
+		{
+			name: "ripe format",
+			text: "13335 CLOUDFLARENET, US\n" +
+				"1299 TWELVE99 Arelion, fka Telia Carrier, SE\n" +
+				"3223 VOXILITY - Voxility LLP, GB\n" +
+				"\n" +
+				"64512 PRIVATE\n",
+			infos: []Info{
+				{ASN: 13335, Name: "CLOUDFLARENET", Country: "US"},
+				{ASN: 1299, Name: "TWELVE99", Organisation: "Arelion, fka Telia Carrier", Country: "SE"},
+				{ASN: 3223, Name: "VOXILITY", Organisation: "Voxility LLP", Country: "GB"},
+				{ASN: 64512, Name: "PRIVATE"},
+			},
+		},
+		{
+			name:    "invalid asn",
+			text:    "13335 CLOUDFLARENET, US\nASX BAD, US\n",
+			wantErr: `line 2: invalid asn: "ASX"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			infos, err := ParseASNames(strings.NewReader(tt.text))
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.infos, infos)
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package asinfo
+
+import (
+	"os"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// Defines how often the dataset file is checked for changes.
+	fileWatchInterval = 5 * time.Minute
+
+	// Defines how often the dataset is downloaded, and how long to wait after a failed download.
+	fetchInterval = 24 * time.Hour
+	fetchRetry    = 10 * time.Minute
+)
+
+// InitDatabase creates a database from the environment. AS_NAMES_FILE can be set to the path of a dataset in the
+// format of the RIPE NCC asn.txt file, or AS_NAMES_URL can be set to a URL to download it from each day. An empty
+// database is returned if neither is set.
+func InitDatabase(log *zap.Logger) (*Database, error) {
+	d := NewDatabase()
+	if path := os.Getenv("AS_NAMES_FILE"); path != "" {
+		if err := WatchFile(log, d, path, fileWatchInterval); err != nil {
+			return nil, err
+		}
+		return d, nil
+	}
+	if url := os.Getenv("AS_NAMES_URL"); url != "" {
+		FetchURL(log, d, url, fetchInterval, fetchRetry)
+	}
+	return d, nil
+}
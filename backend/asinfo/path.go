@@ -0,0 +1,47 @@
+package asinfo
+
+import "strconv"
+
+// Hop is used to define an AS in an AS path. Prepends of the AS are collapsed into one hop.
+type Hop struct {
+	Info
+
+	// Count is used to define the number of times the AS is in the path in a row.
+	Count int `json:"count"`
+}
+
+// String returns the AS, prefixed by the number of times it is in the path if it was prepended, such as
+// "3x AS13335 CLOUDFLARENET (US)".
+func (h Hop) String() string {
+	if h.Count > 1 {
+		return strconv.Itoa(h.Count) + "x " + h.Info.String()
+	}
+	return h.Info.String()
+}
+
+// CollapsePath turns an AS path into hops, collapsing any prepends.
+func CollapsePath(path []int) []Hop {
+	hops := []Hop{}
+	for _, asn := range path {
+		if len(hops) != 0 && hops[len(hops)-1].ASN == uint32(asn) {
+			hops[len(hops)-1].Count++
+			continue
+		}
+		hops = append(hops, Hop{Info: Info{ASN: uint32(asn)}, Count: 1})
+	}
+	return hops
+}
+
+// Path turns an AS path into hops with the information about each AS, collapsing any prepends.
+func (r *Resolver) Path(path []int) []Hop {
+	hops := CollapsePath(path)
+	asns := make([]uint32, len(hops))
+	for i, hop := range hops {
+		asns[i] = hop.ASN
+	}
+	infos := r.LookupAll(asns)
+	for i := range hops {
+		hops[i].Info = infos[hops[i].ASN]
+	}
+	return hops
+}
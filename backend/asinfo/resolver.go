@@ -0,0 +1,189 @@
+package asinfo
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	// Defines how long whois results are cached for, and how long to wait before trying an AS whois had no
+	// answer for again.
+	whoisCacheTTL     = 24 * time.Hour
+	whoisMissCacheTTL = time.Hour
+
+	// Defines the maximum number of whois lookups to run at once.
+	whoisConcurrency = 4
+
+	// Defines how long a lookup waits for whois. Lookups which take longer carry on in the background and fill
+	// the cache, so the AS is named the next time it is looked up.
+	whoisTimeout = 2 * time.Second
+)
+
+// WhoisLookuper is used to define something which can do a whois lookup.
+type WhoisLookuper interface {
+	Whois(hostOrIp string) (string, error)
+}
+
+// Defines a cached whois result.
+type cachedInfo struct {
+	info    Info
+	ok      bool
+	expires time.Time
+}
+
+// Resolver resolves information about ASNs from a database, falling back to whois for ASNs which are not in it.
+type Resolver struct {
+	db      *Database
+	whois   WhoisLookuper
+	timeout time.Duration
+
+	lock  sync.Mutex
+	cache map[uint32]cachedInfo
+	group singleflight.Group
+	sem   chan struct{}
+}
+
+// NewResolver creates a resolver. Either the database or whois can be nil to not use them.
+func NewResolver(db *Database, whois WhoisLookuper) *Resolver {
+	return &Resolver{
+		db:      db,
+		whois:   whois,
+		timeout: whoisTimeout,
+		cache:   map[uint32]cachedInfo{},
+		sem:     make(chan struct{}, whoisConcurrency),
+	}
+}
+
+// Returns if the ASN is reserved, private or for documentation, so it will never have public information.
+func isReserved(asn uint32) bool {
+	return asn == 0 || asn == 23456 || (asn >= 64496 && asn <= 131071) || asn >= 4200000000
+}
+
+// Defines the whois attributes which hold each piece of information. The first one found is used.
+var (
+	whoisNameKeys    = []string{"as-name", "asname"}
+	whoisOrgKeys     = []string{"org-name", "orgname", "owner", "descr"}
+	whoisCountryKeys = []string{"country"}
+)
+
+// Parses the information about an AS from a whois response. False is returned if it has no AS name.
+func parseWhois(asn uint32, text string) (Info, bool) {
+	attrs := map[string]string{}
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == '%' || line[0] == '#' {
+			continue
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+		if _, exists := attrs[key]; !exists && value != "" {
+			attrs[key] = value
+		}
+	}
+	first := func(keys []string) string {
+		for _, k := range keys {
+			if v := attrs[k]; v != "" {
+				return v
+			}
+		}
+		return ""
+	}
+
+	info := Info{
+		ASN:          asn,
+		Name:         first(whoisNameKeys),
+		Organisation: first(whoisOrgKeys),
+		Country:      strings.ToUpper(first(whoisCountryKeys)),
+	}
+	return info, info.Name != ""
+}
+
+// Looks up an ASN with whois, using the cache where possible. If whois doesn't answer within the timeout, the AS
+// is treated as unknown and the lookup finishes in the background.
+func (r *Resolver) lookupWhois(asn uint32) (Info, bool) {
+	r.lock.Lock()
+	cached, ok := r.cache[asn]
+	r.lock.Unlock()
+	if ok && time.Now().Before(cached.expires) {
+		return cached.info, cached.ok
+	}
+
+	key := strconv.FormatUint(uint64(asn), 10)
+	ch := r.group.DoChan(key, func() (interface{}, error) {
+		r.sem <- struct{}{}
+		text, err := r.whois.Whois("AS" + key)
+		<-r.sem
+
+		result := cachedInfo{info: Info{ASN: asn}, expires: time.Now().Add(whoisMissCacheTTL)}
+		if err == nil {
+			if info, ok := parseWhois(asn, text); ok {
+				result = cachedInfo{info: info, ok: true, expires: time.Now().Add(whoisCacheTTL)}
+			}
+		}
+		r.lock.Lock()
+		r.cache[asn] = result
+		r.lock.Unlock()
+		return result, nil
+	})
+	timer := time.NewTimer(r.timeout)
+	defer timer.Stop()
+	select {
+	case v := <-ch:
+		result := v.Val.(cachedInfo)
+		return result.info, result.ok
+	case <-timer.C:
+		return Info{ASN: asn}, false
+	}
+}
+
+// Lookup gets the information for an AS. If it isn't known, false is returned along with an Info which only has
+// the ASN set.
+func (r *Resolver) Lookup(asn uint32) (Info, bool) {
+	if r.db != nil {
+		if info, ok := r.db.Get(asn); ok {
+			return info, true
+		}
+	}
+	if r.whois == nil || isReserved(asn) {
+		return Info{ASN: asn}, false
+	}
+	return r.lookupWhois(asn)
+}
+
+// LookupAll gets the information for several ASes at once.
+func (r *Resolver) LookupAll(asns []uint32) map[uint32]Info {
+	unique := map[uint32]bool{}
+	for _, asn := range asns {
+		unique[asn] = true
+	}
+
+	infos := make(map[uint32]Info, len(unique))
+	lock := sync.Mutex{}
+	wg := sync.WaitGroup{}
+	for asn := range unique {
+		wg.Add(1)
+		go func(asn uint32) {
+			defer wg.Done()
+			info, _ := r.Lookup(asn)
+			lock.Lock()
+			infos[asn] = info
+			lock.Unlock()
+		}(asn)
+	}
+	wg.Wait()
+	return infos
+}
+
+// Describe returns the AS number followed by the name and country, if they are known.
+func (r *Resolver) Describe(asn uint32) string {
+	info, _ := r.Lookup(asn)
+	return info.String()
+}
@@ -0,0 +1,157 @@
+package asinfo
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const arinWhois = `# ARIN WHOIS data and services are subject to the Terms of Use
+
+ASNumber:       13335
+ASName:         CLOUDFLARENET
+ASHandle:       AS13335
+
+OrgName:        Cloudflare, Inc.
+OrgId:          CLOUD14
+Country:        US
+`
+
+const ripeWhois = `% This is the RIPE Database query service.
+
+aut-num:        AS3223
+as-name:        VOXILITY
+org:            ORG-VA1-RIPE
+
+organisation:   ORG-VA1-RIPE
+org-name:       Voxility LLP
+country:        gb
+`
+
+// Defines a fake whois lookuper which counts the lookups for each query. If release is set, lookups wait until
+// it is closed.
+type fakeWhois struct {
+	lock      sync.Mutex
+	responses map[string]string
+	lookups   map[string]int
+	release   chan struct{}
+}
+
+func (f *fakeWhois) Whois(hostOrIp string) (string, error) {
+	if f.release != nil {
+		<-f.release
+	}
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.lookups[hostOrIp]++
+	if v, ok := f.responses[hostOrIp]; ok {
+		return v, nil
+	}
+	return "", errors.New("no match")
+}
+
+func TestResolver_Lookup(t *testing.T) {
+	db := NewDatabase()
+	db.Replace([]Info{{ASN: 2914, Name: "NTT-LTD-2914", Country: "US"}})
+	whois := &fakeWhois{
+		responses: map[string]string{"AS13335": arinWhois, "AS3223": ripeWhois},
+		lookups:   map[string]int{},
+	}
+	r := NewResolver(db, whois)
+
+	tests := []struct {
+		name string
+
+		asn  uint32
+		info Info
+		ok   bool
+	}{
+		{
+			name: "database",
+			asn:  2914,
+			info: Info{ASN: 2914, Name: "NTT-LTD-2914", Country: "US"},
+			ok:   true,
+		},
+		{
+			name: "arin whois",
+			asn:  13335,
+			info: Info{ASN: 13335, Name: "CLOUDFLARENET", Organisation: "Cloudflare, Inc.", Country: "US"},
+			ok:   true,
+		},
+		{
+			name: "ripe whois",
+			asn:  3223,
+			info: Info{ASN: 3223, Name: "VOXILITY", Organisation: "Voxility LLP", Country: "GB"},
+			ok:   true,
+		},
+		{
+			name: "whois failure",
+			asn:  174,
+			info: Info{ASN: 174},
+		},
+		{
+			name: "private",
+			asn:  64512,
+			info: Info{ASN: 64512},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, ok := r.Lookup(tt.asn)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.info, info)
+		})
+	}
+
+	// Make sure the whois results were cached and private ASNs were never looked up.
+	r.Lookup(13335)
+	r.Lookup(174)
+	assert.Equal(t, map[string]int{"AS13335": 1, "AS3223": 1, "AS174": 1}, whois.lookups)
+}
+
+func TestResolver_Lookup_timeout(t *testing.T) {
+	whois := &fakeWhois{
+		responses: map[string]string{"AS13335": arinWhois},
+		lookups:   map[string]int{},
+		release:   make(chan struct{}),
+	}
+	r := NewResolver(nil, whois)
+	r.timeout = 10 * time.Millisecond
+
+	// The lookup should give up waiting for whois.
+	info, ok := r.Lookup(13335)
+	assert.False(t, ok)
+	assert.Equal(t, Info{ASN: 13335}, info)
+
+	// Once whois answers, the result should be cached for the next lookup.
+	close(whois.release)
+	assert.Eventually(t, func() bool {
+		_, ok := r.Lookup(13335)
+		return ok
+	}, time.Second, 5*time.Millisecond)
+	info, _ = r.Lookup(13335)
+	assert.Equal(t, "CLOUDFLARENET", info.Name)
+	assert.Equal(t, map[string]int{"AS13335": 1}, whois.lookups)
+}
+
+func TestResolver_Path(t *testing.T) {
+	db := NewDatabase()
+	db.Replace([]Info{
+		{ASN: 3223, Name: "VOXILITY", Country: "GB"},
+		{ASN: 13335, Name: "CLOUDFLARENET", Country: "US"},
+	})
+	r := NewResolver(db, nil)
+
+	hops := r.Path([]int{3223, 64512, 13335, 13335, 13335})
+	assert.Equal(t, []Hop{
+		{Info: Info{ASN: 3223, Name: "VOXILITY", Country: "GB"}, Count: 1},
+		{Info: Info{ASN: 64512}, Count: 1},
+		{Info: Info{ASN: 13335, Name: "CLOUDFLARENET", Country: "US"}, Count: 3},
+	}, hops)
+	assert.Equal(t, "AS3223 VOXILITY (GB)", hops[0].String())
+	assert.Equal(t, "AS64512", hops[1].String())
+	assert.Equal(t, "3x AS13335 CLOUDFLARENET (US)", hops[2].String())
+}
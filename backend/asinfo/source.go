@@ -0,0 +1,68 @@
+package asinfo
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/krystal/krystal-network-tools/backend/filewatch"
+	"go.uber.org/zap"
+)
+
+// Loads the dataset from a file.
+func loadFile(path string) ([]Info, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseASNames(f)
+}
+
+// WatchFile loads the dataset from a file into the database, and then reloads it whenever the file is changed.
+// The first load happens before this returns.
+func WatchFile(log *zap.Logger, d *Database, path string, interval time.Duration) error {
+	return filewatch.Watch(log, path, interval, func(path string) error {
+		infos, err := loadFile(path)
+		if err != nil {
+			return err
+		}
+		d.Replace(infos)
+		log.Info("loaded as names", zap.String("path", path), zap.Int("asns", len(infos)))
+		return nil
+	})
+}
+
+// Downloads the dataset from a URL.
+func fetchURL(client *http.Client, url string) ([]Info, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New("unexpected status code " + strconv.Itoa(resp.StatusCode) + " from " + url)
+	}
+	return ParseASNames(resp.Body)
+}
+
+// FetchURL downloads the dataset from a URL into the database in the background, and then downloads it again
+// every interval. If a download fails, it is retried after the retry interval.
+func FetchURL(log *zap.Logger, d *Database, url string, interval, retry time.Duration) {
+	client := &http.Client{Timeout: time.Minute}
+	go func() {
+		for {
+			infos, err := fetchURL(client, url)
+			if err != nil {
+				log.Warn("failed to download as names", zap.String("url", url), zap.Error(err))
+				time.Sleep(retry)
+				continue
+			}
+			d.Replace(infos)
+			log.Info("downloaded as names", zap.String("url", url), zap.Int("asns", len(infos)))
+			time.Sleep(interval)
+		}
+	}()
+}
@@ -0,0 +1,177 @@
+package bird
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// DefaultTimeout is used to define how long a query to BIRD can take by default.
+const DefaultTimeout = 10 * time.Second
+
+// Defines the maximum size of a reply. This stops a runaway reply from using all of the memory.
+const maxReplySize = 16 * 1024 * 1024
+
+// Line is a single line of a reply from BIRD.
+type Line struct {
+	// Code is used to define the 4 digit reply code of the line. Continuation lines have the code of the
+	// line before them.
+	Code string
+
+	// Text is used to define the text of the line without the code.
+	Text string
+
+	// IsCont is true if the line was a continuation of the line before it.
+	IsCont bool
+}
+
+// Reply is a full reply from BIRD to a command.
+type Reply struct {
+	// Code is used to define the code of the terminal line of the reply.
+	Code string
+
+	// Lines is used to define every line of the reply, including the terminal line.
+	Lines []Line
+
+	// Raw is used to define the reply as it was sent by BIRD.
+	Raw string
+}
+
+// Error is returned when BIRD replies with a runtime (8xxx) or syntax (9xxx) error.
+type Error struct {
+	// Code is used to define the reply code of the error.
+	Code string
+
+	// Message is used to define the message BIRD sent with the error.
+	Message string
+}
+
+// Error implements the error interface.
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Returns if a code ends a reply. BIRD ends every reply with a 0xxx code on success, 8xxx for runtime errors or
+// 9xxx for syntax errors.
+func isTerminalCode(code string) bool {
+	switch code[0] {
+	case '0', '8', '9':
+		return true
+	}
+	return false
+}
+
+// Client is used to talk to BIRD over its control socket.
+type Client struct {
+	conn    io.ReadWriteCloser
+	r       *bufio.Reader
+	timeout time.Duration
+}
+
+// NewClient creates a client on top of a connection to the control socket and reads the banner. If the
+// connection supports deadlines, each query is limited to the timeout.
+func NewClient(conn io.ReadWriteCloser, timeout time.Duration) (*Client, error) {
+	c := &Client{conn: conn, r: bufio.NewReader(conn), timeout: timeout}
+	c.setDeadline()
+	if _, err := c.readReply(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Dial connects to the control socket at the path specified.
+func Dial(path string, timeout time.Duration) (*Client, error) {
+	conn, err := net.DialTimeout("unix", path, timeout)
+	if err != nil {
+		return nil, err
+	}
+	c, err := NewClient(conn, timeout)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// Sets the deadline on the connection if it supports it.
+func (c *Client) setDeadline() {
+	if d, ok := c.conn.(interface{ SetDeadline(time.Time) error }); ok && c.timeout != 0 {
+		_ = d.SetDeadline(time.Now().Add(c.timeout))
+	}
+}
+
+// Reads lines until the terminal line of a reply.
+func (c *Client) readReply() (*Reply, error) {
+	reply := &Reply{}
+	raw := strings.Builder{}
+	for {
+		line, err := c.r.ReadString('\n')
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		raw.WriteString(line)
+		if raw.Len() > maxReplySize {
+			return nil, errors.New("bird reply is too large")
+		}
+		line = strings.TrimRight(line, "\r\n")
+
+		// Handle continuation lines.
+		if strings.HasPrefix(line, " ") {
+			if len(reply.Lines) == 0 {
+				return nil, errors.New("bird reply started with a continuation line")
+			}
+			reply.Lines = append(reply.Lines, Line{
+				Code:   reply.Lines[len(reply.Lines)-1].Code,
+				Text:   line[1:],
+				IsCont: true,
+			})
+			continue
+		}
+
+		// Handle coded lines.
+		if len(line) < 5 || (line[4] != ' ' && line[4] != '-') {
+			if len(line) == 4 {
+				// Some versions of BIRD don't put a space after an empty terminal line.
+				line += " "
+			} else {
+				return nil, errors.New("invalid bird reply line: " + line)
+			}
+		}
+		code := line[:4]
+		for _, r := range code {
+			if r < '0' || r > '9' {
+				return nil, errors.New("invalid bird reply line: " + line)
+			}
+		}
+		reply.Lines = append(reply.Lines, Line{Code: code, Text: line[5:]})
+		if line[4] == ' ' && isTerminalCode(code) {
+			reply.Code = code
+			reply.Raw = raw.String()
+			if code[0] == '8' || code[0] == '9' {
+				return reply, &Error{Code: code, Message: line[5:]}
+			}
+			return reply, nil
+		}
+	}
+}
+
+// Query sends a command to BIRD and reads the whole reply. If BIRD replies with an error, the reply is returned
+// alongside an *Error.
+func (c *Client) Query(command string) (*Reply, error) {
+	c.setDeadline()
+	if _, err := c.conn.Write([]byte(command + "\n")); err != nil {
+		return nil, err
+	}
+	return c.readReply()
+}
+
+// Close closes the connection to BIRD.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
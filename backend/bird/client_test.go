@@ -0,0 +1,132 @@
+package bird
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeConn is a connection which reads from a reader and records the writes.
+type fakeConn struct {
+	io.Reader
+	writes bytes.Buffer
+}
+
+func (c *fakeConn) Write(p []byte) (int, error) {
+	return c.writes.Write(p)
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func TestClient_Query(t *testing.T) {
+	tests := []struct {
+		name string
+
+		data    string
+		lines   []Line
+		code    string
+		wantErr string
+	}{
+		{
+			name: "route reply",
+			data: "0001 BIRD 2.0.7 ready.\n" +
+				"1007-Table master4:\n" +
+				" 1.1.1.0/24           unicast [gaia_a 2022-01-27] * (100) [AS13335i]\n" +
+				"1012-\tBGP.origin: IGP\n" +
+				" \tBGP.as_path: 13335\n" +
+				"0000 \n",
+			lines: []Line{
+				{Code: "1007", Text: "Table master4:"},
+				{Code: "1007", Text: "1.1.1.0/24           unicast [gaia_a 2022-01-27] * (100) [AS13335i]", IsCont: true},
+				{Code: "1012", Text: "\tBGP.origin: IGP"},
+				{Code: "1012", Text: "\tBGP.as_path: 13335", IsCont: true},
+				{Code: "0000", Text: ""},
+			},
+			code: "0000",
+		},
+		{
+			name: "non-terminal space separated line",
+			data: "0001 BIRD 2.0.7 ready.\n" +
+				"1000-BIRD 2.0.7\n" +
+				"1011 Router ID is 192.0.2.1\n" +
+				"0013 Daemon is up and running\n",
+			lines: []Line{
+				{Code: "1000", Text: "BIRD 2.0.7"},
+				{Code: "1011", Text: "Router ID is 192.0.2.1"},
+				{Code: "0013", Text: "Daemon is up and running"},
+			},
+			code: "0013",
+		},
+		{
+			name:    "syntax error",
+			data:    "0001 BIRD 2.0.7 ready.\n9001 Invalid prefix length 10000\n",
+			lines:   []Line{{Code: "9001", Text: "Invalid prefix length 10000"}},
+			code:    "9001",
+			wantErr: "Invalid prefix length 10000",
+		},
+		{
+			name:    "runtime error",
+			data:    "0001 BIRD 2.0.7 ready.\n8001 Route not found\n",
+			lines:   []Line{{Code: "8001", Text: "Route not found"}},
+			code:    "8001",
+			wantErr: "Route not found",
+		},
+		{
+			name:    "truncated reply",
+			data:    "0001 BIRD 2.0.7 ready.\n1007-Table master4:\n",
+			wantErr: "unexpected EOF",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Read a byte at a time to make sure replies split across reads are handled.
+			conn := &fakeConn{Reader: iotest.OneByteReader(bytes.NewReader([]byte(tt.data)))}
+			c, err := NewClient(conn, time.Second)
+			if !assert.NoError(t, err) {
+				return
+			}
+			reply, err := c.Query("show route for 1.1.1.1 all")
+			assert.Equal(t, "show route for 1.1.1.1 all\n", conn.writes.String())
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			if tt.lines == nil {
+				assert.Nil(t, reply)
+				return
+			}
+			assert.Equal(t, tt.lines, reply.Lines)
+			assert.Equal(t, tt.code, reply.Code)
+			assert.Equal(t, tt.data[23:], reply.Raw)
+		})
+	}
+}
+
+func TestClient_timeout(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+	go func() {
+		_, _ = server.Write([]byte("0001 BIRD 2.0.7 ready.\n"))
+	}()
+
+	c, err := NewClient(client, 50*time.Millisecond)
+	if !assert.NoError(t, err) {
+		return
+	}
+	go func() {
+		// Read the query but never reply.
+		_, _ = io.Copy(io.Discard, server)
+	}()
+	_, err = c.Query("show route")
+	var netErr net.Error
+	assert.ErrorAs(t, err, &netErr)
+	assert.True(t, netErr.Timeout())
+}
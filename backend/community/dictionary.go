@@ -0,0 +1,222 @@
+package community
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Kind is used to define the kind of a BGP community.
+type Kind string
+
+const (
+	// Standard is a RFC 1997 community, formatted as "asn:value".
+	Standard Kind = "standard"
+
+	// Large is a RFC 8092 large community, formatted as "asn:function:parameter".
+	Large Kind = "large"
+
+	// Extended is a RFC 4360 extended community, formatted as "type:administrator:value" the way BIRD prints it,
+	// such as "rt:64500:100".
+	Extended Kind = "extended"
+)
+
+// Decoded is a community with a known meaning.
+type Decoded struct {
+	// Community is used to define the community, with the fields separated by colons.
+	Community string `json:"community"`
+
+	// Kind is used to define the kind of community.
+	Kind Kind `json:"kind"`
+
+	// Meaning is used to define what the community means.
+	Meaning string `json:"meaning"`
+
+	// Source is used to define which dictionary the meaning came from.
+	Source string `json:"source"`
+}
+
+// Defines a field of a pattern. A field matches an exact value, an inclusive numeric range, or anything.
+type patternField struct {
+	value    string
+	min, max uint64
+	isRange  bool
+	wildcard bool
+}
+
+// Returns if the field matches a value from a community.
+func (f patternField) match(s string) bool {
+	switch {
+	case f.wildcard:
+		return true
+	case f.isRange:
+		n, err := strconv.ParseUint(s, 10, 64)
+		return err == nil && n >= f.min && n <= f.max
+	default:
+		return strings.EqualFold(f.value, s)
+	}
+}
+
+// Returns how specific the field is. Exact values beat ranges and ranges beat wildcards.
+func (f patternField) specificity() int {
+	switch {
+	case f.wildcard:
+		return 0
+	case f.isRange:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// Defines a dictionary entry.
+type entry struct {
+	fields      []patternField
+	meaning     string
+	source      string
+	specificity int
+}
+
+// Parses a pattern such as "64500:100", "64500:1-99", "64500:*" or "rt:*:*".
+func parseEntry(pattern, meaning, source string, fieldCount int) (entry, error) {
+	split := strings.Split(pattern, ":")
+	if len(split) != fieldCount {
+		return entry{}, errors.New("pattern " + strconv.Quote(pattern) + " should have " +
+			strconv.Itoa(fieldCount) + " fields")
+	}
+	e := entry{fields: make([]patternField, len(split)), meaning: meaning, source: source}
+	for i, s := range split {
+		s = strings.TrimSpace(s)
+		var f patternField
+		if s == "*" {
+			f.wildcard = true
+		} else if lo, hi, ok := strings.Cut(s, "-"); ok {
+			var err1, err2 error
+			f.min, err1 = strconv.ParseUint(lo, 10, 64)
+			f.max, err2 = strconv.ParseUint(hi, 10, 64)
+			if err1 != nil || err2 != nil || f.min > f.max {
+				return entry{}, errors.New("pattern " + strconv.Quote(pattern) + " has an invalid range")
+			}
+			f.isRange = true
+		} else if s == "" {
+			return entry{}, errors.New("pattern " + strconv.Quote(pattern) + " has an empty field")
+		} else {
+			f.value = s
+		}
+		e.fields[i] = f
+		e.specificity += f.specificity()
+	}
+	return e, nil
+}
+
+// Returns the meaning if the entry matches the fields of a community. $1, $2 and $3 in the meaning are replaced
+// with the fields.
+func (e entry) match(fields []string) (string, bool) {
+	if len(fields) != len(e.fields) {
+		return "", false
+	}
+	for i, f := range e.fields {
+		if !f.match(fields[i]) {
+			return "", false
+		}
+	}
+	meaning := e.meaning
+	for i := len(fields); i > 0; i-- {
+		meaning = strings.ReplaceAll(meaning, "$"+strconv.Itoa(i), fields[i-1])
+	}
+	return meaning, true
+}
+
+// Dictionary is used to look up the meanings of communities.
+type Dictionary struct {
+	entries map[Kind][]entry
+}
+
+// NewDictionary creates a dictionary which only knows the well-known communities.
+func NewDictionary() *Dictionary {
+	d := &Dictionary{entries: map[Kind][]entry{}}
+	for _, s := range wellKnown {
+		if err := d.Add(s); err != nil {
+			panic(err)
+		}
+	}
+	return d
+}
+
+// Defines the number of fields for each kind of community.
+var fieldCounts = map[Kind]int{Standard: 2, Large: 3, Extended: 3}
+
+// Add adds a set of patterns to the dictionary. When entries are equally specific, the last one added wins.
+func (d *Dictionary) Add(s Set) error {
+	for kind, patterns := range map[Kind]map[string]string{
+		Standard: s.Communities,
+		Large:    s.LargeCommunities,
+		Extended: s.ExtCommunities,
+	} {
+		// Sort the patterns so errors and ties are deterministic.
+		keys := make([]string, 0, len(patterns))
+		for k := range patterns {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			e, err := parseEntry(k, patterns[k], s.Name, fieldCounts[kind])
+			if err != nil {
+				return errors.New(s.Name + ": " + err.Error())
+			}
+			d.entries[kind] = append(d.entries[kind], e)
+		}
+	}
+	return nil
+}
+
+// Lookup looks up the meaning of a community. False is returned if it has no known meaning.
+func (d *Dictionary) Lookup(kind Kind, community string) (Decoded, bool) {
+	fields := strings.Split(community, ":")
+	var (
+		best      *entry
+		meaning   string
+		bestScore = -1
+	)
+	for i := range d.entries[kind] {
+		e := &d.entries[kind][i]
+		if e.specificity < bestScore {
+			continue
+		}
+		if m, ok := e.match(fields); ok {
+			best, meaning, bestScore = e, m, e.specificity
+		}
+	}
+	if best == nil {
+		return Decoded{}, false
+	}
+	return Decoded{Community: community, Kind: kind, Meaning: meaning, Source: best.source}, true
+}
+
+// Normalize turns a community in the format BIRD prints it, such as "64500,100", "64500, 1, 2" or
+// "rt, 64500, 100", into the colon separated format used by the dictionary.
+func Normalize(community string) string {
+	split := strings.Split(community, ",")
+	for i, v := range split {
+		split[i] = strings.TrimSpace(v)
+	}
+	return strings.Join(split, ":")
+}
+
+// Decode looks up the meanings of the communities of a route in the format BIRD prints them. Communities without a
+// known meaning are left out.
+func (d *Dictionary) Decode(communities, largeCommunities, extCommunities []string) []Decoded {
+	decoded := []Decoded{}
+	decode := func(kind Kind, list []string) {
+		for _, c := range list {
+			if v, ok := d.Lookup(kind, Normalize(c)); ok {
+				decoded = append(decoded, v)
+			}
+		}
+	}
+	decode(Standard, communities)
+	decode(Large, largeCommunities)
+	decode(Extended, extCommunities)
+	return decoded
+}
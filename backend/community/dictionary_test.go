@@ -0,0 +1,147 @@
+package community
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDictionary_Lookup(t *testing.T) {
+	d := NewDictionary()
+	assert.NoError(t, d.Add(Set{
+		Name:             "Example",
+		LargeCommunities: map[string]string{"64500:2:*": "Learned from AS$3"},
+		ExtCommunities:   map[string]string{"generic:0x43000000:0x2": "RPKI origin validation state: invalid"},
+	}))
+	assert.NoError(t, d.Add(Set{
+		Name: "Upstream",
+		Communities: map[string]string{
+			"3223:*":       "Upstream community $2",
+			"3223:100-199": "Learned in location $2",
+			"3223:888":     "Learned from a peer",
+		},
+	}))
+
+	tests := []struct {
+		name string
+
+		kind      Kind
+		community string
+		meaning   string
+		source    string
+	}{
+		{
+			name:      "well-known",
+			kind:      Standard,
+			community: "65535:666",
+			meaning:   "BLACKHOLE: Discard traffic to this prefix",
+			source:    "RFC 7999",
+		},
+		{
+			name:      "exact beats range and wildcard",
+			kind:      Standard,
+			community: "3223:888",
+			meaning:   "Learned from a peer",
+			source:    "Upstream",
+		},
+		{
+			name:      "range beats wildcard",
+			kind:      Standard,
+			community: "3223:150",
+			meaning:   "Learned in location 150",
+			source:    "Upstream",
+		},
+		{
+			name:      "wildcard",
+			kind:      Standard,
+			community: "3223:5",
+			meaning:   "Upstream community 5",
+			source:    "Upstream",
+		},
+		{
+			name:      "large",
+			kind:      Large,
+			community: "64500:2:13335",
+			meaning:   "Learned from AS13335",
+			source:    "Example",
+		},
+		{
+			name:      "extended",
+			kind:      Extended,
+			community: "generic:0x43000000:0x2",
+			meaning:   "RPKI origin validation state: invalid",
+			source:    "Example",
+		},
+		{
+			name:      "unknown",
+			kind:      Standard,
+			community: "64501:1",
+		},
+		{
+			name:      "wrong kind",
+			kind:      Large,
+			community: "65535:666",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decoded, ok := d.Lookup(tt.kind, tt.community)
+			if tt.meaning == "" {
+				assert.False(t, ok)
+				return
+			}
+			assert.True(t, ok)
+			assert.Equal(t, Decoded{
+				Community: tt.community,
+				Kind:      tt.kind,
+				Meaning:   tt.meaning,
+				Source:    tt.source,
+			}, decoded)
+		})
+	}
+}
+
+func TestDictionary_Add(t *testing.T) {
+	tests := []struct {
+		name string
+
+		set     Set
+		wantErr string
+	}{
+		{
+			name:    "wrong field count",
+			set:     Set{Name: "bad", Communities: map[string]string{"1:2:3": "x"}},
+			wantErr: `bad: pattern "1:2:3" should have 2 fields`,
+		},
+		{
+			name:    "invalid range",
+			set:     Set{Name: "bad", LargeCommunities: map[string]string{"1:5-2:3": "x"}},
+			wantErr: `bad: pattern "1:5-2:3" has an invalid range`,
+		},
+		{
+			name:    "empty field",
+			set:     Set{Name: "bad", ExtCommunities: map[string]string{"rt::1": "x"}},
+			wantErr: `bad: pattern "rt::1" has an empty field`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.EqualError(t, NewDictionary().Add(tt.set), tt.wantErr)
+		})
+	}
+}
+
+func TestDictionary_Decode(t *testing.T) {
+	d := NewDictionary()
+	assert.NoError(t, d.Add(Set{Name: "Example", ExtCommunities: map[string]string{"rt:*:*": "Route target $2:$3"}}))
+	decoded := d.Decode([]string{"65535,65281", "64500,1"}, nil, []string{"rt, 64500, 100"})
+	assert.Equal(t, []Decoded{
+		{
+			Community: "65535:65281",
+			Kind:      Standard,
+			Meaning:   "NO_EXPORT: Do not advertise outside of the AS or confederation",
+			Source:    "RFC 1997",
+		},
+		{Community: "rt:64500:100", Kind: Extended, Meaning: "Route target 64500:100", Source: "Example"},
+	}, decoded)
+}
@@ -0,0 +1,63 @@
+package community
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+)
+
+// LoadFile loads a YAML dictionary file. If the file doesn't set a name, the file name is used.
+func LoadFile(path string) (Set, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Set{}, err
+	}
+	defer f.Close()
+
+	var s Set
+	dec := yaml.NewDecoder(f)
+	dec.KnownFields(true)
+	if err := dec.Decode(&s); err != nil {
+		return Set{}, errors.New(filepath.Base(path) + ": " + err.Error())
+	}
+	if s.Name == "" {
+		s.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	return s, nil
+}
+
+// InitDictionary creates a dictionary from the environment. BGP_COMMUNITY_DIR can be set to a directory of YAML
+// dictionary files, such as one for our own communities and one for each upstream.
+func InitDictionary(log *zap.Logger) (*Dictionary, error) {
+	d := NewDictionary()
+
+	if dir := os.Getenv("BGP_COMMUNITY_DIR"); dir != "" {
+		paths, err := filepath.Glob(filepath.Join(dir, "*.yml"))
+		if err != nil {
+			return nil, err
+		}
+		yamlPaths, err := filepath.Glob(filepath.Join(dir, "*.yaml"))
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, yamlPaths...)
+		sort.Strings(paths)
+		for _, path := range paths {
+			s, err := LoadFile(path)
+			if err != nil {
+				return nil, err
+			}
+			if err := d.Add(s); err != nil {
+				return nil, err
+			}
+			log.Info("loaded bgp community dictionary", zap.String("path", path), zap.String("name", s.Name))
+		}
+	}
+
+	return d, nil
+}
@@ -0,0 +1,56 @@
+package community
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestInitDictionary(t *testing.T) {
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "voxility.yml"), []byte(`communities:
+  "3223:888": Learned from a peer
+large_communities:
+  "3223:1:*": Learned in region $3
+`), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(filepath.Join(dir, "ours.yaml"), []byte(`name: Our network
+large_communities:
+  "64500:1:5": Originated by us
+`), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("BGP_COMMUNITY_DIR", dir)
+
+	d, err := InitDictionary(zap.NewNop())
+	if !assert.NoError(t, err) {
+		return
+	}
+	decoded, ok := d.Lookup(Standard, "3223:888")
+	assert.True(t, ok)
+	assert.Equal(t, "voxility", decoded.Source)
+	decoded, ok = d.Lookup(Large, "3223:1:4")
+	assert.True(t, ok)
+	assert.Equal(t, "Learned in region 4", decoded.Meaning)
+	decoded, ok = d.Lookup(Large, "64500:1:5")
+	assert.True(t, ok)
+	assert.Equal(t, "Originated by us", decoded.Meaning)
+	assert.Equal(t, "Our network", decoded.Source)
+}
+
+func TestLoadFile_unknownField(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bad.yaml")
+	if err := os.WriteFile(path, []byte("comunities: {}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	_, err := LoadFile(path)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "field comunities not found")
+	}
+}
@@ -0,0 +1,54 @@
+package community
+
+// Set is a set of community patterns and their meanings. This is also the format of the YAML dictionary files.
+type Set struct {
+	// Name is used to define the name shown as the source of the meanings, such as the name of the network.
+	Name string `yaml:"name"`
+
+	// Communities is used to define the meanings of standard communities, keyed by pattern.
+	Communities map[string]string `yaml:"communities"`
+
+	// LargeCommunities is used to define the meanings of large communities, keyed by pattern.
+	LargeCommunities map[string]string `yaml:"large_communities"`
+
+	// ExtCommunities is used to define the meanings of extended communities, keyed by pattern.
+	ExtCommunities map[string]string `yaml:"ext_communities"`
+}
+
+// Defines the well-known communities from the IANA registry, with the RFC that defines each. Communities used by
+// particular networks, including our own, are loaded from the YAML dictionary files instead.
+var wellKnown = []Set{
+	{
+		Name: "RFC 1997",
+		Communities: map[string]string{
+			"65535:65281": "NO_EXPORT: Do not advertise outside of the AS or confederation",
+			"65535:65282": "NO_ADVERTISE: Do not advertise to any peer",
+			"65535:65283": "NO_EXPORT_SUBCONFED: Do not advertise outside of the AS",
+		},
+	},
+	{
+		Name:        "RFC 3765",
+		Communities: map[string]string{"65535:65284": "NOPEER: Do not advertise to bilateral peers"},
+	},
+	{
+		Name:        "RFC 7611",
+		Communities: map[string]string{"65535:1": "ACCEPT_OWN: Accept the route even if it was originated locally"},
+	},
+	{
+		Name:        "RFC 7999",
+		Communities: map[string]string{"65535:666": "BLACKHOLE: Discard traffic to this prefix"},
+	},
+	{
+		Name: "RFC 8326",
+		Communities: map[string]string{
+			"65535:0": "GRACEFUL_SHUTDOWN: The session is being shut down, so this route should be depreferenced",
+		},
+	},
+	{
+		Name: "RFC 9494",
+		Communities: map[string]string{
+			"65535:6": "LLGR_STALE: The route is stale and being retained by long-lived graceful restart",
+			"65535:7": "NO_LLGR: Do not retain the route with long-lived graceful restart",
+		},
+	},
+}
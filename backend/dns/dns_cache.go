@@ -0,0 +1,176 @@
+package dns
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	godns "github.com/miekg/dns"
+	"go.uber.org/zap"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	// Defines the longest time a response will be cached for, regardless of its TTL.
+	maxCacheTTL = time.Hour
+
+	// Defines how many entries the cache can hold before expired entries are swept.
+	cacheSweepSize = 10000
+)
+
+// cacheKey is used to identify a query in the cache.
+type cacheKey struct {
+	addr       string
+	hostname   string
+	recordType uint16
+	opts       queryOptions
+}
+
+// String returns the key for use with singleflight.
+func (k cacheKey) String() string {
+	return fmt.Sprintf("%s|%s|%d|%+v", k.addr, k.hostname, k.recordType, k.opts)
+}
+
+// cacheEntry is a response held in the cache.
+type cacheEntry struct {
+	result  *queryResult
+	stored  time.Time
+	expires time.Time
+}
+
+// queryCache is an in-process cache of DNS responses which honours the TTLs of the records.
+type queryCache struct {
+	mu      sync.Mutex
+	entries map[cacheKey]*cacheEntry
+	group   singleflight.Group
+}
+
+// Defines the cache used for all queries.
+var defaultCache = &queryCache{entries: map[cacheKey]*cacheEntry{}}
+
+// Returns how long a response can be cached for. Negative responses use the SOA minimum as described in
+// RFC 2308, and errors other than NXDOMAIN are not cached.
+func cacheTTL(msg *godns.Msg) time.Duration {
+	if msg.Truncated || (msg.Rcode != godns.RcodeSuccess && msg.Rcode != godns.RcodeNameError) {
+		return 0
+	}
+
+	var ttl uint32
+	found := false
+	setMin := func(v uint32) {
+		if !found || v < ttl {
+			ttl = v
+			found = true
+		}
+	}
+	if len(msg.Answer) != 0 {
+		for _, rr := range msg.Answer {
+			setMin(rr.Header().Ttl)
+		}
+	} else {
+		for _, rr := range msg.Ns {
+			switch v := rr.(type) {
+			case *godns.SOA:
+				setMin(v.Hdr.Ttl)
+				setMin(v.Minttl)
+			case *godns.NS:
+				setMin(v.Hdr.Ttl)
+			}
+		}
+	}
+
+	d := time.Duration(ttl) * time.Second
+	if d > maxCacheTTL {
+		d = maxCacheTTL
+	}
+	return d
+}
+
+// Returns a copy of a cached response with the TTLs reduced by the time it has been in the cache.
+func (e *cacheEntry) copy(now time.Time) *queryResult {
+	msg := e.result.Msg.Copy()
+	elapsed := uint32(now.Sub(e.stored) / time.Second)
+	for _, section := range [][]godns.RR{msg.Answer, msg.Ns, msg.Extra} {
+		for _, rr := range section {
+			h := rr.Header()
+			if h.Rrtype == godns.TypeOPT {
+				continue
+			}
+			if h.Ttl > elapsed {
+				h.Ttl -= elapsed
+			} else {
+				h.Ttl = 0
+			}
+		}
+	}
+	return &queryResult{
+		Msg:     msg,
+		Address: e.result.Address,
+		Cached:  true,
+	}
+}
+
+// Removes expired entries. The lock must be held.
+func (c *queryCache) sweep(now time.Time) {
+	for k, v := range c.entries {
+		if !now.Before(v.expires) {
+			delete(c.entries, k)
+		}
+	}
+}
+
+// Gets a response from the cache, or sends the query if there is nothing cached. Identical queries which are
+// in flight at the same time are only sent once.
+func (c *queryCache) query(
+	log *zap.Logger,
+	addr string,
+	recordType uint16,
+	hostname string,
+	opts queryOptions,
+) (*queryResult, error) {
+	noCache := opts.NoCache
+	opts.NoCache = false
+	key := cacheKey{addr: addr, hostname: strings.ToLower(hostname), recordType: recordType, opts: opts}
+
+	// Check the cache.
+	if !noCache {
+		c.mu.Lock()
+		e, ok := c.entries[key]
+		now := time.Now()
+		if ok && now.Before(e.expires) {
+			c.mu.Unlock()
+			return e.copy(now), nil
+		}
+		c.mu.Unlock()
+	}
+
+	// Send the query, collapsing any identical ones.
+	v, err, shared := c.group.Do(key.String(), func() (interface{}, error) {
+		result, err := exchange(log, addr, recordType, hostname, opts)
+		if err != nil {
+			return nil, err
+		}
+		if ttl := cacheTTL(result.Msg); ttl > 0 {
+			now := time.Now()
+			c.mu.Lock()
+			if len(c.entries) >= cacheSweepSize {
+				c.sweep(now)
+			}
+			if len(c.entries) < cacheSweepSize {
+				c.entries[key] = &cacheEntry{result: result, stored: now, expires: now.Add(ttl)}
+			}
+			c.mu.Unlock()
+		}
+		return result, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	result := v.(*queryResult)
+	if shared {
+		// Make sure each caller has their own message.
+		result = &queryResult{Msg: result.Msg.Copy(), Address: result.Address, RTT: result.RTT}
+	}
+	return result, nil
+}
@@ -0,0 +1,86 @@
+package dns
+
+import (
+	"testing"
+	"time"
+
+	godns "github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+)
+
+func mustRR(t *testing.T, s string) godns.RR {
+	t.Helper()
+	rr, err := godns.NewRR(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rr
+}
+
+func Test_cacheTTL(t *testing.T) {
+	tests := []struct {
+		name string
+
+		msg     *godns.Msg
+		expects time.Duration
+	}{
+		{
+			name: "lowest answer ttl",
+			msg: &godns.Msg{Answer: []godns.RR{
+				mustRR(t, "example.com. 300 IN A 192.0.2.1"),
+				mustRR(t, "example.com. 60 IN A 192.0.2.2"),
+			}},
+			expects: time.Minute,
+		},
+		{
+			name: "negative response uses soa minimum",
+			msg: &godns.Msg{
+				MsgHdr: godns.MsgHdr{Rcode: godns.RcodeNameError},
+				Ns: []godns.RR{
+					mustRR(t, "example.com. 3600 IN SOA ns1.example.com. hostmaster.example.com. 1 7200 3600 1209600 120"),
+				},
+			},
+			expects: 2 * time.Minute,
+		},
+		{
+			name: "server failure is not cached",
+			msg: &godns.Msg{
+				MsgHdr: godns.MsgHdr{Rcode: godns.RcodeServerFailure},
+				Answer: []godns.RR{mustRR(t, "example.com. 300 IN A 192.0.2.1")},
+			},
+			expects: 0,
+		},
+		{
+			name:    "long ttls are capped",
+			msg:     &godns.Msg{Answer: []godns.RR{mustRR(t, "example.com. 86400 IN A 192.0.2.1")}},
+			expects: maxCacheTTL,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expects, cacheTTL(tt.msg))
+		})
+	}
+}
+
+func Test_cacheEntry_copy(t *testing.T) {
+	stored := time.Now()
+	e := &cacheEntry{
+		result: &queryResult{
+			Msg: &godns.Msg{Answer: []godns.RR{
+				mustRR(t, "example.com. 300 IN A 192.0.2.1"),
+				mustRR(t, "example.com. 5 IN A 192.0.2.2"),
+			}},
+			Address: "192.0.2.53:53",
+		},
+		stored: stored,
+	}
+	result := e.copy(stored.Add(10 * time.Second))
+	assert.True(t, result.Cached)
+	assert.Equal(t, "192.0.2.53:53", result.Address)
+	assert.Equal(t, uint32(290), result.Answer[0].Header().Ttl)
+	assert.Equal(t, uint32(0), result.Answer[1].Header().Ttl)
+
+	// Make sure the cached message was not changed.
+	assert.Equal(t, uint32(300), e.result.Answer[0].Header().Ttl)
+}
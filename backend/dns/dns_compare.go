@@ -0,0 +1,177 @@
+package dns
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+
+	godns "github.com/miekg/dns"
+	"go.uber.org/zap"
+)
+
+// ResolverResult is the answer from a single resolver in a comparison.
+type ResolverResult struct {
+	// Resolver is used to define the resolver which was queried.
+	Resolver Resolver `json:"resolver"`
+
+	// Result is used to define the full response from the resolver.
+	Result Server `json:"result"`
+
+	// Answers is used to define the sorted answers without their TTLs, which is what is compared.
+	Answers []string `json:"answers"`
+
+	// Differs is true if the resolver did not give the same response as most of the resolvers.
+	Differs bool `json:"differs"`
+}
+
+// Returns the key used to compare the result against the others.
+func (r *ResolverResult) key() string {
+	if r.Result.Error != "" {
+		return "error"
+	}
+	return r.Result.Rcode + "\n" + strings.Join(r.Answers, "\n")
+}
+
+// ComparisonRow is used to define which resolvers returned an answer.
+type ComparisonRow struct {
+	// Answer is used to define the answer without its TTL.
+	Answer string `json:"answer"`
+
+	// Present is used to define if each resolver returned the answer, in the same order as the results.
+	Present []bool `json:"present"`
+}
+
+// Comparison is the result of asking several resolvers the same question.
+type Comparison struct {
+	// Hostname is used to define the hostname which was looked up.
+	Hostname string `json:"hostname"`
+
+	// RecordType is used to define the record type which was looked up.
+	RecordType string `json:"record_type"`
+
+	// Consistent is true if every resolver gave the same response.
+	Consistent bool `json:"consistent"`
+
+	// Results is used to define the response from each resolver.
+	Results []*ResolverResult `json:"results"`
+
+	// Matrix is used to define every answer given by any resolver and which resolvers gave it.
+	Matrix []ComparisonRow `json:"matrix"`
+}
+
+// String returns the comparison as a table.
+func (c *Comparison) String() string {
+	str := "--- " + c.RecordType + " " + c.Hostname
+	if !c.Consistent {
+		str += " (resolvers disagree)"
+	}
+	str += " ---\n"
+	for i, r := range c.Results {
+		str += "-- " + r.Resolver.Name + " [" + r.Resolver.Address + "]"
+		if r.Differs {
+			str += " (differs)"
+		}
+		str += " --\n"
+		if r.Result.Error != "" {
+			str += ";; error: " + r.Result.Error + "\n"
+		} else {
+			str += ";; status: " + r.Result.Rcode + "\n"
+		}
+		for _, row := range c.Matrix {
+			if row.Present[i] {
+				str += row.Answer + "\n"
+			}
+		}
+		str += "\n"
+	}
+	return str
+}
+
+// CompareResolvers asks every resolver the same question in parallel and compares their answers.
+func CompareResolvers(
+	log *zap.Logger, resolvers []Resolver, recordType, hostname string, opts LookupOptions,
+) (*Comparison, error) {
+	recordType = strings.ToUpper(recordType)
+	if t, ok := godns.StringToType[recordType]; !ok || t == godns.TypeANY {
+		return nil, errors.New("invalid record type")
+	}
+	if !strings.HasSuffix(hostname, ".") {
+		hostname += "."
+	}
+	queryOpts, err := opts.queryOptions()
+	if err != nil {
+		return nil, err
+	}
+
+	// Query each resolver.
+	results := make([]*ResolverResult, len(resolvers))
+	wg := sync.WaitGroup{}
+	for i, resolver := range resolvers {
+		wg.Add(1)
+		go func(i int, resolver Resolver) {
+			defer wg.Done()
+			result := &ResolverResult{Resolver: resolver, Answers: []string{}}
+			server, err := queryTypeFromNameserver(log, resolver.Address, recordType, hostname, queryOpts)
+			if err != nil {
+				server = Server{
+					Server:     resolver.Address,
+					Records:    []Record{},
+					Additional: []Record{},
+					Error:      err.Error(),
+				}
+			}
+			result.Result = server
+			for _, record := range server.Records {
+				result.Answers = append(result.Answers, record.Type+" "+record.data)
+			}
+			sort.Strings(result.Answers)
+			results[i] = result
+		}(i, resolver)
+	}
+	wg.Wait()
+
+	// Find the most common response and mark the resolvers which differ from it.
+	counts := map[string]int{}
+	for _, r := range results {
+		counts[r.key()]++
+	}
+	majority := ""
+	for k, v := range counts {
+		if v > counts[majority] || (v == counts[majority] && k < majority) {
+			majority = k
+		}
+	}
+	for _, r := range results {
+		r.Differs = r.key() != majority
+	}
+
+	// Build the matrix of answers.
+	answers := map[string]bool{}
+	for _, r := range results {
+		for _, a := range r.Answers {
+			answers[a] = true
+		}
+	}
+	matrix := []ComparisonRow{}
+	for _, a := range mapKeys(answers) {
+		row := ComparisonRow{Answer: a, Present: make([]bool, len(results))}
+		for i, r := range results {
+			for _, v := range r.Answers {
+				if v == a {
+					row.Present[i] = true
+					break
+				}
+			}
+		}
+		matrix = append(matrix, row)
+	}
+
+	return &Comparison{
+		Hostname:   strings.TrimRight(hostname, "."),
+		RecordType: recordType,
+		Consistent: len(counts) == 1,
+		Results:    results,
+		Matrix:     matrix,
+	}, nil
+}
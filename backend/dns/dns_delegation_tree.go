@@ -0,0 +1,319 @@
+package dns
+
+import (
+	"errors"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+
+	godns "github.com/miekg/dns"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+)
+
+// Defines the maximum number of nameservers that will be queried at once in an exhaustive trace.
+const maxDelegationConcurrency = 8
+
+// Defines the maximum number of levels that will be followed in an exhaustive trace.
+const maxDelegationDepth = 11
+
+// DelegationStatus is used to define what a nameserver did when asked about a name.
+type DelegationStatus string
+
+const (
+	// DelegationReferral means the nameserver referred us to another set of nameservers.
+	DelegationReferral DelegationStatus = "referral"
+
+	// DelegationAnswer means the nameserver answered authoritatively.
+	DelegationAnswer DelegationStatus = "answer"
+
+	// DelegationFailed means the nameserver returned an error or an unusable response.
+	DelegationFailed DelegationStatus = "failed"
+
+	// DelegationTimeout means the nameserver did not respond in time.
+	DelegationTimeout DelegationStatus = "timeout"
+)
+
+// DelegationServer is the result of asking a single nameserver about a name.
+type DelegationServer struct {
+	// Server is used to define the name of the nameserver.
+	Server string `json:"server"`
+
+	// Address is used to define the address that was queried. This is the glue if it was provided.
+	Address string `json:"address"`
+
+	// Status is used to define what the nameserver did.
+	Status DelegationStatus `json:"status"`
+
+	// Error is set when the status is failed or timeout.
+	Error string `json:"error,omitempty"`
+
+	// Zone is used to define the zone that the nameserver referred us to.
+	Zone string `json:"zone,omitempty"`
+
+	// Referral is used to define the sorted NS set that the nameserver referred us to.
+	Referral []string `json:"referral"`
+
+	// Differs is true if the referral is not the same as the one most nameservers at this level gave.
+	Differs bool `json:"differs"`
+
+	// Records is used to define the records that the nameserver returned.
+	Records []Record `json:"records"`
+}
+
+// String returns the server in a human readable format.
+func (s *DelegationServer) String() string {
+	str := "-- " + s.Server + " [" + s.Address + "] " + string(s.Status)
+	switch {
+	case s.Error != "":
+		str += ": " + s.Error
+	case s.Status == DelegationReferral:
+		str += " to " + s.Zone + " (" + strings.Join(s.Referral, ", ") + ")"
+	}
+	if s.Differs {
+		str += " (differs)"
+	}
+	str += " --\n"
+	for _, record := range s.Records {
+		if record.stringer != nil {
+			str += record.stringer() + "\n"
+		}
+	}
+	return str
+}
+
+// DelegationLevel is used to define every nameserver which was asked at one depth of the trace.
+type DelegationLevel struct {
+	// Zone is used to define the zone the nameservers at this level were delegated.
+	Zone string `json:"zone"`
+
+	// Consistent is true if every responding nameserver gave the same referral or answer.
+	Consistent bool `json:"consistent"`
+
+	// Servers is used to define the results from each nameserver.
+	Servers []*DelegationServer `json:"servers"`
+
+	// Error is set if the nameservers at this level were not asked, such as when the trace is too deep.
+	Error string `json:"error,omitempty"`
+}
+
+// DelegationTree is the result of an exhaustive trace.
+type DelegationTree struct {
+	// Hostname is used to define the name that was traced.
+	Hostname string `json:"hostname"`
+
+	// Levels is used to define each level of the delegation from the root downwards.
+	Levels []*DelegationLevel `json:"levels"`
+}
+
+// String returns the tree in a human readable format.
+func (t *DelegationTree) String() string {
+	str := ""
+	for _, level := range t.Levels {
+		str += "--- " + level.Zone
+		if !level.Consistent {
+			str += " (inconsistent)"
+		}
+		if level.Error != "" {
+			str += " (" + level.Error + ")"
+		}
+		str += " ---\n"
+		for _, s := range level.Servers {
+			str += s.String()
+		}
+		str += "\n"
+	}
+	return str
+}
+
+// Asks a single nameserver about a name and classifies the response.
+func probeDelegation(
+	log *zap.Logger, server, address string, recordType uint16, hostname string, opts queryOptions,
+) (*DelegationServer, map[string]string) {
+	result := &DelegationServer{
+		Server:   server,
+		Address:  address,
+		Referral: []string{},
+		Records:  []Record{},
+	}
+	glue := map[string]string{}
+
+	opts.NoRecursion = true
+	msg, err := rawQuery(log, nameserverAddr(address), recordType, hostname, opts)
+	if err != nil {
+		result.Status = DelegationFailed
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			result.Status = DelegationTimeout
+		}
+		result.Error = err.Error()
+		return result, glue
+	}
+
+	// Add the records for showing to the user.
+	for _, rr := range append(msg.Answer, msg.Ns...) {
+		record, err := recordFromAnswer(rr)
+		if err == nil {
+			result.Records = append(result.Records, record)
+		}
+	}
+
+	switch {
+	case msg.Rcode != godns.RcodeSuccess && msg.Rcode != godns.RcodeNameError:
+		result.Status = DelegationFailed
+		result.Error = "server responded with " + godns.RcodeToString[msg.Rcode]
+	case msg.Authoritative:
+		result.Status = DelegationAnswer
+	default:
+		for _, rr := range msg.Ns {
+			if v, ok := rr.(*godns.NS); ok {
+				result.Zone = strings.TrimRight(v.Hdr.Name, ".")
+				result.Referral = append(result.Referral, normaliseName(v.Ns))
+			}
+		}
+		if len(result.Referral) == 0 {
+			result.Status = DelegationFailed
+			result.Error = "response is neither authoritative or a referral"
+			break
+		}
+		result.Status = DelegationReferral
+		sort.Strings(result.Referral)
+
+		// Collect the glue so the next level does not need to look up the nameservers.
+		for _, rr := range msg.Extra {
+			switch v := rr.(type) {
+			case *godns.A:
+				glue[normaliseName(v.Hdr.Name)] = v.A.String()
+			case *godns.AAAA:
+				if _, ok := glue[normaliseName(v.Hdr.Name)]; !ok {
+					glue[normaliseName(v.Hdr.Name)] = v.AAAA.String()
+				}
+			}
+		}
+	}
+
+	return result, glue
+}
+
+// Marks the servers which disagree with the majority and returns the most common referral.
+func markDifferingServers(level *DelegationLevel) (string, []string) {
+	counts := map[string]int{}
+	for _, s := range level.Servers {
+		if s.Status == DelegationReferral {
+			counts[s.Zone+" "+strings.Join(s.Referral, " ")]++
+		}
+	}
+	majority := ""
+	for k, v := range counts {
+		if v > counts[majority] || (v == counts[majority] && k < majority) {
+			majority = k
+		}
+	}
+
+	level.Consistent = len(counts) <= 1
+	for _, s := range level.Servers {
+		if s.Status == DelegationReferral && s.Zone+" "+strings.Join(s.Referral, " ") != majority {
+			s.Differs = true
+		}
+		if len(counts) != 0 && s.Status == DelegationAnswer {
+			// Some servers answered whilst others referred us elsewhere.
+			level.Consistent = false
+		}
+	}
+	if majority == "" {
+		return "", nil
+	}
+	split := strings.Split(majority, " ")
+	return split[0], split[1:]
+}
+
+// TraceDelegations follows the delegation of a hostname from the root, asking every nameserver at every
+// level rather than a random one. The nameservers at the next level are all the ones referred to by any
+// server, so that differing referrals are also followed.
+func TraceDelegations(log *zap.Logger, recordType, hostname string, opts LookupOptions) (*DelegationTree, error) {
+	if !strings.HasSuffix(hostname, ".") {
+		hostname += "."
+	}
+	queryOpts, err := opts.queryOptions()
+	if err != nil {
+		return nil, err
+	}
+	qtype, ok := godns.StringToType[strings.ToUpper(recordType)]
+	if !ok || qtype == godns.TypeANY {
+		qtype = godns.TypeNS
+	}
+
+	// Start with every root server.
+	servers := map[string]string{}
+	for _, v := range getRootServers() {
+		servers[strings.TrimRight(v.Name, ".")], _ = v.Address()
+	}
+	return traceDelegations(hostname, servers, func(server, address string) (*DelegationServer, map[string]string) {
+		return probeDelegation(log, server, address, qtype, hostname, queryOpts)
+	}), nil
+}
+
+// Follows the delegation of a hostname down from the servers given, using the probe function to ask each
+// nameserver. If the delegation is too deep, the tree so far is returned with an error on the level which was
+// not asked.
+func traceDelegations(
+	hostname string, servers map[string]string,
+	probe func(server, address string) (*DelegationServer, map[string]string),
+) *DelegationTree {
+	tree := &DelegationTree{Hostname: strings.TrimRight(hostname, "."), Levels: []*DelegationLevel{}}
+	zone := "."
+	for depth := 0; len(servers) != 0; depth++ {
+		if depth == maxDelegationDepth {
+			tree.Levels = append(tree.Levels, &DelegationLevel{
+				Zone:    zone,
+				Servers: []*DelegationServer{},
+				Error:   "nameserver search depth exceeded",
+			})
+			break
+		}
+
+		// Query every server at this level.
+		level := &DelegationLevel{Zone: zone, Servers: []*DelegationServer{}}
+		nextServers := map[string]string{}
+		lock := sync.Mutex{}
+		sem := make(chan struct{}, maxDelegationConcurrency)
+		eg := errgroup.Group{}
+		for name, address := range servers {
+			name, address := name, address
+			eg.Go(func() error {
+				sem <- struct{}{}
+				defer func() { <-sem }()
+				result, glue := probe(name, address)
+
+				lock.Lock()
+				defer lock.Unlock()
+				level.Servers = append(level.Servers, result)
+				for _, ns := range result.Referral {
+					if address, ok := glue[ns]; ok || nextServers[ns] == "" {
+						if !ok {
+							address = ns + "."
+						}
+						nextServers[ns] = address
+					}
+				}
+				return nil
+			})
+		}
+		_ = eg.Wait()
+		sort.Slice(level.Servers, func(i, j int) bool {
+			return level.Servers[i].Server < level.Servers[j].Server
+		})
+		tree.Levels = append(tree.Levels, level)
+
+		// Move down to the zone most servers referred us to.
+		var referral []string
+		zone, referral = markDifferingServers(level)
+		if referral == nil {
+			break
+		}
+		servers = nextServers
+	}
+
+	return tree
+}
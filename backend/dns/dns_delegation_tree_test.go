@@ -0,0 +1,93 @@
+package dns
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_markDifferingServers(t *testing.T) {
+	level := &DelegationLevel{
+		Zone: "com",
+		Servers: []*DelegationServer{
+			{Server: "a", Status: DelegationReferral, Zone: "example.com", Referral: []string{"ns1", "ns2"}},
+			{Server: "b", Status: DelegationReferral, Zone: "example.com", Referral: []string{"ns1", "ns2"}},
+			{Server: "c", Status: DelegationReferral, Zone: "example.com", Referral: []string{"ns3"}},
+			{Server: "d", Status: DelegationTimeout},
+		},
+	}
+	zone, referral := markDifferingServers(level)
+	assert.Equal(t, "example.com", zone)
+	assert.Equal(t, []string{"ns1", "ns2"}, referral)
+	assert.False(t, level.Consistent)
+	assert.False(t, level.Servers[0].Differs)
+	assert.False(t, level.Servers[1].Differs)
+	assert.True(t, level.Servers[2].Differs)
+	assert.False(t, level.Servers[3].Differs)
+
+	level = &DelegationLevel{
+		Servers: []*DelegationServer{
+			{Server: "a", Status: DelegationAnswer},
+			{Server: "b", Status: DelegationAnswer},
+		},
+	}
+	zone, referral = markDifferingServers(level)
+	assert.Equal(t, "", zone)
+	assert.Nil(t, referral)
+	assert.True(t, level.Consistent)
+}
+
+func Test_traceDelegations(t *testing.T) {
+	tests := []struct {
+		name string
+
+		probe     func(server, address string) (*DelegationServer, map[string]string)
+		levels    int
+		lastZone  string
+		lastError string
+	}{
+		{
+			name: "answered",
+			probe: func(server, address string) (*DelegationServer, map[string]string) {
+				if server == "a.root-servers.net" {
+					return &DelegationServer{
+						Server: server, Status: DelegationReferral, Zone: "com", Referral: []string{"a.gtld-servers.net"},
+					}, map[string]string{"a.gtld-servers.net": "192.5.6.30"}
+				}
+				return &DelegationServer{Server: server, Status: DelegationAnswer, Referral: []string{}}, nil
+			},
+			levels:   2,
+			lastZone: "com",
+		},
+		{
+			name: "depth exceeded",
+			probe: func(server, address string) (*DelegationServer, map[string]string) {
+				// Refer to a new zone one label deeper every time.
+				zone := "deeper." + strings.TrimPrefix(server, "ns.")
+				return &DelegationServer{
+					Server: server, Status: DelegationReferral, Zone: zone, Referral: []string{"ns." + zone},
+				}, nil
+			},
+			levels:    maxDelegationDepth + 1,
+			lastZone:  strings.Repeat("deeper.", maxDelegationDepth) + "a.root-servers.net",
+			lastError: "nameserver search depth exceeded",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tree := traceDelegations(
+				"www.example.com.", map[string]string{"a.root-servers.net": "198.41.0.4"}, tt.probe)
+			assert.Equal(t, "www.example.com", tree.Hostname)
+			if assert.Len(t, tree.Levels, tt.levels) {
+				last := tree.Levels[len(tree.Levels)-1]
+				assert.Equal(t, tt.lastZone, last.Zone)
+				assert.Equal(t, tt.lastError, last.Error)
+				if tt.lastError != "" {
+					assert.Empty(t, last.Servers)
+					assert.Contains(t, tree.String(), "("+tt.lastError+")")
+				}
+			}
+		})
+	}
+}
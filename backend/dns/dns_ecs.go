@@ -0,0 +1,80 @@
+package dns
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+
+	godns "github.com/miekg/dns"
+)
+
+// ClientSubnet is used to define the EDNS Client Subnet option sent with a query and what the server replied with.
+type ClientSubnet struct {
+	// Prefix is used to define the client subnet which was sent.
+	Prefix string `json:"prefix"`
+
+	// ScopePrefix is used to define the prefix length the server says the answer is valid for.
+	ScopePrefix uint8 `json:"scope_prefix"`
+}
+
+// String returns the client subnet in a human readable format.
+func (c *ClientSubnet) String() string {
+	return c.Prefix + " scope /" + strconv.Itoa(int(c.ScopePrefix))
+}
+
+// Parses a client subnet and returns it in its normalised form. A bare IP address uses the source prefix
+// lengths recommended by RFC 7871, which is /24 for IPv4 and /56 for IPv6.
+func normaliseClientSubnet(s string) (string, error) {
+	if ip := net.ParseIP(s); ip != nil {
+		if ip.To4() != nil {
+			s += "/24"
+		} else {
+			s += "/56"
+		}
+	}
+	_, ipNet, err := net.ParseCIDR(s)
+	if err != nil {
+		return "", errors.New("invalid client subnet")
+	}
+	return ipNet.String(), nil
+}
+
+// Adds the EDNS Client Subnet option for a normalised prefix to a message.
+func setClientSubnet(msg *godns.Msg, prefix string) error {
+	_, ipNet, err := net.ParseCIDR(prefix)
+	if err != nil {
+		return err
+	}
+	ones, _ := ipNet.Mask.Size()
+	option := &godns.EDNS0_SUBNET{
+		Code:          godns.EDNS0SUBNET,
+		Family:        1,
+		SourceNetmask: uint8(ones),
+		Address:       ipNet.IP,
+	}
+	if ipNet.IP.To4() == nil {
+		option.Family = 2
+	}
+	msg.SetEdns0(4096, false)
+	opt := msg.IsEdns0()
+	opt.Option = append(opt.Option, option)
+	return nil
+}
+
+// Gets the client subnet the server replied with. Nil is returned if the server did not include the option.
+func clientSubnetFromMsg(msg *godns.Msg) *ClientSubnet {
+	opt := msg.IsEdns0()
+	if opt == nil {
+		return nil
+	}
+	for _, v := range opt.Option {
+		if subnet, ok := v.(*godns.EDNS0_SUBNET); ok {
+			return &ClientSubnet{
+				Prefix:      fmt.Sprintf("%s/%d", subnet.Address, subnet.SourceNetmask),
+				ScopePrefix: subnet.SourceScope,
+			}
+		}
+	}
+	return nil
+}
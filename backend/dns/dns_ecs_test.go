@@ -0,0 +1,44 @@
+package dns
+
+import (
+	"testing"
+
+	godns "github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_normaliseClientSubnet(t *testing.T) {
+	tests := []struct {
+		name string
+
+		input   string
+		expects string
+		err     bool
+	}{
+		{name: "ipv4 prefix", input: "81.2.115.0/24", expects: "81.2.115.0/24"},
+		{name: "ipv4 prefix with host bits", input: "81.2.115.158/20", expects: "81.2.112.0/20"},
+		{name: "ipv4 address", input: "81.2.115.158", expects: "81.2.115.0/24"},
+		{name: "ipv6 address", input: "2a03:2800::1", expects: "2a03:2800::/56"},
+		{name: "invalid", input: "not a subnet", err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			subnet, err := normaliseClientSubnet(tt.input)
+			if tt.err {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expects, subnet)
+		})
+	}
+}
+
+func Test_clientSubnetFromMsg(t *testing.T) {
+	msg := &godns.Msg{}
+	assert.Nil(t, clientSubnetFromMsg(msg))
+	if err := setClientSubnet(msg, "81.2.115.0/24"); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, &ClientSubnet{Prefix: "81.2.115.0/24", ScopePrefix: 0}, clientSubnetFromMsg(msg))
+}
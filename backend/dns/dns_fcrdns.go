@@ -0,0 +1,200 @@
+package dns
+
+import (
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+
+	godns "github.com/miekg/dns"
+	"go.uber.org/zap"
+)
+
+// FCrDNSAddress is an address found when looking up a PTR hostname.
+type FCrDNSAddress struct {
+	// Type is used to define the record type the address came from, A or AAAA.
+	Type string `json:"type"`
+
+	// Address is used to define the address.
+	Address string `json:"address"`
+
+	// TTL is used to define the time to live of the record.
+	TTL uint32 `json:"ttl"`
+}
+
+// FCrDNSHostname is the forward lookup of a single PTR hostname.
+type FCrDNSHostname struct {
+	// Hostname is used to define the hostname from the PTR record.
+	Hostname string `json:"hostname"`
+
+	// TTL is used to define the time to live of the PTR record.
+	TTL uint32 `json:"ttl"`
+
+	// Resolver is used to define the address of the resolver which answered the forward lookups.
+	Resolver string `json:"resolver"`
+
+	// Addresses is used to define the A and AAAA records of the hostname.
+	Addresses []FCrDNSAddress `json:"addresses"`
+
+	// Confirmed is true if the original IP is one of the addresses.
+	Confirmed bool `json:"confirmed"`
+
+	// Error is set if the forward lookups failed.
+	Error string `json:"error,omitempty"`
+}
+
+// FCrDNSResult is the result of a forward-confirmed reverse DNS check.
+type FCrDNSResult struct {
+	// IP is used to define the IP address which was checked.
+	IP string `json:"ip"`
+
+	// ReverseName is used to define the name the PTR records were looked up at.
+	ReverseName string `json:"reverse_name"`
+
+	// Resolver is used to define the address of the resolver which answered the PTR lookup.
+	Resolver string `json:"resolver"`
+
+	// Rcode is used to define the response code of the PTR lookup.
+	Rcode string `json:"rcode"`
+
+	// Confirmed is true if any of the PTR hostnames resolve back to the IP.
+	Confirmed bool `json:"confirmed"`
+
+	// Hostnames is used to define the forward lookups of each PTR hostname.
+	Hostnames []*FCrDNSHostname `json:"hostnames"`
+}
+
+// String returns the result in a human readable format.
+func (r *FCrDNSResult) String() string {
+	str := "--- " + r.IP + " (" + r.ReverseName + ") "
+	if r.Confirmed {
+		str += "forward confirmed"
+	} else {
+		str += "not forward confirmed"
+	}
+	str += " ---\n;; status: " + r.Rcode + ", resolver: " + r.Resolver + "\n"
+	for _, h := range r.Hostnames {
+		str += r.ReverseName + ". " + strconv.FormatUint(uint64(h.TTL), 10) + " PTR " + h.Hostname + "."
+		switch {
+		case h.Error != "":
+			str += " (error: " + h.Error + ")"
+		case h.Confirmed:
+			str += " (confirmed)"
+		default:
+			str += " (does not match)"
+		}
+		str += "\n"
+		for _, a := range h.Addresses {
+			str += "    " + h.Hostname + ". " + strconv.FormatUint(uint64(a.TTL), 10) + " " + a.Type + " " +
+				a.Address + "\n"
+		}
+	}
+	return str
+}
+
+// Defines a function which sends a query to the resolver for a forward-confirmed reverse DNS check.
+type fcrdnsQuery func(recordType uint16, hostname string) (*queryResult, error)
+
+// Looks up the A and AAAA records of a PTR hostname and checks if the IP is one of them.
+func forwardConfirm(query fcrdnsQuery, ip net.IP, hostname string) *FCrDNSHostname {
+	result := &FCrDNSHostname{Hostname: strings.TrimRight(hostname, "."), Addresses: []FCrDNSAddress{}}
+	errs := []string{}
+	for _, qtype := range []uint16{godns.TypeA, godns.TypeAAAA} {
+		msg, err := query(qtype, hostname)
+		if err != nil {
+			errs = append(errs, godns.TypeToString[qtype]+": "+err.Error())
+			continue
+		}
+		result.Resolver = msg.Address
+		if msg.Rcode != godns.RcodeSuccess {
+			errs = append(errs, godns.TypeToString[qtype]+": server responded with "+godns.RcodeToString[msg.Rcode])
+			continue
+		}
+
+		// Collect the addresses. Any CNAMEs will have been followed by the resolver.
+		for _, rr := range msg.Answer {
+			var addr net.IP
+			switch v := rr.(type) {
+			case *godns.A:
+				addr = v.A
+			case *godns.AAAA:
+				addr = v.AAAA
+			default:
+				continue
+			}
+			result.Addresses = append(result.Addresses, FCrDNSAddress{
+				Type:    godns.TypeToString[rr.Header().Rrtype],
+				Address: addr.String(),
+				TTL:     rr.Header().Ttl,
+			})
+			if addr.Equal(ip) {
+				result.Confirmed = true
+			}
+		}
+	}
+	if len(errs) == 2 {
+		result.Error = strings.Join(errs, ", ")
+	}
+	return result
+}
+
+// CheckFCrDNS looks up every PTR record for an IP and then looks up each hostname forward to check if it resolves
+// back to the IP.
+func CheckFCrDNS(log *zap.Logger, ip net.IP, dnsServer string, opts LookupOptions) (*FCrDNSResult, error) {
+	queryOpts, err := opts.queryOptions()
+	if err != nil {
+		return nil, err
+	}
+	return checkFCrDNS(func(recordType uint16, hostname string) (*queryResult, error) {
+		return rawQuery(log, nameserverAddr(dnsServer), recordType, hostname, queryOpts)
+	}, ip)
+}
+
+// Runs a forward-confirmed reverse DNS check for an IP using the query function given.
+func checkFCrDNS(query fcrdnsQuery, ip net.IP) (*FCrDNSResult, error) {
+	hostname := reverseName(ip)
+
+	// Get the PTR records.
+	msg, err := query(godns.TypePTR, hostname)
+	if err != nil {
+		return nil, err
+	}
+	if msg.Rcode != godns.RcodeSuccess && msg.Rcode != godns.RcodeNameError {
+		return nil, errors.New("server responded with " + godns.RcodeToString[msg.Rcode])
+	}
+	result := &FCrDNSResult{
+		IP:          ip.String(),
+		ReverseName: strings.TrimRight(hostname, "."),
+		Resolver:    msg.Address,
+		Rcode:       godns.RcodeToString[msg.Rcode],
+		Hostnames:   []*FCrDNSHostname{},
+	}
+	ptrs := []*godns.PTR{}
+	for _, rr := range msg.Answer {
+		if v, ok := rr.(*godns.PTR); ok {
+			ptrs = append(ptrs, v)
+		}
+	}
+
+	// Look up each hostname forward in parallel.
+	result.Hostnames = make([]*FCrDNSHostname, len(ptrs))
+	wg := sync.WaitGroup{}
+	for i, ptr := range ptrs {
+		wg.Add(1)
+		go func(i int, ptr *godns.PTR) {
+			defer wg.Done()
+			h := forwardConfirm(query, ip, ptr.Ptr)
+			h.TTL = ptr.Hdr.Ttl
+			result.Hostnames[i] = h
+		}(i, ptr)
+	}
+	wg.Wait()
+	for _, h := range result.Hostnames {
+		if h.Confirmed {
+			result.Confirmed = true
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,198 @@
+package dns
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	godns "github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFCrDNSResult_String(t *testing.T) {
+	result := &FCrDNSResult{
+		IP:          "192.0.2.1",
+		ReverseName: "1.2.0.192.in-addr.arpa",
+		Resolver:    "1.1.1.1:53",
+		Rcode:       "NOERROR",
+		Confirmed:   true,
+		Hostnames: []*FCrDNSHostname{
+			{
+				Hostname:  "mail.example.com",
+				TTL:       3600,
+				Addresses: []FCrDNSAddress{{Type: "A", Address: "192.0.2.1", TTL: 300}},
+				Confirmed: true,
+			},
+			{
+				Hostname:  "www.example.com",
+				TTL:       3600,
+				Addresses: []FCrDNSAddress{{Type: "AAAA", Address: "2001:db8::1", TTL: 300}},
+			},
+		},
+	}
+	assert.Equal(t, "--- 192.0.2.1 (1.2.0.192.in-addr.arpa) forward confirmed ---\n"+
+		";; status: NOERROR, resolver: 1.1.1.1:53\n"+
+		"1.2.0.192.in-addr.arpa. 3600 PTR mail.example.com. (confirmed)\n"+
+		"    mail.example.com. 300 A 192.0.2.1\n"+
+		"1.2.0.192.in-addr.arpa. 3600 PTR www.example.com. (does not match)\n"+
+		"    www.example.com. 300 AAAA 2001:db8::1\n", result.String())
+}
+
+// Returns a query function which answers from a map of "TYPE name" to the records in zone file format. Names
+// which are not in the map get NXDOMAIN, and names in the errors map fail.
+func fakeFCrDNSQuery(t *testing.T, records map[string][]string, errs map[string]bool) fcrdnsQuery {
+	return func(recordType uint16, hostname string) (*queryResult, error) {
+		key := godns.TypeToString[recordType] + " " + hostname
+		if errs[key] {
+			return nil, errors.New("i/o timeout")
+		}
+		msg := &godns.Msg{}
+		rrs, ok := records[key]
+		if !ok {
+			msg.Rcode = godns.RcodeNameError
+		}
+		for _, v := range rrs {
+			rr, err := godns.NewRR(v)
+			if err != nil {
+				t.Fatal(err)
+			}
+			msg.Answer = append(msg.Answer, rr)
+		}
+		return &queryResult{Msg: msg, Address: "192.0.2.53:53"}, nil
+	}
+}
+
+func Test_checkFCrDNS(t *testing.T) {
+	tests := []struct {
+		name string
+
+		ip        string
+		records   map[string][]string
+		errs      map[string]bool
+		confirmed bool
+		hostnames []bool
+		errors    []string
+		rcode     string
+		wantErr   string
+	}{
+		{
+			name: "ipv4 matches",
+			ip:   "192.0.2.1",
+			records: map[string][]string{
+				"PTR 1.2.0.192.in-addr.arpa.": {"1.2.0.192.in-addr.arpa. 3600 IN PTR mail.example.com."},
+				"A mail.example.com.":         {"mail.example.com. 300 IN A 192.0.2.1"},
+				"AAAA mail.example.com.":      {},
+			},
+			confirmed: true,
+			hostnames: []bool{true},
+			errors:    []string{""},
+			rcode:     "NOERROR",
+		},
+		{
+			name: "ipv6 matches",
+			ip:   "2001:db8::1",
+			records: map[string][]string{
+				"PTR 1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa.": {
+					"1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa. 3600 IN PTR mail.example.com.",
+				},
+				"A mail.example.com.":    {"mail.example.com. 300 IN A 192.0.2.1"},
+				"AAAA mail.example.com.": {"mail.example.com. 300 IN AAAA 2001:db8:0::1"},
+			},
+			confirmed: true,
+			hostnames: []bool{true},
+			errors:    []string{""},
+			rcode:     "NOERROR",
+		},
+		{
+			name: "mismatch",
+			ip:   "192.0.2.1",
+			records: map[string][]string{
+				"PTR 1.2.0.192.in-addr.arpa.": {"1.2.0.192.in-addr.arpa. 3600 IN PTR mail.example.com."},
+				"A mail.example.com.":         {"mail.example.com. 300 IN A 192.0.2.2"},
+				"AAAA mail.example.com.":      {},
+			},
+			hostnames: []bool{false},
+			errors:    []string{""},
+			rcode:     "NOERROR",
+		},
+		{
+			name: "multiple ptrs with one match",
+			ip:   "192.0.2.1",
+			records: map[string][]string{
+				"PTR 1.2.0.192.in-addr.arpa.": {
+					"1.2.0.192.in-addr.arpa. 3600 IN PTR www.example.com.",
+					"1.2.0.192.in-addr.arpa. 3600 IN PTR mail.example.com.",
+				},
+				"A www.example.com.":     {"www.example.com. 300 IN A 198.51.100.1"},
+				"AAAA www.example.com.":  {},
+				"A mail.example.com.":    {"mail.example.com. 300 IN A 192.0.2.1"},
+				"AAAA mail.example.com.": {},
+			},
+			confirmed: true,
+			hostnames: []bool{false, true},
+			errors:    []string{"", ""},
+			rcode:     "NOERROR",
+		},
+		{
+			name: "nxdomain on forward",
+			ip:   "192.0.2.1",
+			records: map[string][]string{
+				"PTR 1.2.0.192.in-addr.arpa.": {"1.2.0.192.in-addr.arpa. 3600 IN PTR gone.example.com."},
+			},
+			hostnames: []bool{false},
+			errors: []string{
+				"A: server responded with NXDOMAIN, AAAA: server responded with NXDOMAIN",
+			},
+			rcode: "NOERROR",
+		},
+		{
+			name: "forward lookup fails for one type",
+			ip:   "192.0.2.1",
+			records: map[string][]string{
+				"PTR 1.2.0.192.in-addr.arpa.": {"1.2.0.192.in-addr.arpa. 3600 IN PTR mail.example.com."},
+				"A mail.example.com.":         {"mail.example.com. 300 IN A 192.0.2.1"},
+			},
+			errs:      map[string]bool{"AAAA mail.example.com.": true},
+			confirmed: true,
+			hostnames: []bool{true},
+			errors:    []string{""},
+			rcode:     "NOERROR",
+		},
+		{
+			name:      "no ptr",
+			ip:        "192.0.2.1",
+			records:   map[string][]string{},
+			hostnames: []bool{},
+			errors:    []string{},
+			rcode:     "NXDOMAIN",
+		},
+		{
+			name:    "ptr lookup fails",
+			ip:      "192.0.2.1",
+			errs:    map[string]bool{"PTR 1.2.0.192.in-addr.arpa.": true},
+			wantErr: "i/o timeout",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := checkFCrDNS(fakeFCrDNSQuery(t, tt.records, tt.errs), net.ParseIP(tt.ip))
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.rcode, result.Rcode)
+			assert.Equal(t, tt.confirmed, result.Confirmed)
+			hostnames := []bool{}
+			errs := []string{}
+			for _, h := range result.Hostnames {
+				hostnames = append(hostnames, h.Confirmed)
+				errs = append(errs, h.Error)
+				assert.Equal(t, uint32(3600), h.TTL)
+				assert.Equal(t, "192.0.2.53:53", h.Resolver)
+			}
+			assert.Equal(t, tt.hostnames, hostnames)
+			assert.Equal(t, tt.errors, errs)
+		})
+	}
+}
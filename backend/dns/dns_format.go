@@ -0,0 +1,105 @@
+package dns
+
+import (
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Returns the record types in the response in a stable order.
+func (r Response) sortedTypes() []string {
+	types := make([]string, 0, len(r))
+	for t := range r {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// ZoneString returns the records that were found in RFC 1035 master file format, so they can be pasted into a
+// zone file. Records which were found more than once are only included once.
+func (r Response) ZoneString() string {
+	str := ""
+	seen := map[string]bool{}
+	for _, t := range r.sortedTypes() {
+		str += r[t].zoneString(seen)
+	}
+	return str
+}
+
+// DigString returns the responses from each server in the same format as dig.
+func (r Response) DigString() string {
+	str := ""
+	for _, t := range r.sortedTypes() {
+		str += r[t].DigString()
+	}
+	return str
+}
+
+// ZoneString returns the records that were found in RFC 1035 master file format. For traces, only the records
+// from the answering servers are included rather than every referral along the way.
+func (rt RecordType) ZoneString() string {
+	return rt.zoneString(map[string]bool{})
+}
+
+func (rt RecordType) zoneString(seen map[string]bool) string {
+	// Find the servers which answered the record types that were asked for. Trace lookups for NS records end
+	// on the referral, so fall back to the last server if there are none.
+	servers := RecordType{}
+	for _, srv := range rt {
+		if srv.QueryType != "" {
+			servers = append(servers, srv)
+		}
+	}
+	if len(servers) == 0 && len(rt) != 0 {
+		servers = rt[len(rt)-1:]
+	}
+
+	str := ""
+	for _, srv := range servers {
+		for _, record := range srv.Records {
+			if record.stringer == nil {
+				continue
+			}
+			line := record.stringer()
+			if !seen[line] {
+				seen[line] = true
+				str += line + "\n"
+			}
+		}
+	}
+	return str
+}
+
+// DigString returns the responses from each server in the same format as dig.
+func (rt RecordType) DigString() string {
+	str := ""
+	for _, srv := range rt {
+		str += srv.DigString() + "\n"
+	}
+	return str
+}
+
+// DigString returns the response from the server in the same format as dig.
+func (srv Server) DigString() string {
+	if srv.msg == nil {
+		return ";; connection to " + srv.Server + " failed: " + srv.Error + "\n"
+	}
+
+	str := ";; Got answer:\n"
+	str += strings.Replace(srv.msg.String(), ";; opcode:", ";; ->>HEADER<<- opcode:", 1)
+	if !strings.HasSuffix(str, "\n") {
+		str += "\n"
+	}
+	str += "\n;; Query time: " + strconv.Itoa(int(srv.RTT)) + " msec"
+	if srv.Cached {
+		str += " (cached)"
+	}
+	str += "\n"
+	if host, port, err := net.SplitHostPort(srv.Address); err == nil {
+		str += ";; SERVER: " + host + "#" + port + "(" + srv.Server + ") (TCP)\n"
+	}
+	str += ";; MSG SIZE  rcvd: " + strconv.Itoa(srv.msg.Len()) + "\n"
+	return str
+}
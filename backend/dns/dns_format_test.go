@@ -0,0 +1,70 @@
+package dns
+
+import (
+	"testing"
+	"time"
+
+	godns "github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+)
+
+// Creates a server from a response to a question as if it was returned by the nameserver.
+func mustServer(t *testing.T, queryType string, question string, qtype uint16, answers ...string) Server {
+	t.Helper()
+	msg := new(godns.Msg)
+	msg.SetQuestion(question, qtype)
+	msg.Id = 1234
+	msg.Response = true
+	msg.RecursionAvailable = true
+	for _, v := range answers {
+		msg.Answer = append(msg.Answer, mustRR(t, v))
+	}
+	server, err := newServer("1.1.1.1", &queryResult{Msg: msg, Address: "1.1.1.1:53", RTT: 12 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, rr := range msg.Answer {
+		record, err := recordFromAnswer(rr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		server.Records = append(server.Records, record)
+	}
+	server.QueryType = queryType
+	return server
+}
+
+func TestResponse_ZoneString(t *testing.T) {
+	response := Response{
+		"TXT": RecordType{mustServer(
+			t, "TXT", "example.com.", godns.TypeTXT, `example.com. 300 IN TXT "v=spf1 -all" "with \"quotes\""`,
+		)},
+		"A": RecordType{
+			mustServer(t, "", "example.com.", godns.TypeNS, "example.com. 172800 IN NS a.iana-servers.net."),
+			mustServer(t, "A", "example.com.", godns.TypeA, "example.com. 3600 IN A 192.0.2.1",
+				"example.com. 3600 IN A 192.0.2.1"),
+		},
+	}
+	assert.Equal(t, "example.com.\t3600\tIN\tA\t192.0.2.1\n"+
+		"example.com.\t300\tIN\tTXT\t\"v=spf1 -all\" \"with \\\"quotes\\\"\"\n", response.ZoneString())
+}
+
+func TestServer_DigString(t *testing.T) {
+	server := mustServer(t, "A", "example.com.", godns.TypeA, "example.com. 3600 IN A 192.0.2.1")
+	assert.Equal(t, ";; Got answer:\n"+
+		";; ->>HEADER<<- opcode: QUERY, status: NOERROR, id: 1234\n"+
+		";; flags: qr rd ra; QUERY: 1, ANSWER: 1, AUTHORITY: 0, ADDITIONAL: 0\n"+
+		"\n"+
+		";; QUESTION SECTION:\n"+
+		";example.com.\tIN\t A\n"+
+		"\n"+
+		";; ANSWER SECTION:\n"+
+		"example.com.\t3600\tIN\tA\t192.0.2.1\n"+
+		"\n"+
+		";; Query time: 12 msec\n"+
+		";; SERVER: 1.1.1.1#53(1.1.1.1) (TCP)\n"+
+		";; MSG SIZE  rcvd: 56\n", server.DigString())
+
+	failed := Server{Server: "1.1.1.1", Error: "i/o timeout"}
+	assert.Equal(t, ";; connection to 1.1.1.1 failed: i/o timeout\n", failed.DigString())
+}
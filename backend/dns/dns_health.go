@@ -0,0 +1,730 @@
+package dns
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/krystal/krystal-network-tools/backend/asinfo"
+	godns "github.com/miekg/dns"
+	"go.uber.org/zap"
+)
+
+// HealthStatus is used to define the outcome of a health check.
+type HealthStatus string
+
+const (
+	// HealthPass means that nothing is wrong.
+	HealthPass HealthStatus = "pass"
+
+	// HealthWarn means that the zone works but does not follow best practice.
+	HealthWarn HealthStatus = "warn"
+
+	// HealthFail means that the zone is broken or partially broken.
+	HealthFail HealthStatus = "fail"
+)
+
+// HealthCheck is the result of a single check against a zone.
+type HealthCheck struct {
+	// Name is used to define the machine readable name of the check.
+	Name string `json:"name"`
+
+	// Status is used to define the outcome of the check.
+	Status HealthStatus `json:"status"`
+
+	// Message is a human readable explanation of the outcome.
+	Message string `json:"message"`
+
+	// Details contains any extra lines of information about the outcome.
+	Details []string `json:"details"`
+}
+
+// String returns the check in a human readable format.
+func (c HealthCheck) String() string {
+	str := "[" + strings.ToUpper(string(c.Status)) + "] " + c.Name + ": " + c.Message + "\n"
+	for _, v := range c.Details {
+		str += "    " + v + "\n"
+	}
+	return str
+}
+
+// HealthReport is the result of all the checks against a zone.
+type HealthReport struct {
+	// Zone is used to define the zone which was checked.
+	Zone string `json:"zone"`
+
+	// Checks is used to define the results of each check in the order they were ran.
+	Checks []HealthCheck `json:"checks"`
+}
+
+// String returns the report in a human readable format.
+func (r *HealthReport) String() string {
+	str := "--- " + r.Zone + " ---\n"
+	for _, c := range r.Checks {
+		str += c.String()
+	}
+	return str
+}
+
+func (r *HealthReport) add(name string, status HealthStatus, message string, details ...string) {
+	if details == nil {
+		details = []string{}
+	}
+	r.Checks = append(r.Checks, HealthCheck{
+		Name:    name,
+		Status:  status,
+		Message: message,
+		Details: details,
+	})
+}
+
+// delegation is the parent side view of a zone cut.
+type delegation struct {
+	// ParentServer is the nameserver which returned the referral.
+	ParentServer string
+
+	// Nameservers is the NS set given by the parent.
+	Nameservers []string
+
+	// Glue maps nameserver names to the addresses in the additional section of the referral.
+	Glue map[string][]net.IP
+}
+
+// Normalises a DNS name for comparisons.
+func normaliseName(name string) string {
+	return strings.ToLower(strings.TrimRight(name, "."))
+}
+
+// Returns if name is equal to or underneath zone.
+func inBailiwick(name, zone string) bool {
+	name = normaliseName(name)
+	zone = normaliseName(zone)
+	return name == zone || strings.HasSuffix(name, "."+zone)
+}
+
+// Follows referrals down from the root until the parent hands out the NS set for the zone.
+func findDelegation(log *zap.Logger, zone string) (*delegation, error) {
+	nameserver, _ := NextRootServer().Address()
+	for iteration := 0; iteration <= 10; iteration++ {
+		msg, err := rawQuery(
+			log, nameserverAddr(nameserver), godns.TypeNS, zone, queryOptions{NoRecursion: true, NoCache: true},
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query %s: %v", nameserver, err)
+		}
+
+		// Get the NS records from the referral (or the answer if the server is authoritative for both sides).
+		section := msg.Ns
+		if msg.Authoritative {
+			section = msg.Answer
+		}
+		owner := ""
+		nameservers := []string{}
+		for _, rr := range section {
+			if v, ok := rr.(*godns.NS); ok {
+				owner = normaliseName(v.Hdr.Name)
+				nameservers = append(nameservers, normaliseName(v.Ns))
+			}
+		}
+		if len(nameservers) == 0 {
+			if msg.Authoritative {
+				return nil, fmt.Errorf("%s is authoritative but has no delegation for the zone", nameserver)
+			}
+			return nil, errors.New("no answer or authoritive server provided in dns response")
+		}
+
+		// Collect the glue.
+		glue := map[string][]net.IP{}
+		for _, rr := range msg.Extra {
+			switch v := rr.(type) {
+			case *godns.A:
+				glue[normaliseName(v.Hdr.Name)] = append(glue[normaliseName(v.Hdr.Name)], v.A)
+			case *godns.AAAA:
+				glue[normaliseName(v.Hdr.Name)] = append(glue[normaliseName(v.Hdr.Name)], v.AAAA)
+			}
+		}
+
+		// If this is the delegation for our zone, we are done.
+		if owner == normaliseName(zone) {
+			return &delegation{
+				ParentServer: strings.TrimRight(nameserver, "."),
+				Nameservers:  nameservers,
+				Glue:         glue,
+			}, nil
+		}
+
+		// Follow the referral, using the glue where possible to avoid a lookup.
+		next := nameservers[rand.Intn(len(nameservers))]
+		if ips := glue[next]; len(ips) != 0 {
+			nameserver = ips[0].String()
+		} else {
+			nameserver = next
+		}
+	}
+	return nil, errors.New("nameserver search depth exceeded")
+}
+
+// Resolves the A and AAAA records of a name through the specified resolver.
+func resolveAddresses(log *zap.Logger, dnsServer, name string) ([]net.IP, error) {
+	ips := []net.IP{}
+	for _, t := range []uint16{godns.TypeA, godns.TypeAAAA} {
+		msg, err := rawQuery(log, nameserverAddr(dnsServer), t, godns.Fqdn(name), queryOptions{})
+		if err != nil {
+			return nil, err
+		}
+		for _, rr := range msg.Answer {
+			switch v := rr.(type) {
+			case *godns.A:
+				ips = append(ips, v.A)
+			case *godns.AAAA:
+				ips = append(ips, v.AAAA)
+			}
+		}
+	}
+	return ips, nil
+}
+
+// serverProbe is the result of querying a single address of an authoritative nameserver.
+type serverProbe struct {
+	// Name is the nameserver name.
+	Name string
+
+	// IP is the address that was queried.
+	IP net.IP
+
+	// Err is set if the server could not be queried.
+	Err error
+
+	// Authoritative is true if the server answered with the AA flag.
+	Authoritative bool
+
+	// Nameservers is the NS set that the server returned.
+	Nameservers []string
+
+	// SOA is the SOA record that the server returned.
+	SOA *godns.SOA
+
+	// Recursive is true if the server resolved a name outside of its zone for us.
+	Recursive bool
+}
+
+// Defines the names used to check if an authoritative server does recursion.
+var recursionProbeNames = []string{"a.root-servers.net.", "www.iana.org."}
+
+// Returns the nameserver name and address for display.
+func (p *serverProbe) label() string {
+	if p.IP == nil {
+		return p.Name
+	}
+	return p.Name + " (" + p.IP.String() + ")"
+}
+
+func probeServer(log *zap.Logger, zone, name string, ip net.IP) *serverProbe {
+	probe := &serverProbe{Name: name, IP: ip}
+	addr := nameserverAddr(ip.String())
+
+	// The report should reflect the servers as they are now, so the cache is not used.
+	opts := queryOptions{NoRecursion: true, NoCache: true}
+
+	// Check the NS set the server is authoritative for.
+	msg, err := rawQuery(log, addr, godns.TypeNS, zone, opts)
+	if err != nil {
+		probe.Err = err
+		return probe
+	}
+	if msg.Rcode != godns.RcodeSuccess {
+		probe.Err = fmt.Errorf("server responded with %s", godns.RcodeToString[msg.Rcode])
+		return probe
+	}
+	probe.Authoritative = msg.Authoritative
+	probe.Nameservers = []string{}
+	for _, rr := range msg.Answer {
+		if v, ok := rr.(*godns.NS); ok {
+			probe.Nameservers = append(probe.Nameservers, normaliseName(v.Ns))
+		}
+	}
+
+	// Get the SOA from the server.
+	msg, err = rawQuery(log, addr, godns.TypeSOA, zone, opts)
+	if err == nil {
+		for _, rr := range msg.Answer {
+			if v, ok := rr.(*godns.SOA); ok {
+				probe.SOA = v
+				break
+			}
+		}
+	}
+
+	// Check if the server will recurse for a name outside of the zone.
+	probeName := recursionProbeNames[0]
+	if inBailiwick(probeName, zone) {
+		probeName = recursionProbeNames[1]
+	}
+	msg, err = rawQuery(log, addr, godns.TypeA, probeName, queryOptions{NoCache: true})
+	if err == nil {
+		probe.Recursive = msg.RecursionAvailable && !msg.Authoritative &&
+			msg.Rcode == godns.RcodeSuccess && len(msg.Answer) != 0
+	}
+
+	return probe
+}
+
+// Returns a sorted copy of the items in a that are not in b.
+func stringsMissingFrom(a, b []string) []string {
+	m := map[string]bool{}
+	for _, v := range b {
+		m[v] = true
+	}
+	missing := []string{}
+	for _, v := range a {
+		if !m[v] {
+			missing = append(missing, v)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+// Checks that the SOA timers are within the ranges recommended by RFC 1912 and RIPE-203.
+func checkSOATimers(soa *godns.SOA) []string {
+	problems := []string{}
+	if soa.Refresh < 1200 || soa.Refresh > 43200 {
+		problems = append(problems, fmt.Sprintf(
+			"refresh of %d seconds is outside of the recommended 1200-43200 range", soa.Refresh))
+	}
+	if soa.Retry >= soa.Refresh {
+		problems = append(problems, fmt.Sprintf(
+			"retry of %d seconds should be lower than the refresh of %d seconds", soa.Retry, soa.Refresh))
+	}
+	if soa.Expire < 604800 || soa.Expire > 2419200 {
+		problems = append(problems, fmt.Sprintf(
+			"expire of %d seconds is outside of the recommended 604800-2419200 (1-4 weeks) range", soa.Expire))
+	}
+	if soa.Expire < soa.Refresh+soa.Retry {
+		problems = append(problems, fmt.Sprintf(
+			"expire of %d seconds should be larger than refresh plus retry", soa.Expire))
+	}
+	if soa.Minttl < 300 || soa.Minttl > 86400 {
+		problems = append(problems, fmt.Sprintf(
+			"negative caching TTL of %d seconds is outside of the recommended 300-86400 range", soa.Minttl))
+	}
+	return problems
+}
+
+// Gets the origin ASN of an IP address using the Team Cymru DNS service.
+func originASN(log *zap.Logger, dnsServer string, ip net.IP) (string, error) {
+	name := reverseName(ip)
+	if strings.HasSuffix(name, ".in-addr.arpa.") {
+		name = strings.TrimSuffix(name, "in-addr.arpa.") + "origin.asn.cymru.com."
+	} else {
+		name = strings.TrimSuffix(name, "ip6.arpa.") + "origin6.asn.cymru.com."
+	}
+	msg, err := rawQuery(log, nameserverAddr(dnsServer), godns.TypeTXT, name, queryOptions{})
+	if err != nil {
+		return "", err
+	}
+	for _, rr := range msg.Answer {
+		if v, ok := rr.(*godns.TXT); ok && len(v.Txt) != 0 {
+			// The format is "ASN | prefix | country | registry | date".
+			return strings.TrimSpace(strings.SplitN(v.Txt[0], "|", 2)[0]), nil
+		}
+	}
+	return "", errors.New("no origin found")
+}
+
+func (r *HealthReport) checkNameservers(d *delegation, probes []*serverProbe) {
+	// Check for lame servers.
+	lame := []string{}
+	for _, p := range probes {
+		switch {
+		case p.Err != nil:
+			lame = append(lame, fmt.Sprintf("%s: %v", p.label(), p.Err))
+		case !p.Authoritative:
+			lame = append(lame, p.label()+": answer is not authoritative")
+		}
+	}
+	if len(lame) == 0 {
+		r.add("lame_delegation", HealthPass, "All nameservers answer authoritatively for the zone.")
+	} else {
+		r.add("lame_delegation", HealthFail,
+			"Some nameservers are lame. Resolvers sent to them will fail or be delayed.", lame...)
+	}
+
+	// Check the parent and child NS sets are the same.
+	details := []string{}
+	for _, p := range probes {
+		if p.Err != nil || !p.Authoritative {
+			continue
+		}
+		for _, v := range stringsMissingFrom(d.Nameservers, p.Nameservers) {
+			details = append(details, v+" is in the parent but not in the zone on "+p.label())
+		}
+		for _, v := range stringsMissingFrom(p.Nameservers, d.Nameservers) {
+			details = append(details, v+" is in the zone on "+p.label()+" but not in the parent")
+		}
+	}
+	if len(details) == 0 {
+		r.add("ns_consistency", HealthPass, "The parent and child zones list the same nameservers.")
+	} else {
+		r.add("ns_consistency", HealthWarn,
+			"The parent and child zones list different nameservers. Resolvers may use either set.", details...)
+	}
+
+	// Check the recursion of the servers.
+	recursive := []string{}
+	for _, p := range probes {
+		if p.Recursive {
+			recursive = append(recursive, p.label())
+		}
+	}
+	if len(recursive) == 0 {
+		r.add("open_recursion", HealthPass, "No authoritative nameservers offer recursion.")
+	} else {
+		r.add("open_recursion", HealthFail, "Some authoritative nameservers are open resolvers. "+
+			"They can be used for cache poisoning and amplification attacks.", recursive...)
+	}
+}
+
+func (r *HealthReport) checkResolution(d *delegation, resolveErrs map[string]error) {
+	details := []string{}
+	for _, ns := range d.Nameservers {
+		if err := resolveErrs[ns]; err != nil {
+			details = append(details, fmt.Sprintf("%s: %v", ns, err))
+		}
+	}
+	if len(details) == 0 {
+		r.add("ns_addresses", HealthPass, "The addresses of all nameservers can be resolved.")
+	} else {
+		r.add("ns_addresses", HealthFail,
+			"The addresses of some nameservers could not be resolved. Resolvers may not be able to reach them.",
+			details...)
+	}
+}
+
+func (r *HealthReport) checkGlue(d *delegation, resolved map[string][]net.IP, resolveErrs map[string]error) {
+	details := []string{}
+	status := HealthPass
+	for _, ns := range d.Nameservers {
+		glue := d.Glue[ns]
+		if !inBailiwick(ns, r.Zone) {
+			continue
+		}
+		if len(glue) == 0 {
+			status = HealthFail
+			details = append(details, ns+" is inside the zone but the parent has no glue for it")
+			continue
+		}
+		if resolveErrs[ns] != nil {
+			if status == HealthPass {
+				status = HealthWarn
+			}
+			details = append(details, "the glue for "+ns+" could not be checked as its addresses could not be resolved")
+			continue
+		}
+		if len(resolved[ns]) == 0 {
+			status = HealthFail
+			details = append(details, ns+" has glue at the parent but no address records in the zone")
+			continue
+		}
+
+		// Check the glue matches the addresses in the zone.
+		zoneIps := map[string]bool{}
+		for _, ip := range resolved[ns] {
+			zoneIps[ip.String()] = true
+		}
+		for _, ip := range glue {
+			if !zoneIps[ip.String()] {
+				status = HealthFail
+				details = append(details, fmt.Sprintf(
+					"glue %s for %s does not match the address records of the nameserver", ip, ns))
+			}
+		}
+	}
+	switch status {
+	case HealthFail:
+		r.add("glue", HealthFail, "The glue records at the parent are missing or incorrect.", details...)
+	case HealthWarn:
+		r.add("glue", HealthWarn, "Some glue records at the parent could not be checked.", details...)
+	default:
+		r.add("glue", HealthPass, "All glue records at the parent are present and correct.")
+	}
+}
+
+func (r *HealthReport) checkSOA(probes []*serverProbe) {
+	serials := map[uint32][]string{}
+	var soa *godns.SOA
+	for _, p := range probes {
+		if p.SOA == nil {
+			continue
+		}
+		soa = p.SOA
+		serials[p.SOA.Serial] = append(serials[p.SOA.Serial], p.label())
+	}
+	if soa == nil {
+		r.add("soa", HealthFail, "No nameservers returned a SOA record for the zone.")
+		return
+	}
+
+	// Check the serials are consistent.
+	if len(serials) == 1 {
+		r.add("soa_serial", HealthPass, fmt.Sprintf("All nameservers have the serial %d.", soa.Serial))
+	} else {
+		details := []string{}
+		for serial, servers := range serials {
+			details = append(details, fmt.Sprintf("%d: %s", serial, strings.Join(servers, ", ")))
+		}
+		sort.Strings(details)
+		r.add("soa_serial", HealthWarn,
+			"The nameservers have different SOA serials. Zone transfers may be failing.", details...)
+	}
+
+	// Check the timers.
+	if problems := checkSOATimers(soa); len(problems) == 0 {
+		r.add("soa_timers", HealthPass, "The SOA timers are within the recommended ranges.")
+	} else {
+		r.add("soa_timers", HealthWarn, "Some SOA timers are outside of the recommended ranges.", problems...)
+	}
+}
+
+func (r *HealthReport) checkDiversity(
+	log *zap.Logger, dnsServer string, addresses map[string][]net.IP, asNames *asinfo.Resolver,
+) {
+	prefixes := map[string]bool{}
+	asns := map[string]bool{}
+	for _, ips := range addresses {
+		for _, ip := range ips {
+			if v4 := ip.To4(); v4 != nil {
+				prefixes[v4.Mask(net.CIDRMask(24, 32)).String()+"/24"] = true
+			} else {
+				prefixes[ip.Mask(net.CIDRMask(48, 128)).String()+"/48"] = true
+			}
+			if asn, err := originASN(log, dnsServer, ip); err == nil {
+				n, err := strconv.ParseUint(asn, 10, 32)
+				if asNames != nil && err == nil {
+					asns[asNames.Describe(uint32(n))] = true
+				} else {
+					asns["AS"+asn] = true
+				}
+			}
+		}
+	}
+
+	// Check the prefixes.
+	if len(prefixes) > 1 {
+		r.add("network_prefix", HealthPass, fmt.Sprintf("The nameservers are spread across %d networks.", len(prefixes)))
+	} else {
+		r.add("network_prefix", HealthWarn,
+			"All nameservers are within a single network. An outage of that network will take down the zone.",
+			mapKeys(prefixes)...)
+	}
+
+	// Check the ASNs.
+	switch {
+	case len(asns) == 0:
+		r.add("network_asn", HealthWarn, "The origin ASNs of the nameservers could not be determined.")
+	case len(asns) == 1:
+		r.add("network_asn", HealthWarn,
+			"All nameservers are within a single ASN. A routing problem in it will take down the zone.",
+			mapKeys(asns)...)
+	default:
+		r.add("network_asn", HealthPass, "The nameservers are spread across multiple ASNs.", mapKeys(asns)...)
+	}
+}
+
+func (r *HealthReport) checkCNAMEAndMX(log *zap.Logger, dnsServer string, d *delegation) {
+	zone := godns.Fqdn(r.Zone)
+	addr := nameserverAddr(dnsServer)
+
+	// Check that there is no CNAME at the apex and that no nameserver is a CNAME.
+	details := []string{}
+	msg, err := rawQuery(log, addr, godns.TypeCNAME, zone, queryOptions{})
+	if err == nil && len(msg.Answer) != 0 {
+		details = append(details, "the zone apex is a CNAME, which is not allowed alongside the SOA and NS records")
+	}
+	for _, ns := range d.Nameservers {
+		msg, err := rawQuery(log, addr, godns.TypeCNAME, godns.Fqdn(ns), queryOptions{})
+		if err == nil && len(msg.Answer) != 0 {
+			details = append(details, "nameserver "+ns+" is a CNAME, which is not allowed by RFC 2181")
+		}
+	}
+	if len(details) == 0 {
+		r.add("cname", HealthPass, "No CNAME records are used where they are not allowed.")
+	} else {
+		r.add("cname", HealthFail, "CNAME records are used where they are not allowed.", details...)
+	}
+
+	// Check the MX records.
+	msg, err = rawQuery(log, addr, godns.TypeMX, zone, queryOptions{})
+	if err != nil {
+		r.add("mx", HealthWarn, fmt.Sprintf("The MX records could not be looked up: %v", err))
+		return
+	}
+	mxs := []*godns.MX{}
+	for _, rr := range msg.Answer {
+		if v, ok := rr.(*godns.MX); ok {
+			mxs = append(mxs, v)
+		}
+	}
+	if len(mxs) == 0 {
+		r.add("mx", HealthPass, "The zone has no MX records. Mail will be delivered to the address of the apex.")
+		return
+	}
+	details = []string{}
+	for _, mx := range mxs {
+		target := normaliseName(mx.Mx)
+		if target == "" {
+			if len(mxs) != 1 {
+				details = append(details, "a null MX is published alongside other MX records")
+			}
+			continue
+		}
+		if net.ParseIP(target) != nil {
+			details = append(details, target+" is an IP address but MX records must point to a hostname")
+			continue
+		}
+		cname, err := rawQuery(log, addr, godns.TypeCNAME, godns.Fqdn(target), queryOptions{})
+		if err == nil && len(cname.Answer) != 0 {
+			details = append(details, target+" is a CNAME, which is not allowed for MX targets by RFC 2181")
+			continue
+		}
+		if ips, err := resolveAddresses(log, dnsServer, target); err == nil && len(ips) == 0 {
+			details = append(details, target+" has no A or AAAA records")
+		}
+	}
+	if len(details) == 0 {
+		r.add("mx", HealthPass, "All MX records point to hostnames with address records.")
+	} else {
+		r.add("mx", HealthFail, "Some MX records are invalid. Mail to the zone may not be delivered.", details...)
+	}
+}
+
+// Returns the sorted keys of a map.
+func mapKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Resolves the addresses of every nameserver. The addresses and errors are returned in separate maps keyed by the
+// nameserver, so one broken nameserver does not stop the others being checked.
+func resolveNameservers(
+	d *delegation, resolve func(ns string) ([]net.IP, error),
+) (map[string][]net.IP, map[string]error) {
+	resolved := map[string][]net.IP{}
+	resolveErrs := map[string]error{}
+	for _, ns := range d.Nameservers {
+		ips, err := resolve(ns)
+		if err != nil {
+			resolveErrs[ns] = err
+			continue
+		}
+		resolved[ns] = ips
+	}
+	return resolved, resolveErrs
+}
+
+// Returns the addresses to probe for each nameserver. These are the resolved addresses, or the glue if there are
+// none, so nameservers which only work through their glue can still be probed.
+func probeAddresses(d *delegation, resolved map[string][]net.IP) map[string][]net.IP {
+	addresses := map[string][]net.IP{}
+	for _, ns := range d.Nameservers {
+		addresses[ns] = resolved[ns]
+		if len(addresses[ns]) == 0 {
+			addresses[ns] = d.Glue[ns]
+		}
+	}
+	return addresses
+}
+
+// Probes every address of every nameserver at the same time. Each address has its own slot in the result so the
+// probes can be written without a lock, and nameservers without addresses get a probe with an error, which is the
+// resolution error if there was one. The result is sorted by the name and then the address.
+func probeNameservers(
+	d *delegation, addresses map[string][]net.IP, resolveErrs map[string]error,
+	probe func(ns string, ip net.IP) *serverProbe,
+) []*serverProbe {
+	n := 0
+	for _, ns := range d.Nameservers {
+		if len(addresses[ns]) == 0 {
+			n++
+		} else {
+			n += len(addresses[ns])
+		}
+	}
+	probes := make([]*serverProbe, n)
+	i := 0
+	wg := sync.WaitGroup{}
+	for _, ns := range d.Nameservers {
+		if len(addresses[ns]) == 0 {
+			err := errors.New("nameserver has no addresses")
+			if resolveErr := resolveErrs[ns]; resolveErr != nil {
+				err = fmt.Errorf("failed to resolve the addresses of the nameserver: %v", resolveErr)
+			}
+			probes[i] = &serverProbe{Name: ns, Err: err}
+			i++
+			continue
+		}
+		for _, ip := range addresses[ns] {
+			wg.Add(1)
+			go func(slot int, ns string, ip net.IP) {
+				defer wg.Done()
+				probes[slot] = probe(ns, ip)
+			}(i, ns, ip)
+			i++
+		}
+	}
+	wg.Wait()
+	sort.Slice(probes, func(i, j int) bool {
+		if probes[i].Name != probes[j].Name {
+			return probes[i].Name < probes[j].Name
+		}
+		return probes[i].IP.String() < probes[j].IP.String()
+	})
+	return probes
+}
+
+// CheckZoneHealth runs checks against the delegation and nameservers of a zone. The DNS server is used to
+// resolve anything which is not part of the delegation. If the AS name resolver isn't nil, it is used to name the
+// networks the nameservers are in.
+func CheckZoneHealth(log *zap.Logger, dnsServer, zone string, asNames *asinfo.Resolver) (*HealthReport, error) {
+	zone = godns.Fqdn(strings.ToLower(zone))
+	report := &HealthReport{Zone: strings.TrimRight(zone, "."), Checks: []HealthCheck{}}
+
+	// Get the delegation from the parent.
+	d, err := findDelegation(log, zone)
+	if err != nil {
+		report.add("delegation", HealthFail, "The parent zone does not delegate this zone.", err.Error())
+		return report, nil
+	}
+	report.add("delegation", HealthPass,
+		fmt.Sprintf("The parent zone delegates to %d nameservers.", len(d.Nameservers)),
+		"parent server: "+d.ParentServer)
+
+	// Resolve the addresses of all of the nameservers.
+	resolved, resolveErrs := resolveNameservers(d, func(ns string) ([]net.IP, error) {
+		return resolveAddresses(log, dnsServer, ns)
+	})
+	addresses := probeAddresses(d, resolved)
+
+	// Probe every address of every nameserver.
+	probes := probeNameservers(d, addresses, resolveErrs, func(ns string, ip net.IP) *serverProbe {
+		return probeServer(log, zone, ns, ip)
+	})
+
+	// Run all of the checks.
+	report.checkResolution(d, resolveErrs)
+	report.checkNameservers(d, probes)
+	report.checkGlue(d, resolved, resolveErrs)
+	report.checkSOA(probes)
+	report.checkDiversity(log, dnsServer, addresses, asNames)
+	report.checkCNAMEAndMX(log, dnsServer, d)
+	return report, nil
+}
@@ -0,0 +1,218 @@
+package dns
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	godns "github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_checkSOATimers(t *testing.T) {
+	tests := []struct {
+		name string
+
+		soa      godns.SOA
+		problems int
+	}{
+		{
+			name:     "recommended values",
+			soa:      godns.SOA{Refresh: 43200, Retry: 7200, Expire: 1209600, Minttl: 3600},
+			problems: 0,
+		},
+		{
+			name:     "refresh too low",
+			soa:      godns.SOA{Refresh: 60, Retry: 30, Expire: 1209600, Minttl: 3600},
+			problems: 1,
+		},
+		{
+			name:     "retry larger than refresh",
+			soa:      godns.SOA{Refresh: 3600, Retry: 7200, Expire: 1209600, Minttl: 3600},
+			problems: 1,
+		},
+		{
+			name:     "everything wrong",
+			soa:      godns.SOA{Refresh: 100000, Retry: 200000, Expire: 3600, Minttl: 0},
+			problems: 5,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Len(t, checkSOATimers(&tt.soa), tt.problems)
+		})
+	}
+}
+
+func Test_inBailiwick(t *testing.T) {
+	assert.True(t, inBailiwick("ns1.example.com.", "example.com"))
+	assert.True(t, inBailiwick("EXAMPLE.com", "example.com."))
+	assert.False(t, inBailiwick("ns1.notexample.com", "example.com"))
+	assert.False(t, inBailiwick("ns1.example.net", "example.com"))
+}
+
+func Test_probeNameservers(t *testing.T) {
+	soa := func(serial uint32) *godns.SOA {
+		return &godns.SOA{Serial: serial, Refresh: 43200, Retry: 7200, Expire: 1209600, Minttl: 3600}
+	}
+	tests := []struct {
+		name string
+
+		nameservers []string
+		addresses   map[string][]net.IP
+		resolveErrs map[string]error
+		glue        map[string][]net.IP
+		serials     map[string]uint32
+		failing     map[string]bool
+		labels      []string
+		statuses    map[string]HealthStatus
+	}{
+		{
+			name:        "healthy",
+			nameservers: []string{"ns2.example.net", "ns1.example.net"},
+			addresses: map[string][]net.IP{
+				"ns1.example.net": {net.ParseIP("192.0.2.2"), net.ParseIP("192.0.2.1")},
+				"ns2.example.net": {net.ParseIP("198.51.100.1")},
+			},
+			labels: []string{
+				"ns1.example.net (192.0.2.1)", "ns1.example.net (192.0.2.2)", "ns2.example.net (198.51.100.1)",
+			},
+			statuses: map[string]HealthStatus{
+				"lame_delegation": HealthPass,
+				"ns_consistency":  HealthPass,
+				"glue":            HealthPass,
+				"soa_serial":      HealthPass,
+			},
+		},
+		{
+			name:        "nameserver without addresses",
+			nameservers: []string{"ns1.example.net", "ns2.example.net"},
+			addresses: map[string][]net.IP{
+				"ns1.example.net": {net.ParseIP("192.0.2.1")},
+			},
+			labels: []string{"ns1.example.net (192.0.2.1)", "ns2.example.net"},
+			statuses: map[string]HealthStatus{
+				"lame_delegation": HealthFail,
+				"ns_consistency":  HealthPass,
+				"soa_serial":      HealthPass,
+			},
+		},
+		{
+			name:        "in zone nameserver without addresses or glue",
+			nameservers: []string{"ns1.example.com", "ns2.example.net"},
+			addresses: map[string][]net.IP{
+				"ns2.example.net": {net.ParseIP("198.51.100.1")},
+			},
+			labels: []string{"ns1.example.com", "ns2.example.net (198.51.100.1)"},
+			statuses: map[string]HealthStatus{
+				"lame_delegation": HealthFail,
+				"glue":            HealthFail,
+			},
+		},
+		{
+			name:        "nameserver which fails to resolve",
+			nameservers: []string{"ns1.example.net", "ns2.example.net"},
+			addresses: map[string][]net.IP{
+				"ns1.example.net": {net.ParseIP("192.0.2.1")},
+			},
+			resolveErrs: map[string]error{"ns2.example.net": errors.New("server responded with SERVFAIL")},
+			labels:      []string{"ns1.example.net (192.0.2.1)", "ns2.example.net"},
+			statuses: map[string]HealthStatus{
+				"ns_addresses":    HealthFail,
+				"lame_delegation": HealthFail,
+				"glue":            HealthPass,
+				"soa_serial":      HealthPass,
+			},
+		},
+		{
+			name:        "in zone nameserver with glue but no address records",
+			nameservers: []string{"ns1.example.com", "ns2.example.net"},
+			addresses: map[string][]net.IP{
+				"ns2.example.net": {net.ParseIP("198.51.100.1")},
+			},
+			glue:   map[string][]net.IP{"ns1.example.com": {net.ParseIP("192.0.2.1")}},
+			labels: []string{"ns1.example.com (192.0.2.1)", "ns2.example.net (198.51.100.1)"},
+			statuses: map[string]HealthStatus{
+				"ns_addresses":    HealthPass,
+				"lame_delegation": HealthPass,
+				"glue":            HealthFail,
+			},
+		},
+		{
+			name:        "in zone nameserver with glue which fails to resolve",
+			nameservers: []string{"ns1.example.com", "ns2.example.net"},
+			addresses: map[string][]net.IP{
+				"ns2.example.net": {net.ParseIP("198.51.100.1")},
+			},
+			resolveErrs: map[string]error{"ns1.example.com": errors.New("i/o timeout")},
+			glue:        map[string][]net.IP{"ns1.example.com": {net.ParseIP("192.0.2.1")}},
+			labels:      []string{"ns1.example.com (192.0.2.1)", "ns2.example.net (198.51.100.1)"},
+			statuses: map[string]HealthStatus{
+				"ns_addresses":    HealthFail,
+				"lame_delegation": HealthPass,
+				"glue":            HealthWarn,
+			},
+		},
+		{
+			name:        "differing serials and a failing server",
+			nameservers: []string{"ns1.example.net", "ns2.example.net", "ns3.example.net"},
+			addresses: map[string][]net.IP{
+				"ns1.example.net": {net.ParseIP("192.0.2.1")},
+				"ns2.example.net": {net.ParseIP("192.0.2.2")},
+				"ns3.example.net": {net.ParseIP("192.0.2.3")},
+			},
+			serials: map[string]uint32{"ns2.example.net": 2},
+			failing: map[string]bool{"ns3.example.net": true},
+			labels: []string{
+				"ns1.example.net (192.0.2.1)", "ns2.example.net (192.0.2.2)", "ns3.example.net (192.0.2.3)",
+			},
+			statuses: map[string]HealthStatus{
+				"lame_delegation": HealthFail,
+				"soa_serial":      HealthWarn,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &delegation{Nameservers: tt.nameservers, Glue: tt.glue}
+			resolved, resolveErrs := resolveNameservers(d, func(ns string) ([]net.IP, error) {
+				if err := tt.resolveErrs[ns]; err != nil {
+					return nil, err
+				}
+				return tt.addresses[ns], nil
+			})
+			addresses := probeAddresses(d, resolved)
+			probes := probeNameservers(d, addresses, resolveErrs, func(ns string, ip net.IP) *serverProbe {
+				if tt.failing[ns] {
+					return &serverProbe{Name: ns, IP: ip, Err: errors.New("i/o timeout")}
+				}
+				serial := uint32(1)
+				if v, ok := tt.serials[ns]; ok {
+					serial = v
+				}
+				return &serverProbe{
+					Name: ns, IP: ip, Authoritative: true, Nameservers: tt.nameservers, SOA: soa(serial),
+				}
+			})
+			labels := make([]string, len(probes))
+			for i, p := range probes {
+				labels[i] = p.label()
+			}
+			assert.Equal(t, tt.labels, labels)
+
+			// Assemble the report from the probes.
+			report := &HealthReport{Zone: "example.com", Checks: []HealthCheck{}}
+			report.checkResolution(d, resolveErrs)
+			report.checkNameservers(d, probes)
+			report.checkGlue(d, resolved, resolveErrs)
+			report.checkSOA(probes)
+			statuses := map[string]HealthStatus{}
+			for _, c := range report.Checks {
+				statuses[c.Name] = c.Status
+			}
+			for name, status := range tt.statuses {
+				assert.Equal(t, status, statuses[name], name)
+			}
+		})
+	}
+}
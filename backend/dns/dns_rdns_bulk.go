@@ -0,0 +1,234 @@
+package dns
+
+import (
+	"errors"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	godns "github.com/miekg/dns"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+)
+
+// Defines the maximum number of addresses that can be looked up in one bulk lookup.
+const maxBulkRDNSAddresses = 256
+
+// Defines the maximum number of addresses that will be looked up at once in a bulk lookup.
+const maxBulkRDNSConcurrency = 16
+
+// BulkRDNSEntry is the reverse DNS of a single address in a bulk lookup.
+type BulkRDNSEntry struct {
+	// IP is used to define the address.
+	IP string `json:"ip"`
+
+	// Rcode is used to define the response code of the PTR lookup.
+	Rcode string `json:"rcode,omitempty"`
+
+	// Hostnames is used to define the hostnames in the PTR records.
+	Hostnames []string `json:"hostnames"`
+
+	// TTL is used to define the time to live of the PTR records.
+	TTL uint32 `json:"ttl"`
+
+	// CNAME is used to define where the reverse name is aliased to. This is how RFC 2317 classless delegation works.
+	CNAME string `json:"cname,omitempty"`
+
+	// Zone is used to define the reverse zone which holds the PTR records.
+	Zone string `json:"zone,omitempty"`
+
+	// Error is set if the lookup failed.
+	Error string `json:"error,omitempty"`
+}
+
+// ReverseZone is a reverse zone which holds PTR records for some of the addresses in a bulk lookup.
+type ReverseZone struct {
+	// Zone is used to define the apex of the zone.
+	Zone string `json:"zone"`
+
+	// Classless is true if the zone is an RFC 2317 classless delegation that the addresses are aliased into.
+	Classless bool `json:"classless"`
+
+	// Addresses is used to define how many of the addresses are in the zone.
+	Addresses int `json:"addresses"`
+}
+
+// BulkRDNSResult is the result of a bulk reverse DNS lookup.
+type BulkRDNSResult struct {
+	// Prefix is used to define the prefix which was looked up. This is blank when a list was given.
+	Prefix string `json:"prefix,omitempty"`
+
+	// Zones is used to define the reverse zones the addresses are delegated to.
+	Zones []ReverseZone `json:"zones"`
+
+	// Entries is used to define the result for each address in the order they were given.
+	Entries []*BulkRDNSEntry `json:"entries"`
+}
+
+// String returns the result in a human readable format.
+func (r *BulkRDNSResult) String() string {
+	str := "---"
+	if r.Prefix != "" {
+		str += " " + r.Prefix
+	}
+	str += " ---\n"
+	for _, z := range r.Zones {
+		str += ";; zone " + z.Zone + " ("
+		if z.Classless {
+			str += "RFC 2317 classless delegation, "
+		}
+		str += strconv.Itoa(z.Addresses) + " addresses)\n"
+	}
+	for _, e := range r.Entries {
+		switch {
+		case e.Error != "":
+			str += e.IP + " ;; error: " + e.Error + "\n"
+		case len(e.Hostnames) == 0:
+			str += e.IP + " ;; " + e.Rcode + "\n"
+		default:
+			for _, h := range e.Hostnames {
+				str += e.IP + " " + strconv.FormatUint(uint64(e.TTL), 10) + " PTR " + h + ".\n"
+			}
+		}
+	}
+	return str
+}
+
+// ExpandIPv4Prefix returns every address in an IPv4 prefix. Only prefixes of /24 or smaller are allowed.
+func ExpandIPv4Prefix(prefix *net.IPNet) ([]net.IP, error) {
+	ip := prefix.IP.To4()
+	ones, bits := prefix.Mask.Size()
+	if ip == nil || bits != 32 {
+		return nil, errors.New("prefix must be IPv4")
+	}
+	if ones < 24 {
+		return nil, errors.New("prefix must be /24 or smaller")
+	}
+
+	ips := make([]net.IP, 1<<(32-ones))
+	for i := range ips {
+		addr := make(net.IP, 4)
+		copy(addr, ip)
+		addr[3] += byte(i)
+		ips[i] = addr
+	}
+	return ips, nil
+}
+
+// Looks up the PTR records of a single address.
+func lookupBulkEntry(log *zap.Logger, dnsServer string, ip net.IP, opts queryOptions) *BulkRDNSEntry {
+	entry := &BulkRDNSEntry{IP: ip.String(), Hostnames: []string{}}
+	hostname := reverseName(ip)
+	msg, err := rawQuery(log, nameserverAddr(dnsServer), godns.TypePTR, hostname, opts)
+	if err != nil {
+		entry.Error = err.Error()
+		return entry
+	}
+	entry.Rcode = godns.RcodeToString[msg.Rcode]
+	for _, rr := range msg.Answer {
+		switch v := rr.(type) {
+		case *godns.CNAME:
+			if normaliseName(v.Hdr.Name) == normaliseName(hostname) {
+				entry.CNAME = normaliseName(v.Target)
+			}
+		case *godns.PTR:
+			entry.Hostnames = append(entry.Hostnames, normaliseName(v.Ptr))
+			entry.TTL = v.Hdr.Ttl
+		}
+	}
+	return entry
+}
+
+// Returns the name whose zone should be found for an entry. All the addresses in the same reverse /24 (or nibble
+// for IPv6) share a zone unless they are aliased elsewhere, so the parent of the name is used to save queries.
+func zoneLookupName(ip net.IP, entry *BulkRDNSEntry) string {
+	name := reverseName(ip)
+	if entry.CNAME != "" {
+		name = entry.CNAME + "."
+	}
+	if split := strings.SplitN(name, ".", 2); len(split) == 2 && split[1] != "" {
+		return split[1]
+	}
+	return name
+}
+
+// LookupRDNSBulk looks up the PTR records of every address given with bounded concurrency, and finds the
+// reverse zones that the addresses are delegated to with the same bound.
+func LookupRDNSBulk(
+	log *zap.Logger, dnsServer, prefix string, ips []net.IP, opts LookupOptions,
+) (*BulkRDNSResult, error) {
+	if len(ips) == 0 {
+		return nil, errors.New("no addresses were given")
+	}
+	if len(ips) > maxBulkRDNSAddresses {
+		return nil, errors.New("at most " + strconv.Itoa(maxBulkRDNSAddresses) + " addresses can be looked up")
+	}
+	queryOpts, err := opts.queryOptions()
+	if err != nil {
+		return nil, err
+	}
+
+	// Look up every address.
+	result := &BulkRDNSResult{Prefix: prefix, Zones: []ReverseZone{}, Entries: make([]*BulkRDNSEntry, len(ips))}
+	sem := make(chan struct{}, maxBulkRDNSConcurrency)
+	eg := errgroup.Group{}
+	for i, ip := range ips {
+		i, ip := i, ip
+		eg.Go(func() error {
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			result.Entries[i] = lookupBulkEntry(log, dnsServer, ip, queryOpts)
+			return nil
+		})
+	}
+	_ = eg.Wait()
+
+	// Find the zones the addresses are in.
+	names := map[string]bool{}
+	for i, entry := range result.Entries {
+		if entry.Error == "" {
+			names[zoneLookupName(ips[i], entry)] = true
+		}
+	}
+	zones := map[string]string{}
+	zonesLock := sync.Mutex{}
+	for _, name := range mapKeys(names) {
+		name := name
+		eg.Go(func() error {
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			zone, err := findZoneApex(log, dnsServer, name, queryOpts)
+			if err != nil {
+				log.Warn("failed to find reverse zone", zap.String("name", name), zap.Error(err))
+				return nil
+			}
+			zonesLock.Lock()
+			zones[name] = zone
+			zonesLock.Unlock()
+			return nil
+		})
+	}
+	_ = eg.Wait()
+	counts := map[string]*ReverseZone{}
+	for i, entry := range result.Entries {
+		zone, ok := zones[zoneLookupName(ips[i], entry)]
+		if entry.Error != "" || !ok {
+			continue
+		}
+		entry.Zone = zone
+		if counts[zone] == nil {
+			counts[zone] = &ReverseZone{Zone: zone, Classless: entry.CNAME != ""}
+		}
+		counts[zone].Addresses++
+	}
+	for _, v := range counts {
+		result.Zones = append(result.Zones, *v)
+	}
+	sort.Slice(result.Zones, func(i, j int) bool {
+		return result.Zones[i].Zone < result.Zones[j].Zone
+	})
+
+	return result, nil
+}
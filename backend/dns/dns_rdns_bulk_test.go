@@ -0,0 +1,75 @@
+package dns
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpandIPv4Prefix(t *testing.T) {
+	tests := []struct {
+		name string
+
+		prefix  string
+		first   string
+		last    string
+		count   int
+		wantErr string
+	}{
+		{
+			name:   "/24",
+			prefix: "192.0.2.0/24",
+			first:  "192.0.2.0",
+			last:   "192.0.2.255",
+			count:  256,
+		},
+		{
+			name:   "/26",
+			prefix: "192.0.2.64/26",
+			first:  "192.0.2.64",
+			last:   "192.0.2.127",
+			count:  64,
+		},
+		{
+			name:   "/32",
+			prefix: "192.0.2.1/32",
+			first:  "192.0.2.1",
+			last:   "192.0.2.1",
+			count:  1,
+		},
+		{
+			name:    "too large",
+			prefix:  "192.0.0.0/23",
+			wantErr: "prefix must be /24 or smaller",
+		},
+		{
+			name:    "ipv6",
+			prefix:  "2001:db8::/120",
+			wantErr: "prefix must be IPv4",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, ipNet, err := net.ParseCIDR(tt.prefix)
+			assert.NoError(t, err)
+			ips, err := ExpandIPv4Prefix(ipNet)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Len(t, ips, tt.count)
+			assert.Equal(t, tt.first, ips[0].String())
+			assert.Equal(t, tt.last, ips[len(ips)-1].String())
+		})
+	}
+}
+
+func Test_zoneLookupName(t *testing.T) {
+	ip := net.ParseIP("192.0.2.1")
+	assert.Equal(t, "2.0.192.in-addr.arpa.", zoneLookupName(ip, &BulkRDNSEntry{}))
+	assert.Equal(t, "0-25.2.0.192.in-addr.arpa.", zoneLookupName(ip, &BulkRDNSEntry{
+		CNAME: "1.0-25.2.0.192.in-addr.arpa",
+	}))
+}
@@ -9,11 +9,11 @@ import (
 	"reflect"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/gobeam/stringy"
 	godns "github.com/miekg/dns"
 	"go.uber.org/zap"
-	"golang.org/x/sync/errgroup"
 )
 
 // Used to clean the case of things in a value for JSON and remove unwanted keys.
@@ -80,18 +80,166 @@ func (rt RecordType) String() string {
 	return str
 }
 
+// Flags is used to define the header flags of a DNS response.
+type Flags struct {
+	// Authoritative is true if the server is authoritative for the answer.
+	Authoritative bool `json:"aa"`
+
+	// Truncated is true if the response was too large and was truncated.
+	Truncated bool `json:"tc"`
+
+	// RecursionDesired is copied from the query.
+	RecursionDesired bool `json:"rd"`
+
+	// RecursionAvailable is true if the server offers recursion.
+	RecursionAvailable bool `json:"ra"`
+
+	// AuthenticatedData is true if the server validated the answer with DNSSEC.
+	AuthenticatedData bool `json:"ad"`
+
+	// CheckingDisabled is copied from the query.
+	CheckingDisabled bool `json:"cd"`
+}
+
+// String returns the flags in the same format as dig.
+func (f Flags) String() string {
+	flags := []string{"qr"}
+	for _, v := range []struct {
+		set  bool
+		name string
+	}{
+		{f.Authoritative, "aa"},
+		{f.Truncated, "tc"},
+		{f.RecursionDesired, "rd"},
+		{f.RecursionAvailable, "ra"},
+		{f.AuthenticatedData, "ad"},
+		{f.CheckingDisabled, "cd"},
+	} {
+		if v.set {
+			flags = append(flags, v.name)
+		}
+	}
+	return strings.Join(flags, " ")
+}
+
 type Server struct {
-	Server  string   `json:"server"`
+	Server string `json:"server"`
+
+	// Address is used to define the exact address which was queried.
+	Address string `json:"address"`
+
+	// Rcode is used to define the response code such as NOERROR, NXDOMAIN, SERVFAIL or REFUSED.
+	Rcode string `json:"rcode"`
+
+	// Flags is used to define the header flags of the response.
+	Flags Flags `json:"flags"`
+
+	// RTT is used to define the round trip time of the query in milliseconds.
+	RTT float64 `json:"rtt"`
+
+	// Cached is true if the response was served from the cache rather than the server.
+	Cached bool `json:"cached"`
+
+	// ClientSubnet is used to define the EDNS Client Subnet the server replied with, if any.
+	ClientSubnet *ClientSubnet `json:"client_subnet,omitempty"`
+
 	Records []Record `json:"records"`
+
+	// Additional is used to define the records in the additional section, such as glue.
+	Additional []Record `json:"additional"`
+
+	// Hop is used to label which name in a CNAME chain the server was queried for. This is only set in traces.
+	Hop string `json:"hop,omitempty"`
+
+	// Root is used to define which root server was used. This is only set on the first server of a trace.
+	Root *RootInfo `json:"root,omitempty"`
+
+	// QueryType is used to define the record type which was queried when looking up several types at once.
+	QueryType string `json:"query_type,omitempty"`
+
+	// Error is set if the query to the server failed. The other results of the lookup are still returned.
+	Error string `json:"error,omitempty"`
+
+	// The raw response from the server. This is used for the dig output format.
+	msg *godns.Msg
+}
+
+// Creates a server from the metadata of a query result. The caller is expected to fill in the records.
+func newServer(nameserver string, result *queryResult) (Server, error) {
+	server := Server{
+		Server:  nameserver,
+		Address: result.Address,
+		Rcode:   godns.RcodeToString[result.Rcode],
+		Flags: Flags{
+			Authoritative:      result.Authoritative,
+			Truncated:          result.Truncated,
+			RecursionDesired:   result.RecursionDesired,
+			RecursionAvailable: result.RecursionAvailable,
+			AuthenticatedData:  result.AuthenticatedData,
+			CheckingDisabled:   result.CheckingDisabled,
+		},
+		RTT:          float64(result.RTT.Microseconds()) / 1000,
+		Cached:       result.Cached,
+		ClientSubnet: clientSubnetFromMsg(result.Msg),
+		Records:      []Record{},
+		Additional:   []Record{},
+		msg:          result.Msg,
+	}
+	for _, rr := range result.Extra {
+		// The OPT pseudo-record is part of the protocol rather than the data.
+		if rr.Header().Rrtype == godns.TypeOPT {
+			continue
+		}
+		record, err := recordFromAnswer(rr)
+		if err != nil {
+			return Server{}, err
+		}
+		server.Additional = append(server.Additional, record)
+	}
+	return server, nil
 }
 
 func (srv Server) String() string {
-	str := "-- " + srv.Server + " --\n"
+	str := "-- " + srv.Server
+	if srv.Address != "" {
+		str += " [" + srv.Address + "]"
+	}
+	if srv.Hop != "" {
+		str += " (" + srv.Hop + ")"
+	}
+	if srv.Root != nil {
+		str += " (" + srv.Root.Letter + "-root over " + srv.Root.Family + ")"
+	}
+	if srv.QueryType != "" {
+		str += " " + srv.QueryType
+	}
+	str += " --\n"
+	if srv.Error != "" {
+		str += ";; error: " + srv.Error + "\n"
+	}
+	if srv.Rcode != "" {
+		str += fmt.Sprintf(";; status: %s, flags: %s, query time: %.3f ms", srv.Rcode, srv.Flags, srv.RTT)
+		if srv.Cached {
+			str += " (cached)"
+		}
+		if srv.ClientSubnet != nil {
+			str += ", client subnet: " + srv.ClientSubnet.String()
+		}
+		str += "\n"
+	}
 	for _, record := range srv.Records {
 		if record.stringer != nil {
 			str += record.stringer() + "\n"
 		}
 	}
+	if len(srv.Additional) != 0 {
+		str += ";; ADDITIONAL:\n"
+		for _, record := range srv.Additional {
+			if record.stringer != nil {
+				str += record.stringer() + "\n"
+			}
+		}
+	}
 
 	return str
 }
@@ -114,20 +262,69 @@ type Record struct {
 
 	// A function returning a string version of this record.
 	stringer func() string
+
+	// The presentation format of the record data without the header.
+	data string
+}
+
+// Defines how long a single DNS exchange is allowed to take.
+const queryTimeout = 5 * time.Second
+
+// queryOptions is used to change how a raw query is sent.
+type queryOptions struct {
+	// NoRecursion clears the recursion desired flag on the query.
+	NoRecursion bool
+
+	// NoCache sends the query even if there is a cached response. The response is still cached.
+	NoCache bool
+
+	// ClientSubnet is the normalised prefix to send in the EDNS Client Subnet option, if any.
+	ClientSubnet string
+}
+
+// nameserverAddr returns the address used to connect to port 53 on a nameserver.
+func nameserverAddr(nameserver string) string {
+	return net.JoinHostPort(nameserver, "53")
+}
+
+// queryResult is a response from a nameserver along with information about the exchange.
+type queryResult struct {
+	*godns.Msg
+
+	// Address is used to define the address of the nameserver which answered.
+	Address string
+
+	// RTT is used to define how long the exchange took.
+	RTT time.Duration
+
+	// Cached is true if the response came from the cache.
+	Cached bool
 }
 
-// rawQuery sends a DNS request to server specified by addr.
+// rawQuery sends a DNS request to server specified by addr, or returns the cached response if there is one.
 // It returns the raw dns response.
 func rawQuery(
 	log *zap.Logger,
 	addr string,
 	recordType uint16,
 	hostname string,
-) (*godns.Msg, error) {
+	opts queryOptions,
+) (*queryResult, error) {
+	return defaultCache.query(log, addr, recordType, hostname, opts)
+}
+
+// exchange sends a DNS request to the server specified by addr without using the cache.
+func exchange(
+	log *zap.Logger,
+	addr string,
+	recordType uint16,
+	hostname string,
+	opts queryOptions,
+) (*queryResult, error) {
 	// Create the DNS message.
 	msg := &godns.Msg{}
 	msg.Id = godns.Id()
-	msg.RecursionDesired = true
+	msg.RecursionDesired = !opts.NoRecursion
 
 	// DNS servers prefer 1 message per request. Make the question.
 	msg.Question = []godns.Question{{
@@ -135,14 +332,21 @@ func rawQuery(
 		Qtype:  recordType,
 		Qclass: godns.StringToClass["IN"],
 	}}
+	if opts.ClientSubnet != "" {
+		if err := setClientSubnet(msg, opts.ClientSubnet); err != nil {
+			return nil, err
+		}
+	}
 	conn, err := godns.Dial("tcp", addr)
 	if err != nil {
 		log.Error("failed to connect to dns server", zap.Error(err))
 		return nil, err
 	}
 	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(queryTimeout))
 
 	// Send the DNS message.
+	start := time.Now()
 	err = conn.WriteMsg(msg)
 	if err != nil {
 		return nil, err
@@ -152,8 +356,13 @@ func rawQuery(
 	msg, err = conn.ReadMsg()
 	if err != nil {
 		log.Error("failed to read from dns server", zap.Error(err))
+		return nil, err
 	}
-	return msg, err
+	return &queryResult{
+		Msg:     msg,
+		Address: conn.RemoteAddr().String(),
+		RTT:     time.Since(start),
+	}, nil
 }
 
 func recordFromAnswer(answer godns.RR) (Record, error) {
@@ -192,6 +401,7 @@ func recordFromAnswer(answer godns.RR) (Record, error) {
 		Name:     strings.TrimRight(header.Name, "."),
 		Value:    data,
 		stringer: answer.String,
+		data:     strings.TrimPrefix(answer.String(), header.String()),
 	}
 
 	// For MX records, extract priority.
@@ -202,16 +412,20 @@ func recordFromAnswer(answer godns.RR) (Record, error) {
 	return record, nil
 }
 
-func queryTypeFromNameserver(log *zap.Logger, nameserver, recordType, lookup string) ([]Record, error) {
+func queryTypeFromNameserver(
+	log *zap.Logger, nameserver, recordType, lookup string, opts queryOptions,
+) (Server, error) {
 	// Do the main DNS lookup.
-	addr := nameserver + ":53"
-	result, err := rawQuery(log, addr, godns.StringToType[recordType], lookup)
+	addr := nameserverAddr(nameserver)
+	result, err := rawQuery(log, addr, godns.StringToType[recordType], lookup, opts)
 	if err != nil {
 		log.Error("failed to lookup DNS record", zap.Error(err))
-		return nil, err
+		return Server{}, err
+	}
+	server, err := newServer(nameserver, result)
+	if err != nil {
+		return Server{}, err
 	}
-
-	records := []Record{}
 
 	// Go through each answer and check if we need to do any traversals.
 	answers := result.Answer
@@ -221,34 +435,83 @@ func queryTypeFromNameserver(log *zap.Logger, nameserver, recordType, lookup str
 	for _, answer := range answers {
 		record, err := recordFromAnswer(answer)
 		if err != nil {
-			return nil, err
+			return Server{}, err
 		}
-		records = append(records, record)
+		server.Records = append(server.Records, record)
 	}
 
-	return records, nil
+	return server, nil
 }
 
-func findAuthoritativeNameserver(log *zap.Logger, hostname string) (string, RecordType, error) {
+// Queries several record types from a nameserver in parallel. A failure of one type is stored on its server
+// rather than throwing away the other results, unless every type failed.
+func queryTypesFromNameserver(
+	log *zap.Logger, nameserver string, recordTypes []string, lookup string, opts queryOptions,
+) ([]Server, error) {
+	servers := make([]Server, len(recordTypes))
+	errs := make([]error, len(recordTypes))
+	wg := sync.WaitGroup{}
+	for i, recordType := range recordTypes {
+		wg.Add(1)
+		go func(i int, recordType string) {
+			defer wg.Done()
+			server, err := queryTypeFromNameserver(log, nameserver, recordType, lookup, opts)
+			if err != nil {
+				server = Server{
+					Server:     nameserver,
+					Records:    []Record{},
+					Additional: []Record{},
+					Error:      err.Error(),
+				}
+				errs[i] = err
+			}
+			server.QueryType = recordType
+			servers[i] = server
+		}(i, recordType)
+	}
+	wg.Wait()
+
+	// Only fail if nothing worked.
+	for _, err := range errs {
+		if err == nil {
+			return servers, nil
+		}
+	}
+	if len(errs) != 0 {
+		return nil, errs[0]
+	}
+	return servers, nil
+}
+
+// Defines the maximum number of CNAME records that will be followed in a trace.
+const maxCNAMEChain = 10
+
+// Finds the authoritative nameserver for a hostname by following the delegations down from the root. If the
+// hostname is a CNAME, the target is returned alongside the nameserver which answered with it.
+func findAuthoritativeNameserver(
+	log *zap.Logger, hostname string, opts queryOptions,
+) (string, string, RecordType, error) {
 	// Select a root nameserver to begin our search
-	rootNameserver := NextRootServer()
+	root := NextRootServer()
+	rootAddress, rootFamily := root.Address()
 
 	resp := RecordType{}
-	var recursiveSearch func(iteration int, nameserver string) (string, error)
-	recursiveSearch = func(iteration int, nameserver string) (string, error) {
+	cnameTarget := ""
+	var recursiveSearch func(iteration int, nameserver, address string) (string, error)
+	recursiveSearch = func(iteration int, nameserver, address string) (string, error) {
 		if iteration > 10 {
 			return "", errors.New("nameserver search depth exceeded")
 		}
 		iteration += 1
 
-		msg, err := rawQuery(log, nameserver+":53", godns.TypeNS, hostname)
+		msg, err := rawQuery(log, nameserverAddr(address), godns.TypeNS, hostname, opts)
 		if err != nil {
 			return "", err
 		}
 
-		server := Server{
-			Server:  nameserver,
-			Records: []Record{},
+		server, err := newServer(strings.TrimRight(nameserver, "."), msg)
+		if err != nil {
+			return "", err
 		}
 
 		// Add discovered answers/NSes to the records for showing to user
@@ -288,9 +551,11 @@ func findAuthoritativeNameserver(log *zap.Logger, hostname string) (string, Reco
 		}
 
 		// If there's no NS type answers, but a cname answer, it means the user
-		// has queried a cname. This is a weird behaviour.
+		// has queried a cname. The server we asked is authoritative for it, so
+		// the caller can continue the trace from the target.
 		if cnameAnswer != nil {
-			return "", nil
+			cnameTarget = cnameAnswer.Target
+			return nameserver, nil
 		}
 
 		if len(msg.Ns) == 0 {
@@ -302,7 +567,7 @@ func findAuthoritativeNameserver(log *zap.Logger, hostname string) (string, Reco
 		// perform the search on that one now.
 		switch v := msg.Ns[rand.Intn(len(msg.Ns))].(type) {
 		case *godns.NS:
-			return recursiveSearch(iteration, v.Ns)
+			return recursiveSearch(iteration, v.Ns, v.Ns)
 		case *godns.SOA:
 			return v.Ns, nil
 		default:
@@ -310,28 +575,16 @@ func findAuthoritativeNameserver(log *zap.Logger, hostname string) (string, Reco
 		}
 	}
 
-	authoritativeNameserver, err := recursiveSearch(0, rootNameserver)
+	authoritativeNameserver, err := recursiveSearch(0, root.Name, rootAddress)
 	if err != nil {
-		return "", nil, err
+		return "", "", nil, err
 	}
+	resp[0].Root = &RootInfo{Letter: root.Letter(), Family: rootFamily}
 
-	return authoritativeNameserver, resp, nil
+	return authoritativeNameserver, cnameTarget, resp, nil
 }
 
-func traceQuery(log *zap.Logger, dnsServer, recordType, hostname string) (Response, error) {
-	authoritativeNameserver, answer, err := findAuthoritativeNameserver(log, hostname)
-	if err != nil {
-		return nil, err
-	}
-
-	// When tracing on a cname, we pick it up during the auth nameserver search
-	// and aren't provided a authoritative nameserver to continue to.
-	if authoritativeNameserver == "" {
-		return Response{
-			"TRACE": answer,
-		}, nil
-	}
-
+func traceQuery(log *zap.Logger, dnsServer, recordType, hostname string, opts queryOptions) (Response, error) {
 	// Get the record types.
 	recordTypes := []string{strings.ToUpper(recordType)}
 	if recordType == "ANY" {
@@ -342,41 +595,60 @@ func traceQuery(log *zap.Logger, dnsServer, recordType, hostname string) (Respon
 		recordTypes = []string{}
 	}
 
-	eg := errgroup.Group{}
-	answerLock := sync.Mutex{}
-	// Spawn a goroutine to look up each record type.
-	for _, recordLoop := range recordTypes {
-		record := recordLoop
-		eg.Go(func() error {
-			records, err := queryTypeFromNameserver(log, authoritativeNameserver, record, hostname)
-			if err != nil {
-				return err
-			}
-			answerLock.Lock()
-			answer[len(answer)-1].Records = append(
-				answer[len(answer)-1].Records,
-				records...,
-			)
+	// Follow the delegations for the hostname, and then for every CNAME target along the way.
+	answer := RecordType{}
+	seen := map[string]bool{}
+	var authoritativeNameserver string
+	for hop := 0; ; hop++ {
+		name := strings.ToLower(hostname)
+		if seen[name] {
+			return nil, fmt.Errorf("cname loop detected at %s", hostname)
+		}
+		seen[name] = true
+		if hop > maxCNAMEChain {
+			return nil, errors.New("cname chain length exceeded")
+		}
+
+		nameserver, cnameTarget, servers, err := findAuthoritativeNameserver(log, hostname, opts)
+		if err != nil {
+			return nil, err
+		}
+		label := strings.TrimRight(hostname, ".")
+		if hop != 0 {
+			label = fmt.Sprintf("cname %d: %s", hop, label)
+		}
+		for i := range servers {
+			servers[i].Hop = label
+		}
+		answer = append(answer, servers...)
+		authoritativeNameserver = nameserver
 
-			answerLock.Unlock()
-			return nil
-		})
+		// If the user asked for the CNAME itself, we do not need to go any further.
+		if cnameTarget == "" || strings.ToUpper(recordType) == "CNAME" {
+			break
+		}
+		hostname = cnameTarget
 	}
 
-	// Wait for the results.
-	if err := eg.Wait(); err != nil {
+	// Look up each record type.
+	answers, err := queryTypesFromNameserver(log, authoritativeNameserver, recordTypes, hostname, opts)
+	if err != nil {
 		return nil, err
 	}
+	label := answer[len(answer)-1].Hop
+	for i := range answers {
+		answers[i].Hop = label
+	}
+	answer = append(answer, answers...)
 
 	return Response{
 		"TRACE": answer,
 	}, nil
 }
 
-func recursiveQuery(log *zap.Logger, dnsServer, recordType, hostname string) (Response, error) {
+func recursiveQuery(log *zap.Logger, dnsServer, recordType, hostname string, opts queryOptions) (Response, error) {
 	// Create the response map.
 	responses := Response{}
-	responsesLock := sync.Mutex{}
 
 	// Get the record types.
 	recordTypes := []string{strings.ToUpper(recordType)}
@@ -385,67 +657,126 @@ func recursiveQuery(log *zap.Logger, dnsServer, recordType, hostname string) (Re
 		recordTypes = []string{"A", "AAAA", "CNAME", "MX", "PTR", "SOA", "TXT", "NS"}
 	}
 
-	// Create the error groups.
-	eg := errgroup.Group{}
-
-	// Go through each record type and do the lookups.
-	for _, recordLoop := range recordTypes {
-		record := recordLoop
-		eg.Go(func() error {
-			records, err := queryTypeFromNameserver(log, dnsServer, record, hostname)
-			if err != nil {
-				return err
-			}
-			responsesLock.Lock()
-			responses[record] = RecordType{
-				Server{
-					Server:  dnsServer,
-					Records: records,
-				},
-			}
-			responsesLock.Unlock()
-			return nil
-		})
-	}
-
-	// Wait for the group to finish and then return the results.
-	if err := eg.Wait(); err != nil {
+	// Do the lookups and put each record type into the map.
+	servers, err := queryTypesFromNameserver(log, dnsServer, recordTypes, hostname, opts)
+	if err != nil {
 		return nil, err
 	}
+	for _, server := range servers {
+		responses[server.QueryType] = RecordType{server}
+	}
 	return responses, nil
 }
 
-func Lookup(log *zap.Logger, dnsServer, recordType, hostname string, fullTrace bool) (Response, error) {
+// LookupOptions is used to change how a lookup is performed.
+type LookupOptions struct {
+	// Trace is used to follow the delegations from the root rather than asking the DNS server.
+	Trace bool
+
+	// NoCache is used to bypass the cache and always send the queries.
+	NoCache bool
+
+	// ClientSubnet is used to send the EDNS Client Subnet option with the queries. This can be a prefix or an
+	// IP address.
+	ClientSubnet string
+}
+
+// Converts the options into the ones used for each query.
+func (o LookupOptions) queryOptions() (queryOptions, error) {
+	opts := queryOptions{NoCache: o.NoCache}
+	if o.ClientSubnet != "" {
+		subnet, err := normaliseClientSubnet(o.ClientSubnet)
+		if err != nil {
+			return queryOptions{}, err
+		}
+		opts.ClientSubnet = subnet
+	}
+	return opts, nil
+}
+
+func Lookup(log *zap.Logger, dnsServer, recordType, hostname string, opts LookupOptions) (Response, error) {
 	// Add dot to hostname if necessary
 	if !strings.HasSuffix(hostname, ".") {
 		hostname += "."
 	}
 
-	if fullTrace {
-		return traceQuery(log, dnsServer, recordType, hostname)
+	queryOpts, err := opts.queryOptions()
+	if err != nil {
+		return nil, err
 	}
 
-	return recursiveQuery(log, dnsServer, recordType, hostname)
+	if opts.Trace {
+		return traceQuery(log, dnsServer, recordType, hostname, queryOpts)
+	}
+
+	return recursiveQuery(log, dnsServer, recordType, hostname, queryOpts)
 }
 
-func reverseIP(ip net.IP) string {
-	addressParts := strings.Split(ip.String(), ".")
-	reversed := []string{}
+// Returns the name used for a reverse lookup of the IP address. IPv4 addresses are placed under
+// in-addr.arpa. and IPv6 addresses are nibble-reversed under ip6.arpa.
+func reverseName(ip net.IP) string {
+	if v4 := ip.To4(); v4 != nil {
+		return fmt.Sprintf("%d.%d.%d.%d.in-addr.arpa.", v4[3], v4[2], v4[1], v4[0])
+	}
 
-	for i := len(addressParts) - 1; i >= 0; i-- {
-		octet := addressParts[i]
-		reversed = append(reversed, octet)
+	const hexDigits = "0123456789abcdef"
+	b := make([]byte, 0, 73)
+	for i := len(ip) - 1; i >= 0; i-- {
+		b = append(b, hexDigits[ip[i]&0xf], '.', hexDigits[ip[i]>>4], '.')
 	}
+	return string(b) + "ip6.arpa."
+}
 
-	return strings.Join(reversed, ".")
+// Finds the apex of the zone which holds a name from the SOA record returned for it.
+func findZoneApex(log *zap.Logger, dnsServer, name string, opts queryOptions) (string, error) {
+	msg, err := rawQuery(log, nameserverAddr(dnsServer), godns.TypeSOA, name, opts)
+	if err != nil {
+		return "", err
+	}
+	for _, rr := range append(msg.Answer, msg.Ns...) {
+		if v, ok := rr.(*godns.SOA); ok {
+			return strings.TrimRight(v.Hdr.Name, "."), nil
+		}
+	}
+	return "", errors.New("no soa record returned for the name")
+}
+
+// RDNSResult is the result of a traced reverse DNS lookup.
+type RDNSResult struct {
+	// ReverseName is used to define the name that was looked up.
+	ReverseName string `json:"reverse_name"`
+
+	// Zone is used to define the apex of the zone which holds the reverse delegation.
+	Zone string `json:"zone"`
+
+	// Trace is used to define the servers which were queried.
+	Trace RecordType `json:"trace"`
+}
+
+func (r *RDNSResult) String() string {
+	return "--- " + r.ReverseName + " (zone " + r.Zone + ") ---\n" + r.Trace.String()
 }
 
-func LookupRDNS(log *zap.Logger, ip net.IP, dnsServer string) (RecordType, error) {
-	hostname := reverseIP(ip) + ".in-addr.arpa."
-	resp, err := traceQuery(log, dnsServer, "PTR", hostname)
+func LookupRDNS(log *zap.Logger, ip net.IP, dnsServer string, opts LookupOptions) (*RDNSResult, error) {
+	hostname := reverseName(ip)
+	queryOpts, err := opts.queryOptions()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := traceQuery(log, dnsServer, "PTR", hostname, queryOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	// IPv6 reverse zones are often delegated at odd nibble boundaries, so find where the cut actually is.
+	zone, err := findZoneApex(log, dnsServer, hostname, queryOpts)
 	if err != nil {
 		return nil, err
 	}
 
-	return resp["TRACE"], nil
+	return &RDNSResult{
+		ReverseName: strings.TrimRight(hostname, "."),
+		Zone:        zone,
+		Trace:       resp["TRACE"],
+	}, nil
 }
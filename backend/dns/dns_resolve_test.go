@@ -0,0 +1,38 @@
+package dns
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_reverseName(t *testing.T) {
+	tests := []struct {
+		name string
+
+		ip      string
+		expects string
+	}{
+		{
+			name:    "ipv4",
+			ip:      "81.2.115.158",
+			expects: "158.115.2.81.in-addr.arpa.",
+		},
+		{
+			name:    "ipv6",
+			ip:      "2001:db8::567:89ab",
+			expects: "b.a.9.8.7.6.5.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa.",
+		},
+		{
+			name:    "ipv4 mapped ipv6",
+			ip:      "::ffff:1.2.3.4",
+			expects: "4.3.2.1.in-addr.arpa.",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expects, reverseName(net.ParseIP(tt.ip)))
+		})
+	}
+}
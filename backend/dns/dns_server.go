@@ -1,8 +1,10 @@
 package dns
 
 import (
-	"go.uber.org/zap"
 	"os"
+	"strings"
+
+	"go.uber.org/zap"
 )
 
 // GetCachedDNSServer is used to get the cache DNS server.
@@ -29,3 +31,40 @@ func GetCachedDNSServer(log *zap.Logger) string {
 	s = ns[len(ns)-1]
 	return s
 }
+
+// Resolver is used to define a named DNS server.
+type Resolver struct {
+	// Name is used to define the display name of the resolver.
+	Name string `json:"name"`
+
+	// Address is used to define the IP address of the resolver.
+	Address string `json:"address"`
+}
+
+// Defines the public resolvers which are compared when COMPARE_DNS_SERVERS is not set.
+var defaultCompareResolvers = []Resolver{
+	{Name: "Cloudflare", Address: "1.1.1.1"},
+	{Name: "Google", Address: "8.8.8.8"},
+	{Name: "Quad9", Address: "9.9.9.9"},
+}
+
+// GetCompareResolvers is used to get the resolvers answers are compared across. The cache DNS server is always
+// first. COMPARE_DNS_SERVERS can be set to a comma separated list of name=address pairs to replace the public
+// resolvers.
+func GetCompareResolvers(log *zap.Logger, cachedDnsServer string) []Resolver {
+	resolvers := []Resolver{{Name: "Region cache", Address: cachedDnsServer}}
+
+	s := os.Getenv("COMPARE_DNS_SERVERS")
+	if s == "" {
+		return append(resolvers, defaultCompareResolvers...)
+	}
+	for _, v := range strings.Split(s, ",") {
+		split := strings.SplitN(strings.TrimSpace(v), "=", 2)
+		if len(split) != 2 || split[0] == "" || split[1] == "" {
+			log.Warn("ignoring invalid resolver in COMPARE_DNS_SERVERS", zap.String("resolver", v))
+			continue
+		}
+		resolvers = append(resolvers, Resolver{Name: split[0], Address: split[1]})
+	}
+	return resolvers
+}
@@ -0,0 +1,243 @@
+package dns
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	godns "github.com/miekg/dns"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+)
+
+// TTLRecord is a record with the TTL it has at the authoritative server and in the cache.
+type TTLRecord struct {
+	// Name is used to define the name of the record.
+	Name string `json:"name"`
+
+	// Type is used to define the type of the record.
+	Type string `json:"type"`
+
+	// Value is used to define the presentation format of the record data.
+	Value string `json:"value"`
+
+	// AuthoritativeTTL is used to define the TTL at the authoritative server. This is nil if the authoritative
+	// server did not return the record.
+	AuthoritativeTTL *uint32 `json:"authoritative_ttl"`
+
+	// CachedTTL is used to define the remaining TTL in the cache. This is nil if the cache did not return the
+	// record.
+	CachedTTL *uint32 `json:"cached_ttl"`
+
+	// Differs is true if only one of the authoritative server or the cache returned the record.
+	Differs bool `json:"differs"`
+
+	// Stale is true if the cached TTL is higher than the authoritative TTL. This happens when the TTL has been
+	// lowered since the record was cached, or when the resolver doesn't respect the TTL, and means the cache may
+	// hold the record for longer than the authoritative servers allow.
+	Stale bool `json:"stale"`
+}
+
+// TTLComparison is the result of comparing the authoritative answer for a name with the cached one.
+type TTLComparison struct {
+	// Hostname is used to define the hostname which was looked up.
+	Hostname string `json:"hostname"`
+
+	// RecordType is used to define the record type which was looked up.
+	RecordType string `json:"record_type"`
+
+	// Resolver is used to define the address of the caching resolver.
+	Resolver string `json:"resolver"`
+
+	// AuthoritativeServers is used to define the authoritative servers which were asked, one per name in the
+	// CNAME chain.
+	AuthoritativeServers []string `json:"authoritative_servers"`
+
+	// Consistent is true if the cache has the same records as the authoritative servers.
+	Consistent bool `json:"consistent"`
+
+	// Stale is true if any record has a higher TTL in the cache than at the authoritative servers.
+	Stale bool `json:"stale"`
+
+	// CacheExpiresIn is used to define how many seconds it will be until every record in the cache has expired,
+	// which is the longest a change at the authoritative servers can take to be visible through the resolver.
+	CacheExpiresIn uint32 `json:"cache_expires_in"`
+
+	// Records is used to define every record returned by either side.
+	Records []*TTLRecord `json:"records"`
+}
+
+// String returns the comparison in a human readable format.
+func (c *TTLComparison) String() string {
+	str := "--- " + c.RecordType + " " + c.Hostname + " ---\n"
+	str += ";; resolver: " + c.Resolver + ", authoritative: " + strings.Join(c.AuthoritativeServers, ", ") + "\n"
+	if c.Consistent {
+		str += ";; the cache matches the authoritative servers"
+	} else {
+		str += ";; the cache differs from the authoritative servers"
+	}
+	str += ", cache expires in " + strconv.FormatUint(uint64(c.CacheExpiresIn), 10) + "s\n"
+	if c.Stale {
+		str += ";; some cached TTLs are higher than the authoritative TTLs, so the cache may be stale\n"
+	}
+
+	formatTTL := func(ttl *uint32) string {
+		if ttl == nil {
+			return "-"
+		}
+		return strconv.FormatUint(uint64(*ttl), 10)
+	}
+	for _, r := range c.Records {
+		str += r.Name + ". " + r.Type + " " + r.Value + " ;; authoritative ttl: " + formatTTL(r.AuthoritativeTTL) +
+			", cached ttl: " + formatTTL(r.CachedTTL)
+		if r.Differs {
+			str += " (differs)"
+		}
+		if r.Stale {
+			str += " (stale)"
+		}
+		str += "\n"
+	}
+	return str
+}
+
+// Asks the authoritative servers for the records, following any CNAME chain. The records and the servers
+// which were asked are returned.
+func authoritativeRecords(
+	log *zap.Logger, qtype uint16, hostname string, opts queryOptions,
+) ([]godns.RR, []string, error) {
+	records := []godns.RR{}
+	servers := []string{}
+	for hop := 0; ; hop++ {
+		if hop > maxCNAMEChain {
+			return nil, nil, errors.New("cname chain length exceeded")
+		}
+		nameserver, _, _, err := findAuthoritativeNameserver(log, hostname, opts)
+		if err != nil {
+			return nil, nil, err
+		}
+		opts.NoRecursion = true
+		msg, err := rawQuery(log, nameserverAddr(nameserver), qtype, hostname, opts)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to query %s: %v", nameserver, err)
+		}
+		servers = append(servers, nameserver+" ["+msg.Address+"]")
+
+		// Collect the records for this name, and move along the chain if it is a CNAME.
+		target := ""
+		for _, rr := range msg.Answer {
+			if normaliseName(rr.Header().Name) != normaliseName(hostname) {
+				continue
+			}
+			records = append(records, rr)
+			if v, ok := rr.(*godns.CNAME); ok && qtype != godns.TypeCNAME {
+				target = v.Target
+			}
+		}
+		if target == "" {
+			return records, servers, nil
+		}
+		hostname = target
+	}
+}
+
+// Matches up the records from the authoritative servers with the ones from the cache.
+func (c *TTLComparison) matchRecords(authRecords, cachedRecords []godns.RR) {
+	c.Consistent = true
+	c.Records = []*TTLRecord{}
+	records := map[string]*TTLRecord{}
+	getRecord := func(rr godns.RR) *TTLRecord {
+		key := ttlRecordKey(rr)
+		r, ok := records[key]
+		if !ok {
+			r = &TTLRecord{
+				Name:  strings.TrimRight(rr.Header().Name, "."),
+				Type:  godns.TypeToString[rr.Header().Rrtype],
+				Value: strings.TrimPrefix(rr.String(), rr.Header().String()),
+			}
+			records[key] = r
+			c.Records = append(c.Records, r)
+		}
+		return r
+	}
+
+	for _, rr := range authRecords {
+		ttl := rr.Header().Ttl
+		getRecord(rr).AuthoritativeTTL = &ttl
+	}
+	for _, rr := range cachedRecords {
+		ttl := rr.Header().Ttl
+		getRecord(rr).CachedTTL = &ttl
+		if ttl > c.CacheExpiresIn {
+			c.CacheExpiresIn = ttl
+		}
+	}
+	for _, r := range c.Records {
+		r.Differs = r.AuthoritativeTTL == nil || r.CachedTTL == nil
+		if r.Differs {
+			c.Consistent = false
+			continue
+		}
+		r.Stale = *r.CachedTTL > *r.AuthoritativeTTL
+		if r.Stale {
+			c.Stale = true
+		}
+	}
+}
+
+// Returns the key used to match up a record from both sides.
+func ttlRecordKey(rr godns.RR) string {
+	return normaliseName(rr.Header().Name) + " " + godns.TypeToString[rr.Header().Rrtype] + " " +
+		strings.TrimPrefix(rr.String(), rr.Header().String())
+}
+
+// CompareTTLs asks the caching resolver and the authoritative servers for a record at the same time, and compares
+// the records and TTLs they return. Both queries bypass our own cache so the resolver's remaining TTL is shown.
+func CompareTTLs(
+	log *zap.Logger, dnsServer, recordType, hostname string, opts LookupOptions,
+) (*TTLComparison, error) {
+	recordType = strings.ToUpper(recordType)
+	qtype, ok := godns.StringToType[recordType]
+	if !ok || qtype == godns.TypeANY {
+		return nil, errors.New("invalid record type")
+	}
+	if !strings.HasSuffix(hostname, ".") {
+		hostname += "."
+	}
+	opts.NoCache = true
+	queryOpts, err := opts.queryOptions()
+	if err != nil {
+		return nil, err
+	}
+
+	// Do both lookups at the same time.
+	var (
+		authRecords []godns.RR
+		authServers []string
+		cachedMsg   *queryResult
+		eg          errgroup.Group
+	)
+	eg.Go(func() error {
+		var err error
+		authRecords, authServers, err = authoritativeRecords(log, qtype, hostname, queryOpts)
+		return err
+	})
+	eg.Go(func() error {
+		var err error
+		cachedMsg, err = rawQuery(log, nameserverAddr(dnsServer), qtype, hostname, queryOpts)
+		return err
+	})
+	if err := eg.Wait(); err != nil {
+		return nil, err
+	}
+
+	comparison := &TTLComparison{
+		Hostname:             strings.TrimRight(hostname, "."),
+		RecordType:           recordType,
+		Resolver:             cachedMsg.Address,
+		AuthoritativeServers: authServers,
+	}
+	comparison.matchRecords(authRecords, cachedMsg.Answer)
+	return comparison, nil
+}
@@ -0,0 +1,158 @@
+package dns
+
+import (
+	"testing"
+
+	godns "github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTTLComparison_matchRecords(t *testing.T) {
+	uint32p := func(v uint32) *uint32 { return &v }
+	tests := []struct {
+		name string
+
+		auth       []string
+		cached     []string
+		consistent bool
+		stale      bool
+		expiresIn  uint32
+		records    []*TTLRecord
+	}{
+		{
+			name:       "matching records",
+			auth:       []string{"example.com. 3600 IN A 192.0.2.1"},
+			cached:     []string{"example.com. 1200 IN A 192.0.2.1"},
+			consistent: true,
+			expiresIn:  1200,
+			records: []*TTLRecord{
+				{
+					Name:             "example.com",
+					Type:             "A",
+					Value:            "192.0.2.1",
+					AuthoritativeTTL: uint32p(3600),
+					CachedTTL:        uint32p(1200),
+				},
+			},
+		},
+		{
+			name:       "changed record",
+			auth:       []string{"example.com. 300 IN A 192.0.2.2"},
+			cached:     []string{"example.com. 250 IN A 192.0.2.1"},
+			consistent: false,
+			expiresIn:  250,
+			records: []*TTLRecord{
+				{Name: "example.com", Type: "A", Value: "192.0.2.2", AuthoritativeTTL: uint32p(300), Differs: true},
+				{Name: "example.com", Type: "A", Value: "192.0.2.1", CachedTTL: uint32p(250), Differs: true},
+			},
+		},
+		{
+			name:       "cached ttl above authoritative ttl",
+			auth:       []string{"example.com. 300 IN A 192.0.2.1"},
+			cached:     []string{"example.com. 3500 IN A 192.0.2.1"},
+			consistent: true,
+			stale:      true,
+			expiresIn:  3500,
+			records: []*TTLRecord{
+				{
+					Name:             "example.com",
+					Type:             "A",
+					Value:            "192.0.2.1",
+					AuthoritativeTTL: uint32p(300),
+					CachedTTL:        uint32p(3500),
+					Stale:            true,
+				},
+			},
+		},
+		{
+			name:       "cached ttl equal to authoritative ttl",
+			auth:       []string{"example.com. 300 IN A 192.0.2.1"},
+			cached:     []string{"example.com. 300 IN A 192.0.2.1"},
+			consistent: true,
+			expiresIn:  300,
+			records: []*TTLRecord{
+				{
+					Name:             "example.com",
+					Type:             "A",
+					Value:            "192.0.2.1",
+					AuthoritativeTTL: uint32p(300),
+					CachedTTL:        uint32p(300),
+				},
+			},
+		},
+		{
+			name: "cname chain",
+			auth: []string{
+				"www.example.com. 3600 IN CNAME example.com.",
+				"example.com. 300 IN A 192.0.2.1",
+			},
+			cached: []string{
+				"www.example.com. 100 IN CNAME example.com.",
+				"example.com. 20 IN A 192.0.2.1",
+			},
+			consistent: true,
+			expiresIn:  100,
+			records: []*TTLRecord{
+				{
+					Name:             "www.example.com",
+					Type:             "CNAME",
+					Value:            "example.com.",
+					AuthoritativeTTL: uint32p(3600),
+					CachedTTL:        uint32p(100),
+				},
+				{
+					Name:             "example.com",
+					Type:             "A",
+					Value:            "192.0.2.1",
+					AuthoritativeTTL: uint32p(300),
+					CachedTTL:        uint32p(20),
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			toRRs := func(v []string) []godns.RR {
+				rrs := []godns.RR{}
+				for _, s := range v {
+					rrs = append(rrs, mustRR(t, s))
+				}
+				return rrs
+			}
+			c := &TTLComparison{}
+			c.matchRecords(toRRs(tt.auth), toRRs(tt.cached))
+			assert.Equal(t, tt.consistent, c.Consistent)
+			assert.Equal(t, tt.stale, c.Stale)
+			assert.Equal(t, tt.expiresIn, c.CacheExpiresIn)
+			assert.Equal(t, tt.records, c.Records)
+		})
+	}
+}
+
+func TestTTLComparison_String(t *testing.T) {
+	uint32p := func(v uint32) *uint32 { return &v }
+	c := &TTLComparison{
+		Hostname:             "example.com",
+		RecordType:           "A",
+		Resolver:             "1.1.1.1:53",
+		AuthoritativeServers: []string{"ns1.example.com [192.0.2.53:53]"},
+		Consistent:           true,
+		Stale:                true,
+		CacheExpiresIn:       3500,
+		Records: []*TTLRecord{
+			{
+				Name:             "example.com",
+				Type:             "A",
+				Value:            "192.0.2.1",
+				AuthoritativeTTL: uint32p(300),
+				CachedTTL:        uint32p(3500),
+				Stale:            true,
+			},
+		},
+	}
+	assert.Equal(t, "--- A example.com ---\n"+
+		";; resolver: 1.1.1.1:53, authoritative: ns1.example.com [192.0.2.53:53]\n"+
+		";; the cache matches the authoritative servers, cache expires in 3500s\n"+
+		";; some cached TTLs are higher than the authoritative TTLs, so the cache may be stale\n"+
+		"example.com. A 192.0.2.1 ;; authoritative ttl: 300, cached ttl: 3500 (stale)\n", c.String())
+}
@@ -0,0 +1,747 @@
+package dns
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	godns "github.com/miekg/dns"
+	"go.uber.org/zap"
+)
+
+// Defines how long fetching the MTA-STS policy can take.
+const mtaSTSFetchTimeout = 10 * time.Second
+
+// Defines the maximum size of an MTA-STS policy, as described in RFC 8461 section 3.3.
+const mtaSTSMaxPolicySize = 64 * 1024
+
+// Severity is used to define how serious a mail authentication finding is.
+type Severity string
+
+const (
+	// SeverityInfo means the finding is for information only.
+	SeverityInfo Severity = "info"
+
+	// SeverityWarning means the setup works but does not follow best practice.
+	SeverityWarning Severity = "warning"
+
+	// SeverityError means the setup is broken and will affect deliverability.
+	SeverityError Severity = "error"
+)
+
+// Finding is a single problem or observation from a mail authentication check.
+type Finding struct {
+	// Check is used to define the check which made the finding, such as "spf" or "dmarc".
+	Check string `json:"check"`
+
+	// Severity is used to define how serious the finding is.
+	Severity Severity `json:"severity"`
+
+	// Message is a human readable explanation of the finding.
+	Message string `json:"message"`
+}
+
+// String returns the finding in a human readable format.
+func (f Finding) String() string {
+	return "[" + strings.ToUpper(string(f.Severity)) + "] " + f.Check + ": " + f.Message
+}
+
+// TagRecord is a parsed record made up of tag=value pairs, such as a DMARC or DKIM record.
+type TagRecord struct {
+	// Name is used to define the DNS name the record was found at.
+	Name string `json:"name"`
+
+	// Record is used to define the raw record.
+	Record string `json:"record"`
+
+	// Tags is used to define the parsed tags of the record.
+	Tags map[string]string `json:"tags"`
+}
+
+// DKIMResult is the result of checking a single DKIM selector.
+type DKIMResult struct {
+	TagRecord
+
+	// Selector is used to define the selector which was checked.
+	Selector string `json:"selector"`
+
+	// KeyType is used to define the type of the public key, such as "rsa" or "ed25519".
+	KeyType string `json:"key_type"`
+
+	// KeySize is used to define the size of the public key in bits.
+	KeySize int `json:"key_size"`
+}
+
+// MTASTSPolicy is a parsed MTA-STS policy file.
+type MTASTSPolicy struct {
+	// URL is used to define where the policy was fetched from.
+	URL string `json:"url"`
+
+	// Version is used to define the version of the policy.
+	Version string `json:"version"`
+
+	// Mode is used to define the mode of the policy, which is enforce, testing or none.
+	Mode string `json:"mode"`
+
+	// MaxAge is used to define how long the policy can be cached for in seconds.
+	MaxAge int `json:"max_age"`
+
+	// MX is used to define the MX patterns the policy allows.
+	MX []string `json:"mx"`
+}
+
+// MTASTSResult is the result of checking MTA-STS.
+type MTASTSResult struct {
+	// Record is used to define the _mta-sts TXT record.
+	Record *TagRecord `json:"record"`
+
+	// Policy is used to define the policy which was fetched over HTTPS.
+	Policy *MTASTSPolicy `json:"policy"`
+}
+
+// MailAuthReport is the result of all the mail authentication checks against a domain.
+type MailAuthReport struct {
+	// Domain is used to define the domain which was checked.
+	Domain string `json:"domain"`
+
+	// SPF is used to define the expanded SPF record. This is nil if there is no record.
+	SPF *SPFResult `json:"spf"`
+
+	// DMARC is used to define the DMARC record. This is nil if there is no record.
+	DMARC *TagRecord `json:"dmarc"`
+
+	// DKIM is used to define the results for each selector which was checked.
+	DKIM []*DKIMResult `json:"dkim"`
+
+	// MTASTS is used to define the MTA-STS record and policy. This is nil if there is no record.
+	MTASTS *MTASTSResult `json:"mta_sts"`
+
+	// TLSRPT is used to define the TLS-RPT record. This is nil if there is no record.
+	TLSRPT *TagRecord `json:"tls_rpt"`
+
+	// BIMI is used to define the BIMI record. This is nil if there is no record.
+	BIMI *TagRecord `json:"bimi"`
+
+	// Findings is used to define every problem or observation in the order they were found.
+	Findings []Finding `json:"findings"`
+}
+
+// String returns the report in a human readable format.
+func (r *MailAuthReport) String() string {
+	str := "--- " + r.Domain + " ---\n"
+	if r.SPF != nil {
+		str += writeSPFTree(r.SPF, "")
+	}
+	for _, v := range []*TagRecord{r.DMARC, r.TLSRPT, r.BIMI} {
+		if v != nil {
+			str += v.Name + " TXT \"" + v.Record + "\"\n"
+		}
+	}
+	for _, v := range r.DKIM {
+		if v.Record != "" {
+			str += v.Name + " TXT \"" + v.Record + "\"\n"
+		}
+	}
+	if r.MTASTS != nil {
+		str += r.MTASTS.Record.Name + " TXT \"" + r.MTASTS.Record.Record + "\"\n"
+		if p := r.MTASTS.Policy; p != nil {
+			str += p.URL + ": mode " + p.Mode + ", max_age " + strconv.Itoa(p.MaxAge) +
+				", mx " + strings.Join(p.MX, ", ") + "\n"
+		}
+	}
+	str += "\n"
+	for _, f := range r.Findings {
+		str += f.String() + "\n"
+	}
+	return str
+}
+
+// Writes the SPF record and its includes as an indented tree.
+func writeSPFTree(r *SPFResult, indent string) string {
+	str := indent + r.Domain + " TXT \"" + r.Record + "\"\n"
+	for _, v := range r.Includes {
+		str += writeSPFTree(v, indent+"    ")
+	}
+	return str
+}
+
+// mailChecker holds the state of a mail authentication check.
+type mailChecker struct {
+	// lookupTXT returns the TXT records at a name with the strings of each record joined.
+	lookupTXT func(name string) ([]string, error)
+
+	// lookupMX returns the MX hostnames for a name.
+	lookupMX func(name string) ([]string, error)
+
+	// fetchPolicy fetches the body of a HTTPS URL.
+	fetchPolicy func(url string) (string, error)
+
+	// findings is used to define the findings so far.
+	findings []Finding
+}
+
+func (c *mailChecker) add(check string, severity Severity, message string) {
+	c.findings = append(c.findings, Finding{Check: check, Severity: severity, Message: message})
+}
+
+// Returns the records at a name, treating NXDOMAIN as no records.
+func lookupRecords(log *zap.Logger, dnsServer string, recordType uint16, name string) ([]godns.RR, error) {
+	msg, err := rawQuery(log, nameserverAddr(dnsServer), recordType, godns.Fqdn(name), queryOptions{})
+	if err != nil {
+		return nil, err
+	}
+	switch msg.Rcode {
+	case godns.RcodeSuccess:
+		return msg.Answer, nil
+	case godns.RcodeNameError:
+		return nil, nil
+	default:
+		return nil, errors.New("server responded with " + godns.RcodeToString[msg.Rcode])
+	}
+}
+
+// Creates a mail checker which uses the DNS server specified.
+func newMailChecker(log *zap.Logger, dnsServer string) *mailChecker {
+	client := &http.Client{
+		Timeout: mtaSTSFetchTimeout,
+
+		// RFC 8461 section 3.3 says redirects must not be followed.
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	return &mailChecker{
+		lookupTXT: func(name string) ([]string, error) {
+			answers, err := lookupRecords(log, dnsServer, godns.TypeTXT, name)
+			if err != nil {
+				return nil, err
+			}
+			txts := []string{}
+			for _, rr := range answers {
+				if v, ok := rr.(*godns.TXT); ok {
+					txts = append(txts, strings.Join(v.Txt, ""))
+				}
+			}
+			return txts, nil
+		},
+		lookupMX: func(name string) ([]string, error) {
+			answers, err := lookupRecords(log, dnsServer, godns.TypeMX, name)
+			if err != nil {
+				return nil, err
+			}
+			hosts := []string{}
+			for _, rr := range answers {
+				if v, ok := rr.(*godns.MX); ok {
+					hosts = append(hosts, normaliseName(v.Mx))
+				}
+			}
+			return hosts, nil
+		},
+		fetchPolicy: func(url string) (string, error) {
+			resp, err := client.Get(url)
+			if err != nil {
+				return "", err
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != 200 {
+				return "", fmt.Errorf("server responded with status %d", resp.StatusCode)
+			}
+			b, err := io.ReadAll(io.LimitReader(resp.Body, mtaSTSMaxPolicySize+1))
+			if err != nil {
+				return "", err
+			}
+			if len(b) > mtaSTSMaxPolicySize {
+				return "", errors.New("policy is larger than 64KB")
+			}
+			return string(b), nil
+		},
+	}
+}
+
+// Parses a record made up of semicolon separated tag=value pairs.
+func parseTagList(record string) (map[string]string, error) {
+	tags := map[string]string{}
+	for _, v := range strings.Split(record, ";") {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		i := strings.Index(v, "=")
+		if i == -1 {
+			return nil, fmt.Errorf("tag is missing a value: %s", v)
+		}
+		key := strings.ToLower(strings.TrimSpace(v[:i]))
+		if _, ok := tags[key]; ok {
+			return nil, fmt.Errorf("duplicate tag: %s", key)
+		}
+		tags[key] = strings.TrimSpace(v[i+1:])
+	}
+	return tags, nil
+}
+
+// Finds the single record at a name starting with the version tag specified and parses it. Nil is returned if
+// there is no usable record.
+func (c *mailChecker) findTagRecord(check, name, version string) *TagRecord {
+	txts, err := c.lookupTXT(name)
+	if err != nil {
+		c.add(check, SeverityError, fmt.Sprintf("Failed to look up %s: %v", name, err))
+		return nil
+	}
+	records := []string{}
+	for _, v := range txts {
+		if strings.HasPrefix(strings.ToLower(strings.ReplaceAll(v, " ", "")), strings.ToLower(version)) {
+			records = append(records, v)
+		}
+	}
+	if len(records) == 0 {
+		return nil
+	}
+	if len(records) > 1 {
+		c.add(check, SeverityError, "Multiple records were found at "+name+", so receivers may ignore them all.")
+	}
+
+	tags, err := parseTagList(records[0])
+	if err != nil {
+		c.add(check, SeverityError, fmt.Sprintf("The record at %s is invalid: %v", name, err))
+		return nil
+	}
+	return &TagRecord{Name: name, Record: records[0], Tags: tags}
+}
+
+// Returns the domain part of each mailto: URI in a comma separated list, or an error if a URI is not allowed.
+func parseReportURIs(value string, allowHTTPS bool) ([]string, error) {
+	domains := []string{}
+	for _, uri := range strings.Split(value, ",") {
+		uri = strings.TrimSpace(uri)
+		lower := strings.ToLower(uri)
+		switch {
+		case strings.HasPrefix(lower, "mailto:"):
+			// Remove any size limit from the address.
+			address := strings.SplitN(uri[7:], "!", 2)[0]
+			i := strings.LastIndex(address, "@")
+			if i == -1 {
+				return nil, fmt.Errorf("invalid email address: %s", uri)
+			}
+			domains = append(domains, normaliseName(address[i+1:]))
+		case allowHTTPS && strings.HasPrefix(lower, "https://"):
+		default:
+			return nil, fmt.Errorf("unsupported report URI: %s", uri)
+		}
+	}
+	return domains, nil
+}
+
+// Checks the DMARC record for a domain.
+func (c *mailChecker) checkDMARC(domain string) *TagRecord {
+	record := c.findTagRecord("dmarc", "_dmarc."+domain, "v=DMARC1")
+	if record == nil {
+		c.add("dmarc", SeverityWarning, "No DMARC record was found. Receivers have no policy for mail which fails "+
+			"SPF and DKIM, and you will not receive reports.")
+		return nil
+	}
+	tags := record.Tags
+
+	// Check the policies.
+	switch tags["p"] {
+	case "":
+		c.add("dmarc", SeverityError, "The DMARC record has no p tag, which is required.")
+	case "none":
+		c.add("dmarc", SeverityWarning, "The DMARC policy is none, so mail which fails authentication is still "+
+			"delivered.")
+	case "quarantine", "reject":
+		c.add("dmarc", SeverityInfo, "The DMARC policy is "+tags["p"]+".")
+	default:
+		c.add("dmarc", SeverityError, "The DMARC policy "+tags["p"]+" is not valid.")
+	}
+	if sp, ok := tags["sp"]; ok && sp != "none" && sp != "quarantine" && sp != "reject" {
+		c.add("dmarc", SeverityError, "The DMARC subdomain policy "+sp+" is not valid.")
+	}
+	if pct, ok := tags["pct"]; ok {
+		n, err := strconv.Atoi(pct)
+		switch {
+		case err != nil || n < 0 || n > 100:
+			c.add("dmarc", SeverityError, "The DMARC pct tag must be a number between 0 and 100.")
+		case n < 100:
+			c.add("dmarc", SeverityWarning, "The DMARC policy only applies to "+pct+"% of failing mail.")
+		}
+	}
+	for _, tag := range []string{"adkim", "aspf"} {
+		if v, ok := tags[tag]; ok && v != "r" && v != "s" {
+			c.add("dmarc", SeverityError, "The DMARC "+tag+" tag must be r or s.")
+		}
+	}
+
+	// Check the reporting addresses. External destinations must authorise the domain to receive its reports.
+	if _, ok := tags["rua"]; !ok {
+		c.add("dmarc", SeverityInfo, "The DMARC record has no rua tag, so you will not receive aggregate reports.")
+	}
+	for _, tag := range []string{"rua", "ruf"} {
+		v, ok := tags[tag]
+		if !ok {
+			continue
+		}
+		destinations, err := parseReportURIs(v, false)
+		if err != nil {
+			c.add("dmarc", SeverityError, fmt.Sprintf("The DMARC %s tag is invalid: %v", tag, err))
+			continue
+		}
+		for _, dest := range destinations {
+			if inBailiwick(dest, domain) {
+				continue
+			}
+			name := domain + "._report._dmarc." + dest
+			txts, err := c.lookupTXT(name)
+			if err != nil {
+				c.add("dmarc", SeverityWarning, fmt.Sprintf("Failed to look up %s: %v", name, err))
+				continue
+			}
+			authorised := false
+			for _, txt := range txts {
+				if strings.HasPrefix(strings.ToLower(strings.ReplaceAll(txt, " ", "")), "v=dmarc1") {
+					authorised = true
+				}
+			}
+			if !authorised {
+				c.add("dmarc", SeverityWarning, "The "+tag+" destination "+dest+" has not authorised receiving "+
+					"reports for the domain, so reports will not be sent there. It needs a record at "+name+".")
+			}
+		}
+	}
+
+	return record
+}
+
+// Returns the DMARC policy that is applied to all failing mail, or a blank string if there isn't one.
+func enforcedDMARCPolicy(record *TagRecord) string {
+	if record == nil {
+		return ""
+	}
+	if pct, ok := record.Tags["pct"]; ok && pct != "100" {
+		return ""
+	}
+	if p := record.Tags["p"]; p == "quarantine" || p == "reject" {
+		return p
+	}
+	return ""
+}
+
+// Checks a DKIM selector for a domain.
+func (c *mailChecker) checkDKIM(domain, selector string) *DKIMResult {
+	name := selector + "._domainkey." + domain
+	result := &DKIMResult{
+		TagRecord: TagRecord{Name: name, Tags: map[string]string{}},
+		Selector:  selector,
+	}
+	prefix := "DKIM selector " + selector
+
+	txts, err := c.lookupTXT(name)
+	if err != nil {
+		c.add("dkim", SeverityError, fmt.Sprintf("Failed to look up %s: %v", name, err))
+		return result
+	}
+	if len(txts) == 0 {
+		c.add("dkim", SeverityError, "No DKIM key was found for "+prefix+" at "+name+".")
+		return result
+	}
+	if len(txts) > 1 {
+		c.add("dkim", SeverityError, "Multiple records were found for "+prefix+", so signatures may fail to "+
+			"verify.")
+	}
+	result.Record = txts[0]
+	tags, err := parseTagList(txts[0])
+	if err != nil {
+		c.add("dkim", SeverityError, fmt.Sprintf("The record for %s is invalid: %v", prefix, err))
+		return result
+	}
+	result.Tags = tags
+
+	// Check the tags.
+	if v, ok := tags["v"]; ok && v != "DKIM1" {
+		c.add("dkim", SeverityError, "The version of "+prefix+" must be DKIM1.")
+	}
+	for _, flag := range strings.Split(tags["t"], ":") {
+		if strings.TrimSpace(flag) == "y" {
+			c.add("dkim", SeverityWarning, prefix+" is in testing mode, so receivers may treat failures as "+
+				"unsigned mail.")
+		}
+	}
+	result.KeyType = "rsa"
+	if k, ok := tags["k"]; ok {
+		result.KeyType = strings.ToLower(k)
+	}
+	p, ok := tags["p"]
+	if !ok {
+		c.add("dkim", SeverityError, prefix+" has no p tag, which is required.")
+		return result
+	}
+	p = strings.Join(strings.Fields(p), "")
+	if p == "" {
+		c.add("dkim", SeverityWarning, prefix+" has an empty key, which means it has been revoked.")
+		return result
+	}
+
+	// Check the key.
+	key, err := base64.StdEncoding.DecodeString(p)
+	if err != nil {
+		c.add("dkim", SeverityError, prefix+" has a key which is not valid base64.")
+		return result
+	}
+	switch result.KeyType {
+	case "rsa":
+		var pub *rsa.PublicKey
+		if parsed, err := x509.ParsePKIXPublicKey(key); err == nil {
+			pub, _ = parsed.(*rsa.PublicKey)
+		} else {
+			pub, _ = x509.ParsePKCS1PublicKey(key)
+		}
+		if pub == nil {
+			c.add("dkim", SeverityError, prefix+" has a key which is not a valid RSA public key.")
+			return result
+		}
+		result.KeySize = pub.N.BitLen()
+		switch {
+		case result.KeySize < 1024:
+			c.add("dkim", SeverityError, fmt.Sprintf("%s has a %d bit RSA key. Keys smaller than 1024 bits "+
+				"are rejected by most receivers.", prefix, result.KeySize))
+		case result.KeySize < 2048:
+			c.add("dkim", SeverityWarning, fmt.Sprintf("%s has a %d bit RSA key. A 2048 bit key is recommended.",
+				prefix, result.KeySize))
+		}
+	case "ed25519":
+		if len(key) != ed25519.PublicKeySize {
+			c.add("dkim", SeverityError, prefix+" has a key which is not a valid Ed25519 public key.")
+			return result
+		}
+		result.KeySize = 256
+	default:
+		c.add("dkim", SeverityError, prefix+" has an unknown key type "+result.KeyType+".")
+	}
+
+	return result
+}
+
+// Parses an MTA-STS policy file.
+func parseMTASTSPolicy(body string) (*MTASTSPolicy, error) {
+	policy := &MTASTSPolicy{MX: []string{}, MaxAge: -1}
+	for n, line := range strings.Split(body, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		i := strings.Index(line, ":")
+		if i == -1 {
+			// The line isn't included since the policy could be any page and the error is shown to the user.
+			return nil, fmt.Errorf("line %d is not a key and value", n+1)
+		}
+		key, value := strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+1:])
+		switch key {
+		case "version":
+			policy.Version = value
+		case "mode":
+			policy.Mode = value
+		case "max_age":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 || n > 31557600 {
+				return nil, errors.New("max_age must be a number between 0 and 31557600")
+			}
+			policy.MaxAge = n
+		case "mx":
+			policy.MX = append(policy.MX, strings.ToLower(value))
+		}
+	}
+
+	if policy.Version != "STSv1" {
+		return nil, errors.New("version must be STSv1")
+	}
+	switch policy.Mode {
+	case "enforce", "testing":
+		if len(policy.MX) == 0 {
+			return nil, errors.New("at least one mx is required unless the mode is none")
+		}
+	case "none":
+	default:
+		return nil, errors.New("mode must be enforce, testing or none")
+	}
+	if policy.MaxAge == -1 {
+		return nil, errors.New("max_age is required")
+	}
+	return policy, nil
+}
+
+// Returns if a MX hostname matches an MTA-STS mx pattern. A wildcard only matches a single label.
+func matchMTASTSPattern(pattern, host string) bool {
+	pattern = normaliseName(pattern)
+	host = normaliseName(host)
+	if strings.HasPrefix(pattern, "*.") {
+		i := strings.Index(host, ".")
+		return i != -1 && host[i+1:] == pattern[2:]
+	}
+	return pattern == host
+}
+
+// Checks the MTA-STS record and policy for a domain.
+func (c *mailChecker) checkMTASTS(domain string) *MTASTSResult {
+	record := c.findTagRecord("mta_sts", "_mta-sts."+domain, "v=STSv1")
+	if record == nil {
+		c.add("mta_sts", SeverityInfo, "MTA-STS is not set up, so senders can deliver mail without TLS if it is "+
+			"stripped.")
+		return nil
+	}
+	result := &MTASTSResult{Record: record}
+	if id := record.Tags["id"]; id == "" || len(id) > 32 || strings.Trim(id, "abcdefghijklmnopqrstuvwxyz"+
+		"ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789") != "" {
+		c.add("mta_sts", SeverityError, "The MTA-STS record must have an id tag of up to 32 letters and numbers.")
+	}
+
+	// Fetch and parse the policy.
+	url := "https://mta-sts." + domain + "/.well-known/mta-sts.txt"
+	body, err := c.fetchPolicy(url)
+	if err != nil {
+		c.add("mta_sts", SeverityError, fmt.Sprintf("Failed to fetch the MTA-STS policy from %s: %v", url, err))
+		return result
+	}
+	policy, err := parseMTASTSPolicy(body)
+	if err != nil {
+		c.add("mta_sts", SeverityError, fmt.Sprintf("The MTA-STS policy is invalid: %v", err))
+		return result
+	}
+	policy.URL = url
+	result.Policy = policy
+	switch policy.Mode {
+	case "testing":
+		c.add("mta_sts", SeverityInfo, "The MTA-STS policy is in testing mode, so failures are only reported.")
+	case "none":
+		c.add("mta_sts", SeverityInfo, "The MTA-STS policy mode is none, so it is not applied.")
+		return result
+	}
+	if policy.MaxAge < 86400 {
+		c.add("mta_sts", SeverityWarning, "The MTA-STS max_age is less than a day. A week or more is recommended.")
+	}
+
+	// Check the MX records are covered by the policy.
+	hosts, err := c.lookupMX(domain)
+	if err != nil {
+		c.add("mta_sts", SeverityWarning, fmt.Sprintf("Failed to look up the MX records: %v", err))
+		return result
+	}
+	for _, host := range hosts {
+		matched := false
+		for _, pattern := range policy.MX {
+			if matchMTASTSPattern(pattern, host) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			severity := SeverityError
+			if policy.Mode == "testing" {
+				severity = SeverityWarning
+			}
+			c.add("mta_sts", severity, "The MX host "+host+" is not allowed by the MTA-STS policy, so senders "+
+				"will refuse to deliver to it.")
+		}
+	}
+
+	return result
+}
+
+// Checks the TLS-RPT record for a domain.
+func (c *mailChecker) checkTLSRPT(domain string) *TagRecord {
+	record := c.findTagRecord("tls_rpt", "_smtp._tls."+domain, "v=TLSRPTv1")
+	if record == nil {
+		c.add("tls_rpt", SeverityInfo, "TLS-RPT is not set up, so you will not receive reports about TLS failures.")
+		return nil
+	}
+	rua, ok := record.Tags["rua"]
+	if !ok {
+		c.add("tls_rpt", SeverityError, "The TLS-RPT record has no rua tag, which is required.")
+	} else if _, err := parseReportURIs(rua, true); err != nil {
+		c.add("tls_rpt", SeverityError, fmt.Sprintf("The TLS-RPT rua tag is invalid: %v", err))
+	}
+	return record
+}
+
+// Checks the BIMI record for a domain.
+func (c *mailChecker) checkBIMI(domain string, dmarc *TagRecord) *TagRecord {
+	record := c.findTagRecord("bimi", "default._bimi."+domain, "v=BIMI1")
+	if record == nil {
+		c.add("bimi", SeverityInfo, "BIMI is not set up, so no logo will be shown next to your mail.")
+		return nil
+	}
+
+	// Check the logo and certificate locations.
+	l, ok := record.Tags["l"]
+	switch {
+	case !ok:
+		c.add("bimi", SeverityError, "The BIMI record has no l tag, which is required.")
+	case l == "":
+		c.add("bimi", SeverityInfo, "The BIMI record declines to publish a logo.")
+	case !strings.HasPrefix(strings.ToLower(l), "https://"):
+		c.add("bimi", SeverityError, "The BIMI logo must be served over HTTPS.")
+	case !strings.HasSuffix(strings.ToLower(l), ".svg"):
+		c.add("bimi", SeverityWarning, "The BIMI logo should be an SVG Tiny PS file.")
+	}
+	if a := record.Tags["a"]; a == "" {
+		c.add("bimi", SeverityInfo, "The BIMI record has no certificate, which some mailbox providers require "+
+			"before showing the logo.")
+	} else if !strings.HasPrefix(strings.ToLower(a), "https://") {
+		c.add("bimi", SeverityError, "The BIMI certificate must be served over HTTPS.")
+	}
+
+	// BIMI is only used if DMARC is enforced.
+	if enforcedDMARCPolicy(dmarc) == "" {
+		c.add("bimi", SeverityWarning, "BIMI requires a DMARC policy of quarantine or reject applied to all mail, "+
+			"so the logo will not be shown.")
+	}
+	return record
+}
+
+// Runs every check against the domain.
+func (c *mailChecker) check(domain string, dkimSelectors []string) *MailAuthReport {
+	report := &MailAuthReport{Domain: domain, DKIM: []*DKIMResult{}}
+	report.SPF = c.checkSPF(domain, 0, map[string]bool{})
+	report.DMARC = c.checkDMARC(domain)
+	if len(dkimSelectors) == 0 {
+		c.add("dkim", SeverityInfo, "No DKIM selectors were given. Selectors cannot be discovered, so DKIM "+
+			"was not checked.")
+	}
+	sort.Strings(dkimSelectors)
+	for _, selector := range dkimSelectors {
+		report.DKIM = append(report.DKIM, c.checkDKIM(domain, selector))
+	}
+	report.MTASTS = c.checkMTASTS(domain)
+	report.TLSRPT = c.checkTLSRPT(domain)
+	report.BIMI = c.checkBIMI(domain, report.DMARC)
+
+	report.Findings = c.findings
+	if report.Findings == nil {
+		report.Findings = []Finding{}
+	}
+	return report
+}
+
+// CheckMailAuth checks the SPF, DMARC, DKIM, MTA-STS, TLS-RPT and BIMI setup of a domain. DKIM selectors cannot be
+// discovered, so only the selectors given are checked.
+func CheckMailAuth(log *zap.Logger, dnsServer, domain string, dkimSelectors []string) (*MailAuthReport, error) {
+	domain = normaliseName(domain)
+	if _, ok := godns.IsDomainName(domain); !ok || domain == "" {
+		return nil, errors.New("invalid domain")
+	}
+	for _, v := range dkimSelectors {
+		if _, ok := godns.IsDomainName(v); !ok || v == "" {
+			return nil, errors.New("invalid DKIM selector: " + v)
+		}
+	}
+
+	return newMailChecker(log, dnsServer).check(domain, dkimSelectors), nil
+}
@@ -0,0 +1,582 @@
+package dns
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Returns a mail checker which answers TXT lookups from a map.
+func fakeMailChecker(txts map[string][]string) *mailChecker {
+	return &mailChecker{
+		lookupTXT: func(name string) ([]string, error) {
+			return txts[name], nil
+		},
+		lookupMX: func(string) ([]string, error) {
+			return []string{}, nil
+		},
+		fetchPolicy: func(string) (string, error) {
+			return "", nil
+		},
+	}
+}
+
+// Returns if any of the findings have the severity for the check.
+func hasFinding(findings []Finding, check string, severity Severity) bool {
+	for _, v := range findings {
+		if v.Check == check && v.Severity == severity {
+			return true
+		}
+	}
+	return false
+}
+
+func Test_parseSPFTerms(t *testing.T) {
+	tests := []struct {
+		name string
+
+		record  string
+		terms   []SPFTerm
+		wantErr string
+	}{
+		{
+			name:   "mechanisms and modifiers",
+			record: "v=spf1 ip4:192.0.2.0/24 -ip6:2001:db8::/32 a mx/24 include:_spf.example.net ~all",
+			terms: []SPFTerm{
+				{Qualifier: "+", Name: "ip4", Value: "192.0.2.0/24"},
+				{Qualifier: "-", Name: "ip6", Value: "2001:db8::/32"},
+				{Qualifier: "+", Name: "a"},
+				{Qualifier: "+", Name: "mx", Value: "/24"},
+				{Qualifier: "+", Name: "include", Value: "_spf.example.net"},
+				{Qualifier: "~", Name: "all"},
+			},
+		},
+		{
+			name:   "redirect",
+			record: "v=spf1 redirect=_spf.example.net",
+			terms:  []SPFTerm{{Name: "redirect", Value: "_spf.example.net"}},
+		},
+		{
+			name:    "ipv6 in ip4",
+			record:  "v=spf1 ip4:2001:db8::1 -all",
+			wantErr: "invalid ip4 address: 2001:db8::1",
+		},
+		{
+			name:    "unknown mechanism",
+			record:  "v=spf1 foo:example.com -all",
+			wantErr: "unknown mechanism: foo:example.com",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			terms, err := parseSPFTerms(tt.record)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.terms, terms)
+		})
+	}
+}
+
+func Test_mailChecker_checkSPF(t *testing.T) {
+	tests := []struct {
+		name string
+
+		txts        map[string][]string
+		lookupCount int
+		includes    int
+		severity    Severity
+	}{
+		{
+			name: "simple record",
+			txts: map[string][]string{
+				"example.com": {"v=spf1 ip4:192.0.2.1 -all"},
+			},
+			lookupCount: 0,
+			severity:    SeverityInfo,
+		},
+		{
+			name: "nested includes",
+			txts: map[string][]string{
+				"example.com":      {"v=spf1 include:_spf.example.com -all"},
+				"_spf.example.com": {"v=spf1 a mx include:_spf.example.net ~all"},
+				"_spf.example.net": {"v=spf1 ip4:192.0.2.0/24 ~all"},
+			},
+			lookupCount: 4,
+			includes:    1,
+			severity:    SeverityInfo,
+		},
+		{
+			name: "include loop",
+			txts: map[string][]string{
+				"example.com":      {"v=spf1 include:_spf.example.com -all"},
+				"_spf.example.com": {"v=spf1 include:example.com -all"},
+			},
+			lookupCount: 2,
+			includes:    1,
+			severity:    SeverityError,
+		},
+		{
+			name: "too many lookups",
+			txts: map[string][]string{
+				"example.com": {"v=spf1 a mx a:1.example.com a:2.example.com a:3.example.com a:4.example.com " +
+					"a:5.example.com a:6.example.com a:7.example.com a:8.example.com a:9.example.com -all"},
+			},
+			lookupCount: 11,
+			severity:    SeverityError,
+		},
+		{
+			name: "missing include",
+			txts: map[string][]string{
+				"example.com": {"v=spf1 include:_spf.example.com -all"},
+			},
+			lookupCount: 1,
+			includes:    1,
+			severity:    SeverityError,
+		},
+		{
+			name: "plus all",
+			txts: map[string][]string{
+				"example.com": {"v=spf1 +all"},
+			},
+			severity: SeverityError,
+		},
+		{
+			name: "redirect",
+			txts: map[string][]string{
+				"example.com":      {"v=spf1 redirect=_spf.example.com"},
+				"_spf.example.com": {"v=spf1 mx -all"},
+			},
+			lookupCount: 2,
+			includes:    1,
+			severity:    SeverityInfo,
+		},
+		{
+			name: "redirect ignored because of all",
+			txts: map[string][]string{
+				"example.com": {"v=spf1 a:1.example.com a:2.example.com a:3.example.com a:4.example.com " +
+					"a:5.example.com a:6.example.com a:7.example.com a:8.example.com a:9.example.com mx " +
+					"redirect=_spf.example.com -all"},
+				"_spf.example.com": {"v=spf1 mx -all"},
+			},
+			lookupCount: 10,
+			severity:    SeverityInfo,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := fakeMailChecker(tt.txts)
+			result := c.checkSPF("example.com", 0, map[string]bool{})
+			assert.Equal(t, tt.lookupCount, result.LookupCount)
+			assert.Len(t, result.Includes, tt.includes)
+			assert.True(t, hasFinding(c.findings, "spf", tt.severity))
+			if tt.severity == SeverityInfo {
+				assert.False(t, hasFinding(c.findings, "spf", SeverityError))
+				assert.False(t, hasFinding(c.findings, "spf", SeverityWarning))
+			}
+		})
+	}
+}
+
+func Test_mailChecker_checkDMARC(t *testing.T) {
+	tests := []struct {
+		name string
+
+		txts     map[string][]string
+		policy   string
+		severity Severity
+	}{
+		{
+			name: "enforced with reports",
+			txts: map[string][]string{
+				"_dmarc.example.com": {"v=DMARC1; p=reject; rua=mailto:dmarc@example.com"},
+			},
+			policy:   "reject",
+			severity: SeverityInfo,
+		},
+		{
+			name:     "no record",
+			txts:     map[string][]string{},
+			severity: SeverityWarning,
+		},
+		{
+			name: "partial percentage",
+			txts: map[string][]string{
+				"_dmarc.example.com": {"v=DMARC1; p=quarantine; pct=50; rua=mailto:dmarc@example.com"},
+			},
+			severity: SeverityWarning,
+		},
+		{
+			name: "unauthorised external reports",
+			txts: map[string][]string{
+				"_dmarc.example.com": {"v=DMARC1; p=reject; rua=mailto:dmarc@example.net"},
+			},
+			policy:   "reject",
+			severity: SeverityWarning,
+		},
+		{
+			name: "authorised external reports",
+			txts: map[string][]string{
+				"_dmarc.example.com":                     {"v=DMARC1; p=reject; rua=mailto:dmarc@example.net"},
+				"example.com._report._dmarc.example.net": {"v=DMARC1"},
+			},
+			policy:   "reject",
+			severity: SeverityInfo,
+		},
+		{
+			name: "invalid policy",
+			txts: map[string][]string{
+				"_dmarc.example.com": {"v=DMARC1; p=block"},
+			},
+			severity: SeverityError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := fakeMailChecker(tt.txts)
+			record := c.checkDMARC("example.com")
+			assert.Equal(t, tt.policy, enforcedDMARCPolicy(record))
+			assert.True(t, hasFinding(c.findings, "dmarc", tt.severity))
+			if tt.severity == SeverityInfo {
+				assert.False(t, hasFinding(c.findings, "dmarc", SeverityError))
+				assert.False(t, hasFinding(c.findings, "dmarc", SeverityWarning))
+			}
+		})
+	}
+}
+
+// Returns a base64 RSA public key with a modulus of the number of bits, in PKIX or PKCS #1 form. The key is
+// only used to check the size, so it doesn't need a matching private key.
+func fakeRSAKey(t *testing.T, bits int, pkcs1 bool) string {
+	n := new(big.Int).Lsh(big.NewInt(1), uint(bits-1))
+	n.Add(n, big.NewInt(1))
+	pub := &rsa.PublicKey{N: n, E: 65537}
+	if pkcs1 {
+		return base64.StdEncoding.EncodeToString(x509.MarshalPKCS1PublicKey(pub))
+	}
+	b, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.StdEncoding.EncodeToString(b)
+}
+
+func Test_mailChecker_checkDKIM(t *testing.T) {
+	const name = "mail._domainkey.example.com"
+	ed25519Key := base64.StdEncoding.EncodeToString(make([]byte, ed25519.PublicKeySize))
+	tests := []struct {
+		name string
+
+		txts     []string
+		keyType  string
+		keySize  int
+		severity Severity
+	}{
+		{
+			name:    "2048 bit rsa key",
+			txts:    []string{"v=DKIM1; k=rsa; p=" + fakeRSAKey(t, 2048, false)},
+			keyType: "rsa",
+			keySize: 2048,
+		},
+		{
+			name:    "pkcs1 rsa key without a key type",
+			txts:    []string{"v=DKIM1; p=" + fakeRSAKey(t, 2048, true)},
+			keyType: "rsa",
+			keySize: 2048,
+		},
+		{
+			name:     "1024 bit rsa key",
+			txts:     []string{"v=DKIM1; k=rsa; p=" + fakeRSAKey(t, 1024, false)},
+			keyType:  "rsa",
+			keySize:  1024,
+			severity: SeverityWarning,
+		},
+		{
+			name:     "512 bit rsa key",
+			txts:     []string{"v=DKIM1; k=rsa; p=" + fakeRSAKey(t, 512, false)},
+			keyType:  "rsa",
+			keySize:  512,
+			severity: SeverityError,
+		},
+		{
+			name:    "ed25519 key",
+			txts:    []string{"v=DKIM1; k=ed25519; p=" + ed25519Key},
+			keyType: "ed25519",
+			keySize: 256,
+		},
+		{
+			name:     "wrong length ed25519 key",
+			txts:     []string{"v=DKIM1; k=ed25519; p=" + base64.StdEncoding.EncodeToString([]byte("short"))},
+			keyType:  "ed25519",
+			severity: SeverityError,
+		},
+		{
+			name:    "key split with whitespace",
+			txts:    []string{"v=DKIM1; p=" + fakeRSAKey(t, 2048, false)[:100] + " " + fakeRSAKey(t, 2048, false)[100:]},
+			keyType: "rsa",
+			keySize: 2048,
+		},
+		{
+			name:     "invalid base64",
+			txts:     []string{"v=DKIM1; p=not*base64"},
+			keyType:  "rsa",
+			severity: SeverityError,
+		},
+		{
+			name:     "not an rsa key",
+			txts:     []string{"v=DKIM1; p=" + base64.StdEncoding.EncodeToString([]byte("garbage"))},
+			keyType:  "rsa",
+			severity: SeverityError,
+		},
+		{
+			name:     "revoked key",
+			txts:     []string{"v=DKIM1; p="},
+			keyType:  "rsa",
+			severity: SeverityWarning,
+		},
+		{
+			name:     "testing mode",
+			txts:     []string{"v=DKIM1; t=y; p=" + fakeRSAKey(t, 2048, false)},
+			keyType:  "rsa",
+			keySize:  2048,
+			severity: SeverityWarning,
+		},
+		{
+			name:     "unknown key type",
+			txts:     []string{"v=DKIM1; k=dsa; p=" + ed25519Key},
+			keyType:  "dsa",
+			severity: SeverityError,
+		},
+		{
+			name:     "missing key",
+			txts:     []string{"v=DKIM1; k=rsa"},
+			keyType:  "rsa",
+			severity: SeverityError,
+		},
+		{
+			name:     "wrong version",
+			txts:     []string{"v=DKIM2; p=" + fakeRSAKey(t, 2048, false)},
+			keyType:  "rsa",
+			keySize:  2048,
+			severity: SeverityError,
+		},
+		{
+			name:     "no record",
+			severity: SeverityError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := fakeMailChecker(map[string][]string{name: tt.txts})
+			result := c.checkDKIM("example.com", "mail")
+			assert.Equal(t, name, result.Name)
+			assert.Equal(t, tt.keyType, result.KeyType)
+			assert.Equal(t, tt.keySize, result.KeySize)
+			if tt.severity == "" {
+				assert.Empty(t, c.findings)
+			} else {
+				assert.True(t, hasFinding(c.findings, "dkim", tt.severity))
+			}
+		})
+	}
+}
+
+func Test_mailChecker_checkTLSRPT(t *testing.T) {
+	tests := []struct {
+		name string
+
+		txts     []string
+		found    bool
+		severity Severity
+	}{
+		{
+			name:  "mailto reports",
+			txts:  []string{"v=TLSRPTv1; rua=mailto:tlsrpt@example.com"},
+			found: true,
+		},
+		{
+			name:  "https reports",
+			txts:  []string{"v=TLSRPTv1; rua=https://reports.example.com/tlsrpt"},
+			found: true,
+		},
+		{
+			name:     "no record",
+			severity: SeverityInfo,
+		},
+		{
+			name:     "missing rua",
+			txts:     []string{"v=TLSRPTv1;"},
+			found:    true,
+			severity: SeverityError,
+		},
+		{
+			name:     "invalid rua",
+			txts:     []string{"v=TLSRPTv1; rua=ftp://example.com"},
+			found:    true,
+			severity: SeverityError,
+		},
+		{
+			name:     "multiple records",
+			txts:     []string{"v=TLSRPTv1; rua=mailto:a@example.com", "v=TLSRPTv1; rua=mailto:b@example.com"},
+			found:    true,
+			severity: SeverityError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := fakeMailChecker(map[string][]string{"_smtp._tls.example.com": tt.txts})
+			record := c.checkTLSRPT("example.com")
+			assert.Equal(t, tt.found, record != nil)
+			if tt.severity == "" {
+				assert.Empty(t, c.findings)
+			} else {
+				assert.True(t, hasFinding(c.findings, "tls_rpt", tt.severity))
+			}
+		})
+	}
+}
+
+func Test_mailChecker_checkBIMI(t *testing.T) {
+	enforced := &TagRecord{Tags: map[string]string{"v": "DMARC1", "p": "reject"}}
+	tests := []struct {
+		name string
+
+		txts     []string
+		dmarc    *TagRecord
+		found    bool
+		severity Severity
+	}{
+		{
+			name:  "logo and certificate",
+			txts:  []string{"v=BIMI1; l=https://example.com/logo.svg; a=https://example.com/vmc.pem"},
+			dmarc: enforced,
+			found: true,
+		},
+		{
+			name:     "no record",
+			dmarc:    enforced,
+			severity: SeverityInfo,
+		},
+		{
+			name:     "no certificate",
+			txts:     []string{"v=BIMI1; l=https://example.com/logo.svg"},
+			dmarc:    enforced,
+			found:    true,
+			severity: SeverityInfo,
+		},
+		{
+			name:     "missing logo",
+			txts:     []string{"v=BIMI1; a=https://example.com/vmc.pem"},
+			dmarc:    enforced,
+			found:    true,
+			severity: SeverityError,
+		},
+		{
+			name:     "logo over http",
+			txts:     []string{"v=BIMI1; l=http://example.com/logo.svg; a=https://example.com/vmc.pem"},
+			dmarc:    enforced,
+			found:    true,
+			severity: SeverityError,
+		},
+		{
+			name:     "logo not an svg",
+			txts:     []string{"v=BIMI1; l=https://example.com/logo.png; a=https://example.com/vmc.pem"},
+			dmarc:    enforced,
+			found:    true,
+			severity: SeverityWarning,
+		},
+		{
+			name:     "certificate over http",
+			txts:     []string{"v=BIMI1; l=https://example.com/logo.svg; a=http://example.com/vmc.pem"},
+			dmarc:    enforced,
+			found:    true,
+			severity: SeverityError,
+		},
+		{
+			name:     "dmarc not enforced",
+			txts:     []string{"v=BIMI1; l=https://example.com/logo.svg; a=https://example.com/vmc.pem"},
+			dmarc:    &TagRecord{Tags: map[string]string{"v": "DMARC1", "p": "none"}},
+			found:    true,
+			severity: SeverityWarning,
+		},
+		{
+			name:     "no dmarc",
+			txts:     []string{"v=BIMI1; l=https://example.com/logo.svg; a=https://example.com/vmc.pem"},
+			found:    true,
+			severity: SeverityWarning,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := fakeMailChecker(map[string][]string{"default._bimi.example.com": tt.txts})
+			record := c.checkBIMI("example.com", tt.dmarc)
+			assert.Equal(t, tt.found, record != nil)
+			if tt.severity == "" {
+				assert.Empty(t, c.findings)
+			} else {
+				assert.True(t, hasFinding(c.findings, "bimi", tt.severity))
+			}
+		})
+	}
+}
+
+func Test_parseMTASTSPolicy(t *testing.T) {
+	tests := []struct {
+		name string
+
+		body    string
+		policy  *MTASTSPolicy
+		wantErr string
+	}{
+		{
+			name: "valid policy",
+			body: "version: STSv1\r\nmode: enforce\r\nmx: mail.example.com\r\nmx: *.example.net\r\nmax_age: 604800\r\n",
+			policy: &MTASTSPolicy{
+				Version: "STSv1",
+				Mode:    "enforce",
+				MaxAge:  604800,
+				MX:      []string{"mail.example.com", "*.example.net"},
+			},
+		},
+		{
+			name:    "missing mx",
+			body:    "version: STSv1\nmode: enforce\nmax_age: 604800\n",
+			wantErr: "at least one mx is required unless the mode is none",
+		},
+		{
+			name:    "wrong version",
+			body:    "version: STSv2\nmode: none\nmax_age: 604800\n",
+			wantErr: "version must be STSv1",
+		},
+		{
+			name:    "not a policy",
+			body:    "version: STSv1\n<html><body>secret internal page</body></html>\n",
+			wantErr: "line 2 is not a key and value",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, err := parseMTASTSPolicy(tt.body)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.policy, policy)
+		})
+	}
+}
+
+func Test_matchMTASTSPattern(t *testing.T) {
+	assert.True(t, matchMTASTSPattern("mail.example.com", "MAIL.example.com."))
+	assert.True(t, matchMTASTSPattern("*.example.com", "mx1.example.com"))
+	assert.False(t, matchMTASTSPattern("*.example.com", "a.mx1.example.com"))
+	assert.False(t, matchMTASTSPattern("*.example.com", "example.com"))
+}
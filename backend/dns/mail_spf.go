@@ -0,0 +1,230 @@
+package dns
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// Defines the maximum number of DNS lookups an SPF evaluation can cause, as described in RFC 7208 section 4.6.4.
+const spfLookupLimit = 10
+
+// Defines how deep includes will be expanded before giving up.
+const spfMaxDepth = 10
+
+// SPFTerm is a mechanism or modifier in an SPF record.
+type SPFTerm struct {
+	// Qualifier is used to define the qualifier of a mechanism, such as "-" or "~". This is blank for modifiers.
+	Qualifier string `json:"qualifier,omitempty"`
+
+	// Name is used to define the name of the mechanism or modifier, such as "include" or "redirect".
+	Name string `json:"name"`
+
+	// Value is used to define the value of the term, such as the domain of an include.
+	Value string `json:"value,omitempty"`
+}
+
+// SPFResult is the result of parsing and expanding an SPF record.
+type SPFResult struct {
+	// Domain is used to define the domain the record was found at.
+	Domain string `json:"domain"`
+
+	// Record is used to define the raw SPF record.
+	Record string `json:"record"`
+
+	// Terms is used to define the parsed terms of the record.
+	Terms []SPFTerm `json:"terms"`
+
+	// Includes is used to define the expanded records of any include or redirect terms.
+	Includes []*SPFResult `json:"includes"`
+
+	// LookupCount is used to define the number of DNS lookups this record causes, including any includes.
+	LookupCount int `json:"lookup_count"`
+}
+
+// Finds the SPF records in a set of TXT records.
+func findSPFRecords(txts []string) []string {
+	records := []string{}
+	for _, v := range txts {
+		lower := strings.ToLower(v)
+		if lower == "v=spf1" || strings.HasPrefix(lower, "v=spf1 ") {
+			records = append(records, v)
+		}
+	}
+	return records
+}
+
+// Parses the terms of an SPF record.
+func parseSPFTerms(record string) ([]SPFTerm, error) {
+	terms := []SPFTerm{}
+	for _, v := range strings.Fields(record)[1:] {
+		// Handle modifiers.
+		if i := strings.Index(v, "="); i != -1 && !strings.ContainsAny(v[:i], ":/") {
+			terms = append(terms, SPFTerm{Name: strings.ToLower(v[:i]), Value: v[i+1:]})
+			continue
+		}
+
+		// Handle mechanisms.
+		term := SPFTerm{Qualifier: "+"}
+		if strings.ContainsAny(v[:1], "+-~?") {
+			term.Qualifier = v[:1]
+			v = v[1:]
+		}
+		name := v
+		if i := strings.IndexAny(v, ":/"); i != -1 {
+			name = v[:i]
+			term.Value = strings.TrimPrefix(v[i:], ":")
+		}
+		term.Name = strings.ToLower(name)
+		switch term.Name {
+		case "all":
+			if term.Value != "" {
+				return nil, fmt.Errorf("the all mechanism does not take a value: %s", v)
+			}
+		case "include", "exists":
+			if term.Value == "" {
+				return nil, fmt.Errorf("the %s mechanism requires a domain: %s", term.Name, v)
+			}
+		case "ip4", "ip6":
+			value := term.Value
+			if !strings.Contains(value, "/") {
+				value += map[string]string{"ip4": "/32", "ip6": "/128"}[term.Name]
+			}
+			ip, _, err := net.ParseCIDR(value)
+			if err != nil || (term.Name == "ip4") != (ip.To4() != nil) {
+				return nil, fmt.Errorf("invalid %s address: %s", term.Name, term.Value)
+			}
+		case "a", "mx", "ptr":
+		default:
+			return nil, fmt.Errorf("unknown mechanism: %s", v)
+		}
+		terms = append(terms, term)
+	}
+	return terms, nil
+}
+
+// Returns if the term causes a DNS lookup when evaluated.
+func (t SPFTerm) causesLookup() bool {
+	switch t.Name {
+	case "include", "a", "mx", "ptr", "exists", "redirect":
+		return true
+	}
+	return false
+}
+
+// Fetches, parses and expands the SPF record for a domain. Every problem found is added to the findings.
+func (c *mailChecker) checkSPF(domain string, depth int, seen map[string]bool) *SPFResult {
+	result := &SPFResult{Domain: domain, Terms: []SPFTerm{}, Includes: []*SPFResult{}}
+	isRoot := depth == 0
+	if depth > spfMaxDepth {
+		c.add("spf", SeverityError, "SPF includes are nested too deeply at "+domain+".")
+		return result
+	}
+	if seen[strings.ToLower(domain)] {
+		c.add("spf", SeverityError, "SPF include loop detected at "+domain+".")
+		return result
+	}
+	seen[strings.ToLower(domain)] = true
+	defer delete(seen, strings.ToLower(domain))
+
+	// Get the record.
+	txts, err := c.lookupTXT(domain)
+	if err != nil {
+		c.add("spf", SeverityError, fmt.Sprintf("Failed to look up the SPF record for %s: %v", domain, err))
+		return result
+	}
+	records := findSPFRecords(txts)
+	switch {
+	case len(records) == 0 && isRoot:
+		c.add("spf", SeverityWarning, "No SPF record was found. Receivers cannot tell which servers may send mail "+
+			"for the domain.")
+		return nil
+	case len(records) == 0:
+		c.add("spf", SeverityError, "The SPF record includes "+domain+", which has no SPF record. This is a "+
+			"permanent error.")
+		return result
+	case len(records) > 1:
+		c.add("spf", SeverityError, "Multiple SPF records were found for "+domain+". This is a permanent error.")
+	}
+	result.Record = records[0]
+
+	// Parse the record.
+	terms, err := parseSPFTerms(result.Record)
+	if err != nil {
+		c.add("spf", SeverityError, fmt.Sprintf("The SPF record for %s is invalid: %v", domain, err))
+		return result
+	}
+	result.Terms = terms
+
+	// Go through each of the terms.
+	var all *SPFTerm
+	var redirect string
+	for i, term := range terms {
+		// The redirect is only counted if it is followed, which is worked out after all of the terms.
+		if term.causesLookup() && term.Name != "redirect" {
+			result.LookupCount++
+		}
+		if strings.Contains(term.Value, "%") {
+			c.add("spf", SeverityInfo, "The SPF record for "+domain+" uses macros, which are not expanded.")
+			continue
+		}
+		switch term.Name {
+		case "all":
+			all = &terms[i]
+		case "redirect":
+			redirect = term.Value
+		case "ptr":
+			c.add("spf", SeverityWarning, "The SPF record for "+domain+" uses the ptr mechanism, which is "+
+				"deprecated by RFC 7208 and may be ignored by receivers.")
+		case "include":
+			included := c.checkSPF(term.Value, depth+1, seen)
+			if included != nil {
+				result.Includes = append(result.Includes, included)
+				result.LookupCount += included.LookupCount
+			}
+		}
+	}
+
+	// Handle the redirect. This is ignored if there is an all mechanism.
+	if redirect != "" {
+		if all != nil {
+			c.add("spf", SeverityInfo, "The SPF record for "+domain+" has both all and redirect. The redirect is "+
+				"ignored.")
+		} else {
+			result.LookupCount++
+			if !strings.Contains(redirect, "%") {
+				redirected := c.checkSPF(redirect, depth+1, seen)
+				if redirected != nil {
+					result.Includes = append(result.Includes, redirected)
+					result.LookupCount += redirected.LookupCount
+				}
+			}
+		}
+	}
+
+	// Check the all mechanism on the top level record.
+	if isRoot {
+		switch {
+		case all == nil && redirect == "":
+			c.add("spf", SeverityWarning, "The SPF record has no all mechanism, so mail from other servers is "+
+				"treated as neutral.")
+		case all == nil:
+		case all.Qualifier == "+":
+			c.add("spf", SeverityError, "The SPF record ends in +all, which allows any server to send mail for "+
+				"the domain.")
+		case all.Qualifier == "?":
+			c.add("spf", SeverityWarning, "The SPF record ends in ?all, which treats mail from other servers as "+
+				"neutral.")
+		}
+
+		if result.LookupCount > spfLookupLimit {
+			c.add("spf", SeverityError, fmt.Sprintf("The SPF record causes %d DNS lookups, which is more than "+
+				"the limit of %d. Receivers will treat this as a permanent error.", result.LookupCount, spfLookupLimit))
+		} else {
+			c.add("spf", SeverityInfo, fmt.Sprintf("The SPF record causes %d of the %d allowed DNS lookups.",
+				result.LookupCount, spfLookupLimit))
+		}
+	}
+
+	return result
+}
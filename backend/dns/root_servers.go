@@ -1,30 +1,210 @@
 package dns
 
-import "sync/atomic"
+import (
+	"bytes"
+	_ "embed"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
 
-// RootServers is a slice of root servers.
-var RootServers []string
+	godns "github.com/miekg/dns"
+	"go.uber.org/zap"
+)
 
-// Create the slice.
+//go:embed named.root
+var embeddedRootHints []byte
+
+// RootServer is used to define a root server and its addresses.
+type RootServer struct {
+	// Name is used to define the fully qualified name of the root server.
+	Name string
+
+	// IPv4 is used to define the IPv4 address of the root server.
+	IPv4 net.IP
+
+	// IPv6 is used to define the IPv6 address of the root server.
+	IPv6 net.IP
+}
+
+// Letter returns the letter of the root server, such as "a" for a.root-servers.net.
+func (r RootServer) Letter() string {
+	return strings.SplitN(strings.ToLower(r.Name), ".", 2)[0]
+}
+
+// Address returns the address which should be used to query the root server and its address family.
+func (r RootServer) Address() (string, string) {
+	if r.IPv6 != nil && (rootServersIPv6 || r.IPv4 == nil) {
+		return r.IPv6.String(), "ipv6"
+	}
+	if r.IPv4 != nil {
+		return r.IPv4.String(), "ipv4"
+	}
+
+	// Fallback to the system resolver if there is no address in the hints.
+	return r.Name, ""
+}
+
+// RootInfo is used to define which root server a trace started from.
+type RootInfo struct {
+	// Letter is used to define the letter of the root server.
+	Letter string `json:"letter"`
+
+	// Family is used to define the address family used to reach the root server.
+	Family string `json:"family"`
+}
+
+var (
+	// Defines the root servers and the lock for replacing them.
+	rootServers     []RootServer
+	rootServersLock sync.RWMutex
+
+	// Defines if the IPv6 addresses of the root servers should be used.
+	rootServersIPv6 bool
+)
+
+// Load the embedded root hints.
 func init() {
-	chars := []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j", "k", "l", "m"}
-	RootServers = make([]string, len(chars))
-	for i, c := range chars {
-		RootServers[i] = c + ".root-servers.net."
+	servers, err := parseRootHints(bytes.NewReader(embeddedRootHints), "named.root")
+	if err != nil {
+		panic(err)
+	}
+	rootServers = servers
+}
+
+// Parses a root hints file in the named.root format.
+func parseRootHints(r io.Reader, filename string) ([]RootServer, error) {
+	names := []string{}
+	addresses := map[string]*RootServer{}
+	getServer := func(name string) *RootServer {
+		name = strings.ToLower(name)
+		s, ok := addresses[name]
+		if !ok {
+			s = &RootServer{Name: name}
+			addresses[name] = s
+		}
+		return s
+	}
+
+	zp := godns.NewZoneParser(r, ".", filename)
+	for rr, ok := zp.Next(); ok; rr, ok = zp.Next() {
+		switch v := rr.(type) {
+		case *godns.NS:
+			if v.Hdr.Name == "." {
+				names = append(names, strings.ToLower(v.Ns))
+			}
+		case *godns.A:
+			getServer(v.Hdr.Name).IPv4 = v.A
+		case *godns.AAAA:
+			getServer(v.Hdr.Name).IPv6 = v.AAAA
+		}
+	}
+	if err := zp.Err(); err != nil {
+		return nil, err
+	}
+	if len(names) == 0 {
+		return nil, errors.New("no root servers found in root hints")
+	}
+
+	servers := make([]RootServer, len(names))
+	for i, name := range names {
+		servers[i] = *getServer(name)
+	}
+	return servers, nil
+}
+
+// Returns the current root servers.
+func getRootServers() []RootServer {
+	rootServersLock.RLock()
+	defer rootServersLock.RUnlock()
+	return rootServers
+}
+
+// Replaces the current root servers.
+func setRootServers(servers []RootServer) {
+	rootServersLock.Lock()
+	rootServers = servers
+	rootServersLock.Unlock()
+}
+
+// Asks a root server for the current root NS set and their addresses.
+func primeRootServers(log *zap.Logger) ([]RootServer, error) {
+	addr, _ := NextRootServer().Address()
+	msg, err := rawQuery(log, nameserverAddr(addr), godns.TypeNS, ".", queryOptions{NoRecursion: true, NoCache: true})
+	if err != nil {
+		return nil, err
+	}
+
+	// Build a zone from the response so it can be parsed like the hints.
+	b := strings.Builder{}
+	for _, section := range [][]godns.RR{msg.Answer, msg.Extra} {
+		for _, rr := range section {
+			if rr.Header().Rrtype != godns.TypeOPT {
+				b.WriteString(rr.String() + "\n")
+			}
+		}
+	}
+	servers, err := parseRootHints(strings.NewReader(b.String()), "priming response")
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range servers {
+		if v.IPv4 == nil && v.IPv6 == nil {
+			return nil, errors.New("priming response is missing the address of " + v.Name)
+		}
+	}
+	return servers, nil
+}
+
+// InitRootServers configures the root servers from the environment. ROOT_HINTS_FILE can be set to the path of a
+// named.root file to use instead of the embedded one, ROOT_SERVERS_IPV6 can be set to true to query the roots
+// over IPv6, and ROOT_PRIMING can be set to true to fetch the current root servers from the roots on startup.
+func InitRootServers(log *zap.Logger) error {
+	rootServersIPv6 = os.Getenv("ROOT_SERVERS_IPV6") == "true"
+
+	// Handle the root hints override.
+	if path := os.Getenv("ROOT_HINTS_FILE"); path != "" {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		servers, err := parseRootHints(f, path)
+		if err != nil {
+			return err
+		}
+		setRootServers(servers)
+		log.Info("loaded root hints", zap.String("path", path), zap.Int("servers", len(servers)))
+	}
+
+	// Handle priming. If this fails, we can carry on with the hints.
+	if os.Getenv("ROOT_PRIMING") == "true" {
+		servers, err := primeRootServers(log)
+		if err != nil {
+			log.Warn("failed to prime root servers, using root hints", zap.Error(err))
+			return nil
+		}
+		setRootServers(servers)
+		log.Info("primed root servers", zap.Int("servers", len(servers)))
 	}
+	return nil
 }
 
 // Defines the root server index.
 var rootServerIndex uintptr
 
 // NextRootServer returns the next root server along, looping back around.
-func NextRootServer() string {
+func NextRootServer() RootServer {
+	servers := getRootServers()
 	new_ := atomic.AddUintptr(&rootServerIndex, 1)
-	if new_ >= uintptr(len(RootServers)) {
+	if new_ >= uintptr(len(servers)) {
 		// In the event we hit this, we should wrap around to zero.
 		// We may hit zero a couple times with this, but it's close enough.
 		atomic.StoreUintptr(&rootServerIndex, 0)
-		return RootServers[0]
+		return servers[0]
 	}
-	return RootServers[new_]
+	return servers[new_]
 }
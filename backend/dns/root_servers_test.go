@@ -0,0 +1,27 @@
+package dns
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_parseRootHints(t *testing.T) {
+	servers, err := parseRootHints(bytes.NewReader(embeddedRootHints), "named.root")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, servers, 13)
+	for _, v := range servers {
+		assert.NotNil(t, v.IPv4, v.Name)
+		assert.NotNil(t, v.IPv6, v.Name)
+	}
+	assert.Equal(t, "a.root-servers.net.", servers[0].Name)
+	assert.Equal(t, "a", servers[0].Letter())
+	assert.Equal(t, "198.41.0.4", servers[0].IPv4.String())
+
+	_, err = parseRootHints(strings.NewReader("; nothing here\n"), "empty")
+	assert.Error(t, err)
+}
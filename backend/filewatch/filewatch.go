@@ -0,0 +1,45 @@
+// Package filewatch reloads data files when they change.
+package filewatch
+
+import (
+	"os"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Watch calls load with the path of a file, and then calls it again whenever the modification time of the file
+// changes. The file is checked every interval. The first load happens before this returns and its error is
+// returned. Errors from later loads are logged, and the load is tried again at the next interval.
+func Watch(log *zap.Logger, path string, interval time.Duration, load func(path string) error) error {
+	reload := func() (time.Time, error) {
+		info, err := os.Stat(path)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if err := load(path); err != nil {
+			return time.Time{}, err
+		}
+		return info.ModTime(), nil
+	}
+	modTime, err := reload()
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		for range time.Tick(interval) {
+			info, err := os.Stat(path)
+			if err != nil || info.ModTime().Equal(modTime) {
+				continue
+			}
+			newModTime, err := reload()
+			if err != nil {
+				log.Warn("failed to reload file", zap.String("path", path), zap.Error(err))
+				continue
+			}
+			modTime = newModTime
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,91 @@
+package filewatch
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestWatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.txt")
+	if err := os.WriteFile(path, []byte("first"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The first load should happen before Watch returns.
+	lock := sync.Mutex{}
+	loaded := []string{}
+	err := Watch(zap.NewNop(), path, 5*time.Millisecond, func(path string) error {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		lock.Lock()
+		loaded = append(loaded, string(b))
+		lock.Unlock()
+		return nil
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	lock.Lock()
+	assert.Equal(t, []string{"first"}, loaded)
+	lock.Unlock()
+
+	// Changing the file should load it again.
+	if err := os.WriteFile(path, []byte("second"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+	assert.Eventually(t, func() bool {
+		lock.Lock()
+		defer lock.Unlock()
+		return len(loaded) == 2 && loaded[1] == "second"
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestWatch_errors(t *testing.T) {
+	tests := []struct {
+		name string
+
+		create  bool
+		loadErr error
+		wantErr string
+	}{
+		{
+			name:    "missing file",
+			wantErr: "no such file or directory",
+		},
+		{
+			name:    "load error",
+			create:  true,
+			loadErr: errors.New("invalid data"),
+			wantErr: "invalid data",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "data.txt")
+			if tt.create {
+				if err := os.WriteFile(path, []byte("data"), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			err := Watch(zap.NewNop(), path, time.Hour, func(string) error {
+				return tt.loadErr
+			})
+			if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), tt.wantErr)
+			}
+		})
+	}
+}
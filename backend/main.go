@@ -15,7 +15,10 @@ import (
 	"github.com/gin-gonic/contrib/static"
 	"github.com/gin-gonic/gin"
 	api "github.com/krystal/krystal-network-tools/backend/api_v1"
+	"github.com/krystal/krystal-network-tools/backend/asinfo"
+	"github.com/krystal/krystal-network-tools/backend/community"
 	"github.com/krystal/krystal-network-tools/backend/dns"
+	"github.com/krystal/krystal-network-tools/backend/rpki"
 	pingttl "github.com/strideynet/go-ping-ttl"
 	"go.uber.org/zap"
 )
@@ -109,7 +112,26 @@ func main() {
 	r.Use(ginzap.Ginzap(logger, time.RFC3339, true))
 	r.Use(ginzap.RecoveryWithZap(logger, true))
 	g := r.Group("/v1")
-	api.Init(g, logger, dns.GetCachedDNSServer(logger), pinger)
+	if err := dns.InitRootServers(logger); err != nil {
+		logger.Fatal("failed to initialize root servers", zap.Error(err))
+	}
+	cachedDnsServer := dns.GetCachedDNSServer(logger)
+	rpkiValidator, err := rpki.InitValidator(logger)
+	if err != nil {
+		logger.Fatal("failed to initialize rpki validator", zap.Error(err))
+	}
+	communities, err := community.InitDictionary(logger)
+	if err != nil {
+		logger.Fatal("failed to initialize bgp community dictionary", zap.Error(err))
+	}
+	asNames, err := asinfo.InitDatabase(logger)
+	if err != nil {
+		logger.Fatal("failed to initialize as names", zap.Error(err))
+	}
+	api.Init(
+		g, logger, cachedDnsServer, dns.GetCompareResolvers(logger, cachedDnsServer), pinger, rpkiValidator,
+		communities, asNames,
+	)
 
 	// Build the listener.
 	httpsHost := os.Getenv("HTTPS_HOST")
@@ -0,0 +1,89 @@
+package rpki
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/krystal/krystal-network-tools/backend/filewatch"
+	"go.uber.org/zap"
+)
+
+// jsonASN is an AS number in a VRP file. rpki-client writes these as numbers and Routinator writes them as
+// strings such as "AS13335".
+type jsonASN uint32
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (a *jsonASN) UnmarshalJSON(b []byte) error {
+	s := strings.Trim(string(b), `"`)
+	s = strings.TrimPrefix(strings.ToUpper(s), "AS")
+	n, err := strconv.ParseUint(s, 10, 32)
+	if err != nil {
+		return fmt.Errorf("invalid asn: %s", b)
+	}
+	*a = jsonASN(n)
+	return nil
+}
+
+// jsonVRPFile is the JSON format used by rpki-client and Routinator.
+type jsonVRPFile struct {
+	ROAs []struct {
+		ASN       jsonASN `json:"asn"`
+		Prefix    string  `json:"prefix"`
+		MaxLength int     `json:"maxLength"`
+		TA        string  `json:"ta"`
+	} `json:"roas"`
+}
+
+// ParseVRPs parses VRPs in the JSON format used by rpki-client and Routinator.
+func ParseVRPs(r io.Reader) ([]VRP, error) {
+	var file jsonVRPFile
+	if err := json.NewDecoder(r).Decode(&file); err != nil {
+		return nil, err
+	}
+
+	vrps := make([]VRP, len(file.ROAs))
+	for i, roa := range file.ROAs {
+		prefix, err := netip.ParsePrefix(roa.Prefix)
+		if err != nil {
+			return nil, err
+		}
+		if roa.MaxLength == 0 {
+			roa.MaxLength = prefix.Bits()
+		}
+		if roa.MaxLength < prefix.Bits() || roa.MaxLength > prefix.Addr().BitLen() {
+			return nil, fmt.Errorf("invalid max length for %s: %d", roa.Prefix, roa.MaxLength)
+		}
+		vrps[i] = VRP{Prefix: prefix, MaxLength: roa.MaxLength, ASN: uint32(roa.ASN), TA: roa.TA}
+	}
+	return vrps, nil
+}
+
+// Loads the VRPs from a file.
+func loadFile(path string) ([]VRP, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseVRPs(f)
+}
+
+// WatchFile loads the VRPs from a file into the validator, and then reloads them whenever the file is changed.
+// The first load happens before this returns.
+func WatchFile(log *zap.Logger, v *Validator, path string, interval time.Duration) error {
+	return filewatch.Watch(log, path, interval, func(path string) error {
+		vrps, err := loadFile(path)
+		if err != nil {
+			return err
+		}
+		v.Replace(vrps)
+		log.Info("loaded rpki vrps", zap.String("path", path), zap.Int("vrps", len(vrps)))
+		return nil
+	})
+}
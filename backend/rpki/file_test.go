@@ -0,0 +1,53 @@
+package rpki
+
+import (
+	"net/netip"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseVRPs(t *testing.T) {
+	tests := []struct {
+		name string
+
+		json    string
+		vrps    []VRP
+		wantErr string
+	}{
+		{
+			name: "rpki-client",
+			json: `{"metadata": {"buildtime": "2022-03-01T00:00:00Z"}, "roas": [
+				{"asn": 13335, "prefix": "1.1.1.0/24", "maxLength": 24, "ta": "apnic", "expires": 1646438400}
+			]}`,
+			vrps: []VRP{{Prefix: netip.MustParsePrefix("1.1.1.0/24"), MaxLength: 24, ASN: 13335, TA: "apnic"}},
+		},
+		{
+			name: "routinator",
+			json: `{"roas": [{"asn": "AS13335", "prefix": "2606:4700::/32", "maxLength": 48, "ta": "arin"}]}`,
+			vrps: []VRP{{Prefix: netip.MustParsePrefix("2606:4700::/32"), MaxLength: 48, ASN: 13335, TA: "arin"}},
+		},
+		{
+			name:    "invalid max length",
+			json:    `{"roas": [{"asn": 13335, "prefix": "1.1.1.0/24", "maxLength": 16}]}`,
+			wantErr: "invalid max length for 1.1.1.0/24: 16",
+		},
+		{
+			name:    "invalid asn",
+			json:    `{"roas": [{"asn": "ASX", "prefix": "1.1.1.0/24", "maxLength": 24}]}`,
+			wantErr: `invalid asn: "ASX"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vrps, err := ParseVRPs(strings.NewReader(tt.json))
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.vrps, vrps)
+		})
+	}
+}
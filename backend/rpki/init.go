@@ -0,0 +1,29 @@
+package rpki
+
+import (
+	"os"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Defines how often the VRP file is checked for changes.
+const fileWatchInterval = 5 * time.Minute
+
+// InitValidator creates a validator from the environment. RPKI_VRP_FILE can be set to the path of a VRP file in
+// the JSON format used by rpki-client and Routinator, or RPKI_RTR_SERVER can be set to the host:port of an RTR
+// cache. Nil is returned if neither is set.
+func InitValidator(log *zap.Logger) (*Validator, error) {
+	v := NewValidator()
+	if path := os.Getenv("RPKI_VRP_FILE"); path != "" {
+		if err := WatchFile(log, v, path, fileWatchInterval); err != nil {
+			return nil, err
+		}
+		return v, nil
+	}
+	if addr := os.Getenv("RPKI_RTR_SERVER"); addr != "" {
+		RunRTR(log, v, addr)
+		return v, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,292 @@
+package rpki
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Defines the RTR PDU types, as described in RFC 8210 section 5.
+const (
+	pduSerialNotify  = 0
+	pduSerialQuery   = 1
+	pduResetQuery    = 2
+	pduCacheResponse = 3
+	pduIPv4Prefix    = 4
+	pduIPv6Prefix    = 6
+	pduEndOfData     = 7
+	pduCacheReset    = 8
+	pduRouterKey     = 9
+	pduErrorReport   = 10
+)
+
+// Defines the error code sent by a cache which does not support the protocol version.
+const rtrUnsupportedVersion = 4
+
+// Defines the maximum size of a PDU that will be read.
+const maxPDUSize = 64 * 1024
+
+// Defines the timers used when the cache does not send its own, as recommended in RFC 8210 section 6.
+const (
+	defaultRTRRefresh = time.Hour
+	defaultRTRRetry   = 10 * time.Minute
+)
+
+// rtrPDU is a single PDU from the cache.
+type rtrPDU struct {
+	version uint8
+	pduType uint8
+	session uint16
+	body    []byte
+}
+
+// rtrErrorReport is an error report PDU sent by the cache.
+type rtrErrorReport struct {
+	code uint16
+	text string
+}
+
+func (e *rtrErrorReport) Error() string {
+	return fmt.Sprintf("rtr error report %d: %s", e.code, e.text)
+}
+
+// Reads a single PDU.
+func readPDU(r io.Reader) (*rtrPDU, error) {
+	header := make([]byte, 8)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	length := binary.BigEndian.Uint32(header[4:])
+	if length < 8 || length > maxPDUSize {
+		return nil, fmt.Errorf("invalid rtr pdu length %d", length)
+	}
+	pdu := &rtrPDU{
+		version: header[0],
+		pduType: header[1],
+		session: binary.BigEndian.Uint16(header[2:]),
+		body:    make([]byte, length-8),
+	}
+	if _, err := io.ReadFull(r, pdu.body); err != nil {
+		return nil, err
+	}
+	return pdu, nil
+}
+
+// Writes a PDU with the body specified.
+func writePDU(w io.Writer, version, pduType uint8, session uint16, body []byte) error {
+	b := make([]byte, 8, 8+len(body))
+	b[0] = version
+	b[1] = pduType
+	binary.BigEndian.PutUint16(b[2:], session)
+	binary.BigEndian.PutUint32(b[4:], uint32(8+len(body)))
+	_, err := w.Write(append(b, body...))
+	return err
+}
+
+// Parses a prefix PDU. True is returned if the VRP is being announced rather than withdrawn.
+func parsePrefixPDU(pdu *rtrPDU) (VRP, bool, error) {
+	addrLen := 4
+	if pdu.pduType == pduIPv6Prefix {
+		addrLen = 16
+	}
+	if len(pdu.body) != 4+addrLen+4 {
+		return VRP{}, false, errors.New("invalid rtr prefix pdu length")
+	}
+	addr, _ := netip.AddrFromSlice(pdu.body[4 : 4+addrLen])
+	prefix, err := addr.Prefix(int(pdu.body[1]))
+	if err != nil {
+		return VRP{}, false, err
+	}
+	return VRP{
+		Prefix:    prefix,
+		MaxLength: int(pdu.body[2]),
+		ASN:       binary.BigEndian.Uint32(pdu.body[4+addrLen:]),
+	}, pdu.body[0]&1 == 1, nil
+}
+
+// rtrClient keeps a validator up to date from an RTR cache.
+type rtrClient struct {
+	log       *zap.Logger
+	validator *Validator
+	addr      string
+	version   uint8
+
+	// Defines the state of the last full sync.
+	synced  bool
+	session uint16
+	serial  uint32
+	vrps    map[VRP]bool
+	refresh time.Duration
+	retry   time.Duration
+}
+
+// Reads PDUs until the end of data, applying the announcements and withdrawals to the VRPs. The VRPs are only
+// swapped into the validator once the end of data is reached.
+func (c *rtrClient) readResponse(conn net.Conn, reset bool) error {
+	vrps := map[VRP]bool{}
+	if !reset {
+		for k := range c.vrps {
+			vrps[k] = true
+		}
+	}
+
+	for {
+		pdu, err := readPDU(conn)
+		if err != nil {
+			return err
+		}
+		switch pdu.pduType {
+		case pduCacheResponse:
+			c.session = pdu.session
+		case pduIPv4Prefix, pduIPv6Prefix:
+			vrp, announce, err := parsePrefixPDU(pdu)
+			if err != nil {
+				return err
+			}
+			if vrp.MaxLength < vrp.Prefix.Bits() || vrp.MaxLength > vrp.Prefix.Addr().BitLen() {
+				// This is rejected in the same way as in a VRP file, but one bad VRP shouldn't stop the sync.
+				c.log.Warn("skipping rtr prefix with an invalid max length",
+					zap.Stringer("prefix", vrp.Prefix), zap.Int("max_length", vrp.MaxLength))
+				continue
+			}
+			if announce {
+				vrps[vrp] = true
+			} else {
+				delete(vrps, vrp)
+			}
+		case pduRouterKey:
+			// We don't do BGPsec, so these can be ignored.
+		case pduEndOfData:
+			if len(pdu.body) < 4 {
+				return errors.New("invalid rtr end of data pdu length")
+			}
+			c.serial = binary.BigEndian.Uint32(pdu.body)
+			if len(pdu.body) >= 12 {
+				if refresh := binary.BigEndian.Uint32(pdu.body[4:]); refresh != 0 {
+					c.refresh = time.Duration(refresh) * time.Second
+				}
+				if retry := binary.BigEndian.Uint32(pdu.body[8:]); retry != 0 {
+					c.retry = time.Duration(retry) * time.Second
+				}
+			}
+
+			// Store the VRPs.
+			c.vrps = vrps
+			c.synced = true
+			list := make([]VRP, 0, len(vrps))
+			for k := range vrps {
+				list = append(list, k)
+			}
+			c.validator.Replace(list)
+			c.log.Info("synced rpki vrps over rtr", zap.String("addr", c.addr), zap.Int("vrps", len(list)),
+				zap.Uint32("serial", c.serial))
+			return nil
+		case pduCacheReset:
+			return c.query(conn, true)
+		case pduErrorReport:
+			return parseErrorReport(pdu)
+		case pduSerialNotify:
+			// This can arrive at any time, and we are already getting the latest data.
+		default:
+			return fmt.Errorf("unexpected rtr pdu type %d", pdu.pduType)
+		}
+	}
+}
+
+// Parses an error report PDU.
+func parseErrorReport(pdu *rtrPDU) error {
+	report := &rtrErrorReport{code: pdu.session}
+	if len(pdu.body) >= 4 {
+		pduLen := int(binary.BigEndian.Uint32(pdu.body))
+		if len(pdu.body) >= 8+pduLen {
+			textLen := int(binary.BigEndian.Uint32(pdu.body[4+pduLen:]))
+			if len(pdu.body) >= 8+pduLen+textLen {
+				report.text = string(pdu.body[8+pduLen : 8+pduLen+textLen])
+			}
+		}
+	}
+	return report
+}
+
+// Sends a reset or serial query and reads the response.
+func (c *rtrClient) query(conn net.Conn, reset bool) error {
+	var err error
+	if reset || !c.synced {
+		reset = true
+		err = writePDU(conn, c.version, pduResetQuery, 0, nil)
+	} else {
+		body := make([]byte, 4)
+		binary.BigEndian.PutUint32(body, c.serial)
+		err = writePDU(conn, c.version, pduSerialQuery, c.session, body)
+	}
+	if err != nil {
+		return err
+	}
+	_ = conn.SetReadDeadline(time.Now().Add(c.retry))
+	return c.readResponse(conn, reset)
+}
+
+// Runs a session with the cache until an error occurs. A full sync is done first, and then the client waits for
+// a serial notify or the refresh interval before asking for the changes.
+func (c *rtrClient) runSession(conn net.Conn) error {
+	if err := c.query(conn, true); err != nil {
+		return err
+	}
+	for {
+		_ = conn.SetReadDeadline(time.Now().Add(c.refresh))
+		pdu, err := readPDU(conn)
+		var netErr net.Error
+		switch {
+		case errors.As(err, &netErr) && netErr.Timeout():
+		case err != nil:
+			return err
+		case pdu.pduType == pduErrorReport:
+			return parseErrorReport(pdu)
+		case pdu.pduType != pduSerialNotify:
+			return fmt.Errorf("unexpected rtr pdu type %d", pdu.pduType)
+		}
+		if err := c.query(conn, false); err != nil {
+			return err
+		}
+	}
+}
+
+// Connects to the cache and runs sessions forever, reconnecting after the retry interval on errors. The version
+// is dropped to 0 if the cache does not support version 1.
+func (c *rtrClient) run() {
+	for {
+		conn, err := net.DialTimeout("tcp", c.addr, 10*time.Second)
+		if err == nil {
+			err = c.runSession(conn)
+			_ = conn.Close()
+		}
+
+		var report *rtrErrorReport
+		if errors.As(err, &report) && report.code == rtrUnsupportedVersion && c.version != 0 {
+			c.log.Info("rtr cache does not support version 1, falling back to version 0", zap.String("addr", c.addr))
+			c.version = 0
+			continue
+		}
+		c.log.Warn("rtr session failed", zap.String("addr", c.addr), zap.Error(err))
+		time.Sleep(c.retry)
+	}
+}
+
+// RunRTR keeps the validator up to date from the RTR cache at the address specified in the background.
+func RunRTR(log *zap.Logger, v *Validator, addr string) {
+	c := &rtrClient{
+		log:       log,
+		validator: v,
+		addr:      addr,
+		version:   1,
+		refresh:   defaultRTRRefresh,
+		retry:     defaultRTRRetry,
+	}
+	go c.run()
+}
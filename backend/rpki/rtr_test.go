@@ -0,0 +1,116 @@
+package rpki
+
+import (
+	"encoding/binary"
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// Builds the body of a prefix PDU.
+func prefixBody(announce bool, prefix string, maxLength int, asn uint32) []byte {
+	p := netip.MustParsePrefix(prefix)
+	b := []byte{0, byte(p.Bits()), byte(maxLength), 0}
+	if announce {
+		b[0] = 1
+	}
+	b = append(b, p.Addr().AsSlice()...)
+	return binary.BigEndian.AppendUint32(b, asn)
+}
+
+func TestRTRClient_query(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+	defer client.Close()
+
+	v := NewValidator()
+	c := &rtrClient{
+		log:       zap.NewNop(),
+		validator: v,
+		version:   1,
+		refresh:   defaultRTRRefresh,
+		retry:     time.Second,
+	}
+
+	// Run the cache side.
+	go func() {
+		// Full sync.
+		pdu, err := readPDU(server)
+		if err != nil || pdu.pduType != pduResetQuery {
+			return
+		}
+		_ = writePDU(server, 1, pduCacheResponse, 42, nil)
+		_ = writePDU(server, 1, pduIPv4Prefix, 0, prefixBody(true, "192.0.2.0/24", 24, 64500))
+		_ = writePDU(server, 1, pduIPv6Prefix, 0, prefixBody(true, "2001:db8::/32", 48, 64501))
+		_ = writePDU(server, 1, pduIPv4Prefix, 0, prefixBody(true, "203.0.113.0/24", 16, 64503))
+		_ = writePDU(server, 1, pduIPv6Prefix, 0, prefixBody(true, "2001:db8:ffff::/48", 129, 64504))
+		eod := binary.BigEndian.AppendUint32(nil, 1)
+		eod = binary.BigEndian.AppendUint32(eod, 1800)
+		eod = binary.BigEndian.AppendUint32(eod, 300)
+		eod = binary.BigEndian.AppendUint32(eod, 7200)
+		_ = writePDU(server, 1, pduEndOfData, 42, eod)
+
+		// Incremental update.
+		pdu, err = readPDU(server)
+		if err != nil || pdu.pduType != pduSerialQuery || binary.BigEndian.Uint32(pdu.body) != 1 {
+			return
+		}
+		_ = writePDU(server, 1, pduCacheResponse, 42, nil)
+		_ = writePDU(server, 1, pduIPv4Prefix, 0, prefixBody(false, "192.0.2.0/24", 24, 64500))
+		_ = writePDU(server, 1, pduIPv4Prefix, 0, prefixBody(true, "198.51.100.0/24", 24, 64502))
+		eod = binary.BigEndian.AppendUint32(nil, 2)
+		eod = binary.BigEndian.AppendUint32(eod, 1800)
+		eod = binary.BigEndian.AppendUint32(eod, 300)
+		eod = binary.BigEndian.AppendUint32(eod, 7200)
+		_ = writePDU(server, 1, pduEndOfData, 42, eod)
+	}()
+
+	// Do the full sync.
+	if !assert.NoError(t, c.query(client, true)) {
+		return
+	}
+	assert.Equal(t, uint16(42), c.session)
+	assert.Equal(t, uint32(1), c.serial)
+	assert.Equal(t, 30*time.Minute, c.refresh)
+	assert.Equal(t, 5*time.Minute, c.retry)
+	assert.Equal(t, StateValid, v.Validate(netip.MustParsePrefix("192.0.2.0/24"), 64500).State)
+	assert.Equal(t, StateValid, v.Validate(netip.MustParsePrefix("2001:db8:1::/48"), 64501).State)
+	assert.Len(t, c.vrps, 2)
+	assert.Equal(t, StateNotFound, v.Validate(netip.MustParsePrefix("203.0.113.0/24"), 64503).State)
+
+	// Do the incremental update.
+	if !assert.NoError(t, c.query(client, false)) {
+		return
+	}
+	assert.Equal(t, uint32(2), c.serial)
+	assert.Equal(t, StateNotFound, v.Validate(netip.MustParsePrefix("192.0.2.0/24"), 64500).State)
+	assert.Equal(t, StateValid, v.Validate(netip.MustParsePrefix("198.51.100.0/24"), 64502).State)
+}
+
+func TestRTRClient_errorReport(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+	defer client.Close()
+
+	c := &rtrClient{log: zap.NewNop(), validator: NewValidator(), version: 1, retry: time.Second}
+	go func() {
+		if _, err := readPDU(server); err != nil {
+			return
+		}
+		text := "unsupported version"
+		body := binary.BigEndian.AppendUint32(nil, 0)
+		body = binary.BigEndian.AppendUint32(body, uint32(len(text)))
+		_ = writePDU(server, 0, pduErrorReport, rtrUnsupportedVersion, append(body, text...))
+	}()
+
+	err := c.query(client, true)
+	var report *rtrErrorReport
+	if assert.ErrorAs(t, err, &report) {
+		assert.Equal(t, uint16(rtrUnsupportedVersion), report.code)
+		assert.Equal(t, "unsupported version", report.text)
+	}
+}
@@ -0,0 +1,136 @@
+package rpki
+
+import (
+	"net/netip"
+	"sync"
+	"time"
+)
+
+// State is used to define the RPKI validation state of a route, as described in RFC 6811.
+type State string
+
+const (
+	// StateValid means a ROA covers the route with the same origin AS and an allowed prefix length.
+	StateValid State = "valid"
+
+	// StateInvalidASN means ROAs cover the route but none of them are for the origin AS.
+	StateInvalidASN State = "invalid-asn"
+
+	// StateInvalidLength means a ROA for the origin AS covers the route but the prefix is longer than allowed.
+	StateInvalidLength State = "invalid-length"
+
+	// StateNotFound means no ROAs cover the route.
+	StateNotFound State = "not-found"
+)
+
+// VRP is a validated ROA payload.
+type VRP struct {
+	// Prefix is used to define the prefix of the ROA.
+	Prefix netip.Prefix `json:"prefix"`
+
+	// MaxLength is used to define the longest prefix length the origin AS may announce.
+	MaxLength int `json:"max_length"`
+
+	// ASN is used to define the AS that may originate the prefix.
+	ASN uint32 `json:"asn"`
+
+	// TA is used to define the trust anchor the ROA came from, if it is known.
+	TA string `json:"ta,omitempty"`
+}
+
+// Result is the result of validating a route.
+type Result struct {
+	// State is used to define the validation state.
+	State State `json:"state"`
+
+	// Covering is used to define every VRP which covers the route.
+	Covering []VRP `json:"covering_roas"`
+}
+
+// Validator validates routes against a set of VRPs. The VRPs can be replaced at any time.
+type Validator struct {
+	lock sync.RWMutex
+
+	// Defines the VRPs indexed by prefix length and then prefix.
+	vrps map[int]map[netip.Prefix][]VRP
+
+	// Defines the number of VRPs and when they were last replaced.
+	count   int
+	updated time.Time
+}
+
+// NewValidator creates a validator with no VRPs.
+func NewValidator() *Validator {
+	return &Validator{}
+}
+
+// Replace replaces every VRP in the validator.
+func (v *Validator) Replace(vrps []VRP) {
+	index := map[int]map[netip.Prefix][]VRP{}
+	for _, vrp := range vrps {
+		vrp.Prefix = vrp.Prefix.Masked()
+		bits := vrp.Prefix.Bits()
+		if index[bits] == nil {
+			index[bits] = map[netip.Prefix][]VRP{}
+		}
+		index[bits][vrp.Prefix] = append(index[bits][vrp.Prefix], vrp)
+	}
+
+	v.lock.Lock()
+	v.vrps = index
+	v.count = len(vrps)
+	v.updated = time.Now()
+	v.lock.Unlock()
+}
+
+// Ready returns if the validator has been loaded with VRPs.
+func (v *Validator) Ready() bool {
+	v.lock.RLock()
+	defer v.lock.RUnlock()
+	return v.vrps != nil
+}
+
+// Stats returns the number of VRPs and when they were last replaced.
+func (v *Validator) Stats() (int, time.Time) {
+	v.lock.RLock()
+	defer v.lock.RUnlock()
+	return v.count, v.updated
+}
+
+// Validate returns the validation state of a route and the VRPs that cover it.
+func (v *Validator) Validate(prefix netip.Prefix, originASN uint32) Result {
+	prefix = prefix.Masked()
+	result := Result{State: StateNotFound, Covering: []VRP{}}
+
+	// Find every VRP for the prefix or a less specific one.
+	v.lock.RLock()
+	for bits := 0; bits <= prefix.Bits(); bits++ {
+		candidates := v.vrps[bits]
+		if candidates == nil {
+			continue
+		}
+		covering, err := prefix.Addr().Prefix(bits)
+		if err != nil {
+			continue
+		}
+		result.Covering = append(result.Covering, candidates[covering]...)
+	}
+	v.lock.RUnlock()
+	if len(result.Covering) == 0 {
+		return result
+	}
+
+	// Work out the state. AS0 ROAs can never match.
+	result.State = StateInvalidASN
+	for _, vrp := range result.Covering {
+		if vrp.ASN != originASN || vrp.ASN == 0 {
+			continue
+		}
+		if prefix.Bits() <= vrp.MaxLength {
+			result.State = StateValid
+			break
+		}
+		result.State = StateInvalidLength
+	}
+	return result
+}
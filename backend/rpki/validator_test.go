@@ -0,0 +1,94 @@
+package rpki
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidator_Validate(t *testing.T) {
+	v := NewValidator()
+	assert.False(t, v.Ready())
+	v.Replace([]VRP{
+		{Prefix: netip.MustParsePrefix("192.0.2.0/24"), MaxLength: 24, ASN: 64500},
+		{Prefix: netip.MustParsePrefix("198.51.100.0/22"), MaxLength: 24, ASN: 64501},
+		{Prefix: netip.MustParsePrefix("203.0.113.0/24"), MaxLength: 24, ASN: 0},
+		{Prefix: netip.MustParsePrefix("2001:db8::/32"), MaxLength: 48, ASN: 64502},
+	})
+	assert.True(t, v.Ready())
+	count, _ := v.Stats()
+	assert.Equal(t, 4, count)
+
+	tests := []struct {
+		name string
+
+		prefix   string
+		asn      uint32
+		state    State
+		covering int
+	}{
+		{
+			name:     "valid",
+			prefix:   "192.0.2.0/24",
+			asn:      64500,
+			state:    StateValid,
+			covering: 1,
+		},
+		{
+			name:     "invalid asn",
+			prefix:   "192.0.2.0/24",
+			asn:      64501,
+			state:    StateInvalidASN,
+			covering: 1,
+		},
+		{
+			name:     "invalid length",
+			prefix:   "192.0.2.0/25",
+			asn:      64500,
+			state:    StateInvalidLength,
+			covering: 1,
+		},
+		{
+			name:     "valid more specific within max length",
+			prefix:   "198.51.101.0/24",
+			asn:      64501,
+			state:    StateValid,
+			covering: 1,
+		},
+		{
+			name:     "as0",
+			prefix:   "203.0.113.0/24",
+			asn:      0,
+			state:    StateInvalidASN,
+			covering: 1,
+		},
+		{
+			name:   "not found",
+			prefix: "192.0.0.0/16",
+			asn:    64500,
+			state:  StateNotFound,
+		},
+		{
+			name:     "ipv6 valid",
+			prefix:   "2001:db8:1::/48",
+			asn:      64502,
+			state:    StateValid,
+			covering: 1,
+		},
+		{
+			name:     "ipv6 invalid length",
+			prefix:   "2001:db8:1::/64",
+			asn:      64502,
+			state:    StateInvalidLength,
+			covering: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := v.Validate(netip.MustParsePrefix(tt.prefix), tt.asn)
+			assert.Equal(t, tt.state, result.State)
+			assert.Len(t, result.Covering, tt.covering)
+		})
+	}
+}